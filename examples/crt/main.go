@@ -0,0 +1,98 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/tinyrange/gowin/internal/graphics"
+	"github.com/tinyrange/gowin/internal/text"
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+// crtShader bends the picture like a curved tube, darkens alternate
+// scanlines and fades the corners.
+const crtShader = `#version 130
+in vec2 v_texCoord;
+in vec4 v_color;
+
+out vec4 fragColor;
+
+uniform sampler2D u_texture;
+uniform vec2 u_resolution;
+uniform float u_time;
+uniform float u_curvature;
+
+void main() {
+	vec2 uv = v_texCoord * 2.0 - 1.0;
+	uv *= 1.0 + u_curvature * dot(uv.yx, uv.yx);
+	uv = uv * 0.5 + 0.5;
+	if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+		fragColor = vec4(0.0, 0.0, 0.0, 1.0);
+		return;
+	}
+
+	vec3 color = texture(u_texture, uv).rgb;
+	float scanline = 0.75 + 0.25 * sin(uv.y * u_resolution.y * 3.14159);
+	float flicker = 0.97 + 0.03 * sin(u_time * 60.0);
+	float vignette = 16.0 * uv.x * uv.y * (1.0 - uv.x) * (1.0 - uv.y);
+	color *= scanline * flicker * pow(vignette, 0.25);
+	fragColor = vec4(color, 1.0);
+}`
+
+func main() {
+	gfx, err := graphics.New("CRT post-process", 800, 600)
+	if err != nil {
+		log.Fatalf("init: %v", err)
+	}
+	gfx.SetClearColor(color.RGBA{R: 10, G: 30, B: 20, A: 255})
+
+	crt, err := gfx.NewShader(crtShader)
+	if err != nil {
+		log.Fatalf("shader: %v", err)
+	}
+	crt.SetFloat("u_curvature", 0.08)
+	gfx.SetPostProcess(crt)
+
+	white := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	white.Set(0, 0, color.White)
+	tex, err := gfx.NewTexture(white)
+	if err != nil {
+		log.Fatalf("texture: %v", err)
+	}
+
+	font, err := text.Load(gfx)
+	if err != nil {
+		log.Fatalf("font: %v", err)
+	}
+
+	enabled := true
+	var t float64
+	err = gfx.Loop(func(f graphics.Frame) error {
+		t += 1.0 / 60
+
+		if f.KeyJustPressed(window.KeySpace) {
+			enabled = !enabled
+			if enabled {
+				gfx.SetPostProcess(crt)
+			} else {
+				gfx.SetPostProcess(nil)
+			}
+		}
+
+		vw, vh := gfx.ViewSize()
+		for i := 0; i < 6; i++ {
+			phase := t + float64(i)*0.6
+			x := vw/2 + float32(math.Cos(phase))*vw/3 - 40
+			y := vh/2 + float32(math.Sin(phase*1.3))*vh/3 - 40
+			f.RenderQuad(x, y, 80, 80, tex, graphics.ColorGreen)
+		}
+
+		font.RenderText("READY.\nPress space to toggle the CRT effect", 40, 60, 24, graphics.ColorGreen)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("run loop: %v", err)
+	}
+}
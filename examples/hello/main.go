@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 	"log"
 	"log/slog"
 	"os"
@@ -49,27 +48,31 @@ func main() {
 
 	slog.Info("Scale", "scale", gfx.Scale())
 
+	var fps graphics.FPSCounter
+
 	err = gfx.Loop(func(f graphics.Frame) error {
+		fps.Tick()
+
 		// Get mouse position
 		mouseX, mouseY := f.CursorPos()
 
 		// Handle WASD movement
-		if f.GetKeyState(window.KeyW).IsDown() {
+		if f.KeyDown(window.KeyW) {
 			wasdY -= moveSpeed
 		}
-		if f.GetKeyState(window.KeyS).IsDown() {
+		if f.KeyDown(window.KeyS) {
 			wasdY += moveSpeed
 		}
-		if f.GetKeyState(window.KeyA).IsDown() {
+		if f.KeyDown(window.KeyA) {
 			wasdX -= moveSpeed
 		}
-		if f.GetKeyState(window.KeyD).IsDown() {
+		if f.KeyDown(window.KeyD) {
 			wasdX += moveSpeed
 		}
 
 		// Determine mouse quad color based on click state
 		mouseColor := graphics.ColorWhite
-		if f.GetButtonState(window.ButtonLeft).IsDown() {
+		if f.ButtonDown(window.ButtonLeft) {
 			mouseColor = graphics.ColorRed
 		}
 
@@ -79,33 +82,19 @@ func main() {
 		// Render WASD-controlled quad
 		f.RenderQuad(wasdX, wasdY, float32(quadSize), float32(quadSize), tex, graphics.ColorBlue)
 
-		// Set viewport for text rendering
-		w, h := f.WindowSize()
-		font.SetViewport(int32(w), int32(h))
-
-		text := fmt.Sprintf("The quick brown fox jumps over the lazy dog.\nScale = %f", gfx.Scale())
+		text := fmt.Sprintf("The quick brown fox jumps over the lazy dog.\nScale = %f\nFPS = %.1f", gfx.Scale(), fps.FPS())
 
 		font.RenderText(text, 10, 24, 16, graphics.ColorYellow)
 
 		if *screenshot {
-			screenshot, err := f.Screenshot()
-			if err != nil {
-				log.Fatalf("screenshot: %v", err)
-			}
-
 			screenshotPath := "screenshot.png"
 
-			file, err := os.Create(screenshotPath)
-			if err != nil {
-				return fmt.Errorf("create screenshot file: %v", err)
-			}
-			defer file.Close()
-
-			if err := png.Encode(file, screenshot); err != nil {
-				return fmt.Errorf("encode screenshot: %v", err)
+			if err := graphics.SaveScreenshot(f, screenshotPath); err != nil {
+				return fmt.Errorf("screenshot: %v", err)
 			}
 
-			return fmt.Errorf("taken screenshot at %s", screenshotPath)
+			slog.Info("Took screenshot", "path", screenshotPath)
+			return graphics.ErrStopLoop
 		}
 
 		return nil
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"math/rand/v2"
+
+	"github.com/tinyrange/gowin/internal/graphics"
+	"github.com/tinyrange/gowin/internal/text"
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+const (
+	maxParticles = 20000
+	spawnRate    = 4000 // particles per second
+	gravity      = 300  // logical pixels per second squared
+	particleSize = 24
+)
+
+type particle struct {
+	x, y   float32
+	vx, vy float32
+	age    float32
+	life   float32
+	hue    float32
+}
+
+func main() {
+	gfx, err := graphics.NewWithOptions(graphics.Options{
+		Title:      "Particles",
+		Width:      1024,
+		Height:     768,
+		ClearColor: color.RGBA{R: 8, G: 8, B: 16, A: 255},
+	})
+	if err != nil {
+		log.Fatalf("init: %v", err)
+	}
+
+	font, err := text.Load(gfx)
+	if err != nil {
+		log.Fatalf("font: %v", err)
+	}
+
+	spark, err := gfx.NewTextureWithOptions(makeSpark(64), graphics.TextureOptions{Filter: graphics.FilterLinear})
+	if err != nil {
+		log.Fatalf("texture: %v", err)
+	}
+	batch := graphics.NewSpriteBatch(spark)
+
+	particles := make([]particle, 0, maxParticles)
+	var pending float32 // fractional particles carried over to the next frame
+	var fps graphics.FPSCounter
+
+	err = gfx.Loop(func(f graphics.Frame) error {
+		fps.Tick()
+		dt := float32(f.DeltaTime().Seconds())
+
+		// Emit from the cursor, or above the middle of the window while the
+		// mouse is outside it.
+		ex, ey := f.CursorPos()
+		if !f.MouseInWindow() {
+			w, h := gfx.ViewSize()
+			ex, ey = w/2, h/3
+		}
+		pending += spawnRate * dt
+		for ; pending >= 1 && len(particles) < maxParticles; pending-- {
+			a := rand.Float64() * 2 * math.Pi
+			speed := 50 + rand.Float32()*250
+			particles = append(particles, particle{
+				x:    ex,
+				y:    ey,
+				vx:   float32(math.Cos(a)) * speed,
+				vy:   float32(math.Sin(a))*speed - 200,
+				life: 1 + rand.Float32()*2,
+				hue:  rand.Float32() * 60,
+			})
+		}
+		pending = float32(math.Mod(float64(pending), 1))
+
+		// Step the simulation, dropping dead particles in place.
+		live := particles[:0]
+		for _, p := range particles {
+			p.age += dt
+			if p.age >= p.life {
+				continue
+			}
+			p.vy += gravity * dt
+			p.x += p.vx * dt
+			p.y += p.vy * dt
+			live = append(live, p)
+		}
+		particles = live
+
+		batch.Reset()
+		for _, p := range particles {
+			fade := 1 - p.age/p.life
+			size := particleSize * (0.5 + fade)
+			batch.Add(p.x-size/2, p.y-size/2, size, size, hueColor(p.hue, fade))
+		}
+
+		f.SetBlendMode(graphics.BlendAdditive)
+		f.DrawBatch(batch)
+		f.SetBlendMode(graphics.BlendAlpha)
+
+		if f.KeyJustPressed(window.KeyEscape) {
+			return graphics.ErrStopLoop
+		}
+
+		font.RenderText(fmt.Sprintf("%d particles\nFPS = %.1f", len(particles), fps.FPS()), 10, 24, 16, graphics.ColorWhite)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("run loop: %v", err)
+	}
+}
+
+// makeSpark returns a size x size white dot whose alpha falls off smoothly
+// from the centre.
+func makeSpark(size int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	c := float64(size) / 2
+	for y := range size {
+		for x := range size {
+			d := math.Hypot(float64(x)+0.5-c, float64(y)+0.5-c) / c
+			a := math.Max(0, 1-d)
+			img.SetNRGBA(x, y, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: uint8(a * a * 0xff)})
+		}
+	}
+	return img
+}
+
+// hueColor returns the color of the given hue in degrees, from red at 0
+// to yellow at 60, with alpha a.
+func hueColor(hue, a float32) color.NRGBA {
+	return color.NRGBA{R: 0xff, G: uint8(hue / 60 * 0xff), B: 0x40, A: uint8(a * 0xff)}
+}
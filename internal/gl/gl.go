@@ -5,6 +5,10 @@ import "unsafe"
 const (
 	// ColorBufferBit is a mask used with Clear to clear the color buffer.
 	ColorBufferBit = 0x00004000
+	// StencilBufferBit is a mask used with Clear to clear the stencil buffer.
+	StencilBufferBit = 0x00000400
+	// DepthBufferBit is a mask used with Clear to clear the depth buffer.
+	DepthBufferBit = 0x00000100
 
 	// Texture2D is the texture target for 2D textures.
 	Texture2D = 0x0DE1
@@ -30,6 +34,9 @@ const (
 	Nearest = 0x2600
 	// Linear selects linear filtering.
 	Linear = 0x2601
+	// LinearMipmapLinear selects trilinear filtering: linear within and
+	// between mipmap levels. Only valid as a minification filter.
+	LinearMipmapLinear = 0x2703
 
 	// ClampToEdge clamps texture coordinates to the edge of the texture.
 	ClampToEdge = 0x812F
@@ -42,9 +49,16 @@ const (
 	Red = 0x1903
 	// R8 is an internal texture format for 8-bit red channel (OpenGL 3.0+).
 	R8 = 0x8229
+	// BGRA is a pixel format with red and blue swapped relative to RGBA.
+	BGRA = 0x80E1
+	// RGB is a pixel format representing red/green/blue without alpha.
+	RGB = 0x1907
 
 	// UnsignedByte is a pixel data type indicating 8-bit unsigned values.
 	UnsignedByte = 0x1401
+	// UnsignedShort565 is a pixel data type packing an RGB pixel into 16
+	// bits: 5 of red in the high bits, 6 of green and 5 of blue.
+	UnsignedShort565 = 0x8363
 	// Float is a data type indicating 32-bit floating point values.
 	Float = 0x1406
 
@@ -52,13 +66,40 @@ const (
 	Triangles = 0x0004
 	// TriangleStrip is a primitive type for drawing a connected strip of triangles.
 	TriangleStrip = 0x0005
+	// Points is a primitive type for drawing individual points.
+	Points = 0x0000
+	// Lines is a primitive type for drawing independent line segments.
+	Lines = 0x0001
+	// LineLoop is a primitive type for drawing a closed loop of lines.
+	LineLoop = 0x0002
+	// LineStrip is a primitive type for drawing a connected strip of lines.
+	LineStrip = 0x0003
+
+	// ProgramPointSize lets the vertex shader set gl_PointSize.
+	ProgramPointSize = 0x8642
 
 	// ArrayBuffer is the target for vertex buffer objects.
 	ArrayBuffer = 0x8892
+	// PixelPackBuffer is the target for buffers that ReadPixels writes into.
+	PixelPackBuffer = 0x88EB
+	// PixelUnpackBuffer is the target for buffers that TexImage2D and
+	// TexSubImage2D read from.
+	PixelUnpackBuffer = 0x88EC
 	// StaticDraw indicates that buffer data will be modified once and used many times.
 	StaticDraw = 0x88E4
 	// DynamicDraw indicates that buffer data will be modified repeatedly and used many times.
 	DynamicDraw = 0x88E8
+	// StreamDraw indicates that buffer data will be modified once and used at most a few times.
+	StreamDraw = 0x88E0
+	// StreamRead indicates that buffer data will be written by GL once and read back by the application.
+	StreamRead = 0x88E1
+
+	// MapBufferRange access flags.
+	MapReadBit             = 0x0001
+	MapWriteBit            = 0x0002
+	MapInvalidateRangeBit  = 0x0004
+	MapInvalidateBufferBit = 0x0008
+	MapUnsynchronizedBit   = 0x0020
 
 	// Shader types
 	VertexShader   = 0x8B31
@@ -71,11 +112,45 @@ const (
 	// Texture unit
 	Texture0 = 0x84C0
 
+	// Framebuffer objects.
+	Framebuffer            = 0x8D40
+	Renderbuffer           = 0x8D41
+	ColorAttachment0       = 0x8CE0
+	DepthStencilAttachment = 0x821A
+	FramebufferComplete    = 0x8CD5
+	// Depth24Stencil8 is a packed depth and stencil renderbuffer format.
+	Depth24Stencil8 = 0x88F0
+
 	// Blending capabilities and factors.
 	Blend            = 0x0BE2
+	Zero             = 0
+	One              = 1
 	SrcAlpha         = 0x0302
 	OneMinusSrcAlpha = 0x0303
 
+	// StencilTest enables stencil testing.
+	StencilTest = 0x0B90
+	// DepthTest enables depth testing.
+	DepthTest = 0x0B71
+	// ScissorTest restricts drawing and clearing to the Scissor rectangle.
+	ScissorTest = 0x0C11
+
+	// Multisample enables multisample rasterization on multisampled framebuffers.
+	Multisample = 0x809D
+
+	// Comparison functions used by StencilFunc and DepthFunc.
+	Less     = 0x0201
+	Equal    = 0x0202
+	LEqual   = 0x0203
+	NotEqual = 0x0205
+	Always   = 0x0207
+
+	// Stencil operations used by StencilOp.
+	Keep    = 0x1E00
+	Replace = 0x1E01
+	Incr    = 0x1E02
+	Decr    = 0x1E03
+
 	// Texture formats.
 	LuminanceAlpha = 0x190A
 
@@ -83,8 +158,27 @@ const (
 	//
 	// Vendor returns the company responsible for the GL implementation.
 	Vendor = 0x1F00
+	// Renderer names the device or software rasterizer doing the drawing.
+	Renderer = 0x1F01
 	// Version returns the GL version string of the current context.
 	Version = 0x1F02
+	// Extensions returns the space-separated extension list. Core profiles
+	// reject it in favour of GetStringi with NumExtensions.
+	Extensions = 0x1F03
+
+	// GetIntegerv and GetFloatv parameters.
+	MaxTextureSize     = 0x0D33
+	Viewport           = 0x0BA2
+	CurrentProgram     = 0x8B8D
+	VertexArrayBinding = 0x85B5
+	ArrayBufferBinding = 0x8894
+	ActiveTexture      = 0x84E0
+	TextureBinding2D   = 0x8069
+	NumExtensions      = 0x821D
+
+	// EXT_texture_filter_anisotropic.
+	TextureMaxAnisotropy    = 0x84FE
+	MaxTextureMaxAnisotropy = 0x84FF
 )
 
 // OpenGL describes the subset of OpenGL entry points used by this package.
@@ -98,10 +192,29 @@ type OpenGL interface {
 	// Clear clears buffers to preset values (e.g., ColorBufferBit).
 	Clear(mask uint32)
 
+	// Flush forces issued commands to start executing without waiting for them.
+	Flush()
+
+	// Finish blocks until all issued commands have completed.
+	Finish()
+
 	// Viewport sets the affine transformation of x and y from normalized device
 	// coordinates to window coordinates.
 	Viewport(x, y, width, height int32)
 
+	// Scissor sets the rectangle, in window coordinates, that drawing and
+	// Clear are limited to while ScissorTest is enabled.
+	Scissor(x, y, width, height int32)
+
+	// LineWidth sets the rasterized width of lines. Core profiles only
+	// guarantee a width of 1.0; wider lines are deprecated and may be clamped
+	// or rejected, so thick lines should be triangulated instead.
+	LineWidth(width float32)
+
+	// PointSize sets the rasterized diameter of points when ProgramPointSize
+	// is disabled. Drivers clamp it to an implementation-defined maximum.
+	PointSize(size float32)
+
 	// Enable enables a server-side GL capability (e.g., Blend).
 	Enable(cap uint32)
 
@@ -111,6 +224,9 @@ type OpenGL interface {
 	// GenTextures generates texture object names.
 	GenTextures(n int32, textures *uint32)
 
+	// DeleteTextures deletes named textures, unbinding any that are bound.
+	DeleteTextures(n int32, textures *uint32)
+
 	// BindTexture binds a named texture to a texturing target (e.g., Texture2D).
 	BindTexture(target, texture uint32)
 
@@ -145,6 +261,13 @@ type OpenGL interface {
 	// TexParameteri sets texture parameters for the currently bound texture.
 	TexParameteri(target, pname uint32, param int32)
 
+	// TexParameterf sets a floating point texture parameter, such as
+	// TextureMaxAnisotropy, for the currently bound texture.
+	TexParameterf(target, pname uint32, param float32)
+
+	// GenerateMipmap generates the mipmap chain for the texture bound to target.
+	GenerateMipmap(target uint32)
+
 	// PixelStorei sets pixel storage modes (e.g., UnpackAlignment).
 	PixelStorei(pname uint32, param int32)
 
@@ -153,6 +276,30 @@ type OpenGL interface {
 
 	// BlendFunc specifies the pixel arithmetic for blending (e.g., SrcAlpha and OneMinusSrcAlpha).
 	BlendFunc(sfactor, dfactor uint32)
+	// BlendFuncSeparate is BlendFunc with separate factors for the alpha channel.
+	BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha uint32)
+
+	// StencilFunc sets the function and reference value for stencil testing.
+	StencilFunc(fn uint32, ref int32, mask uint32)
+
+	// StencilOp sets the actions taken when the stencil test fails, the
+	// depth test fails, and both pass.
+	StencilOp(fail, zfail, zpass uint32)
+
+	// StencilMask controls which bits of the stencil buffer can be written.
+	StencilMask(mask uint32)
+
+	// ColorMask controls which color channels draws write to.
+	ColorMask(r, g, b, a bool)
+
+	// DepthMask controls whether draws write to the depth buffer.
+	DepthMask(flag bool)
+
+	// ClearStencil sets the value used by Clear when clearing the stencil buffer.
+	ClearStencil(s int32)
+
+	// DepthFunc sets the comparison used for depth testing (e.g., Less).
+	DepthFunc(fn uint32)
 
 	// Buffer operations
 	GenBuffers(n int32, buffers *uint32)
@@ -161,11 +308,22 @@ type OpenGL interface {
 	BufferData(target uint32, size int, data unsafe.Pointer, usage uint32)
 	BufferSubData(target uint32, offset int, size int, data unsafe.Pointer)
 
+	// MapBufferRange maps length bytes of the buffer bound to target, starting
+	// at offset, into client memory. It returns nil if the mapping failed.
+	MapBufferRange(target uint32, offset int, length int, access uint32) unsafe.Pointer
+
+	// UnmapBuffer releases the mapping of the buffer bound to target. It
+	// returns false if the buffer contents were lost while mapped and must be
+	// uploaded again.
+	UnmapBuffer(target uint32) bool
+
 	// Vertex Array Object operations
 	GenVertexArrays(n int32, arrays *uint32)
 	DeleteVertexArrays(n int32, arrays *uint32)
 	BindVertexArray(array uint32)
-	VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset unsafe.Pointer)
+	// VertexAttribPointer sources attribute index from the bound
+	// ArrayBuffer; offset is a byte offset into it, not a pointer.
+	VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset uintptr)
 	EnableVertexAttribArray(index uint32)
 
 	// Shader operations
@@ -190,12 +348,29 @@ type OpenGL interface {
 	// GetAttribLocation returns the location of an attribute variable.
 	GetAttribLocation(program uint32, name string) int32
 	Uniform1i(location int32, v0 int32)
+	Uniform1f(location int32, v0 float32)
+	Uniform2f(location int32, v0, v1 float32)
 	Uniform4f(location int32, v0, v1, v2, v3 float32)
 	UniformMatrix4fv(location int32, count int32, transpose bool, value *float32)
 
 	// Drawing
 	DrawArrays(mode uint32, first int32, count int32)
 
+	// Framebuffer operations. Binding framebuffer 0 restores drawing to the
+	// window.
+	GenFramebuffers(n int32, framebuffers *uint32)
+	DeleteFramebuffers(n int32, framebuffers *uint32)
+	BindFramebuffer(target, framebuffer uint32)
+	FramebufferTexture2D(target, attachment, textarget, texture uint32, level int32)
+	// CheckFramebufferStatus returns FramebufferComplete if the bound
+	// framebuffer can be drawn to.
+	CheckFramebufferStatus(target uint32) uint32
+	GenRenderbuffers(n int32, renderbuffers *uint32)
+	DeleteRenderbuffers(n int32, renderbuffers *uint32)
+	BindRenderbuffer(target, renderbuffer uint32)
+	RenderbufferStorage(target, internalformat uint32, width, height int32)
+	FramebufferRenderbuffer(target, attachment, renderbuffertarget, renderbuffer uint32)
+
 	// ReadPixels reads a block of pixels from the framebuffer into client memory.
 	ReadPixels(
 		x int32,
@@ -207,12 +382,34 @@ type OpenGL interface {
 		pixels unsafe.Pointer,
 	)
 
+	// GetTexImage reads back the image of the texture bound to target at the
+	// given mipmap level into client memory.
+	GetTexImage(
+		target uint32,
+		level int32,
+		format uint32,
+		xtype uint32,
+		pixels unsafe.Pointer,
+	)
+
 	// GetString returns a string describing a GL property for the current context.
 	//
 	// Common names are Vendor and Version.
 	// If the name is not recognized or no context is current, implementations may
 	// return the empty string.
 	GetString(name uint32) string
+
+	// GetStringi returns the index'th string of an indexed property such as
+	// Extensions. Requires GL 3.0.
+	GetStringi(name, index uint32) string
+
+	// GetIntegerv writes the value or values of an integer state variable,
+	// such as CurrentProgram, to data.
+	GetIntegerv(pname uint32, data *int32)
+
+	// GetFloatv writes the value or values of a floating point state variable
+	// to data.
+	GetFloatv(pname uint32, data *float32)
 }
 
 func gostring(ptr *byte) string {
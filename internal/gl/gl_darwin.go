@@ -8,22 +8,43 @@ import (
 	"github.com/ebitengine/purego"
 )
 
+// openGL must provide every method graphics and text use.
+var _ OpenGL = (*openGL)(nil)
+
 type openGL struct {
-	clearColor    func(float32, float32, float32, float32)
-	clear         func(uint32)
-	viewport      func(int32, int32, int32, int32)
-	enable        func(uint32)
-	disable       func(uint32)
-	genTextures   func(int32, *uint32)
-	bindTexture   func(uint32, uint32)
-	texImage2D    func(uint32, int32, int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
-	texSubImage2D func(uint32, int32, int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
-	texParameteri func(uint32, uint32, int32)
-	pixelStorei   func(uint32, int32)
-	activeTexture func(uint32)
-	blendFunc     func(uint32, uint32)
-	readPixels    func(int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
-	getString     func(uint32) *byte
+	clearColor     func(float32, float32, float32, float32)
+	clear          func(uint32)
+	flush          func()
+	finish         func()
+	viewport       func(int32, int32, int32, int32)
+	scissor        func(int32, int32, int32, int32)
+	lineWidth      func(float32)
+	pointSize      func(float32)
+	enable         func(uint32)
+	disable        func(uint32)
+	genTextures    func(int32, *uint32)
+	deleteTextures func(int32, *uint32)
+	bindTexture    func(uint32, uint32)
+	texImage2D     func(uint32, int32, int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
+	texSubImage2D  func(uint32, int32, int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
+	texParameteri  func(uint32, uint32, int32)
+	texParameterf  func(uint32, uint32, float32)
+	pixelStorei    func(uint32, int32)
+	activeTexture  func(uint32)
+	blendFunc      func(uint32, uint32)
+	stencilFunc    func(uint32, int32, uint32)
+	stencilOp      func(uint32, uint32, uint32)
+	stencilMask    func(uint32)
+	colorMask      func(bool, bool, bool, bool)
+	depthMask      func(bool)
+	clearStencil   func(int32)
+	depthFunc      func(uint32)
+	readPixels     func(int32, int32, int32, int32, uint32, uint32, unsafe.Pointer)
+	getTexImage    func(uint32, int32, uint32, uint32, unsafe.Pointer)
+	getString      func(uint32) *byte
+	getStringi     func(uint32, uint32) *byte
+	getIntegerv    func(uint32, *int32)
+	getFloatv      func(uint32, *float32)
 
 	// Buffer operations
 	genBuffers    func(int32, *uint32)
@@ -32,11 +53,16 @@ type openGL struct {
 	bufferData    func(uint32, int, unsafe.Pointer, uint32)
 	bufferSubData func(uint32, int, int, unsafe.Pointer)
 
+	mapBufferRange    func(uint32, int, int, uint32) unsafe.Pointer
+	unmapBuffer       func(uint32) uint8
+	generateMipmap    func(uint32)
+	blendFuncSeparate func(uint32, uint32, uint32, uint32)
+
 	// VAO operations
 	genVertexArrays         func(int32, *uint32)
 	deleteVertexArrays      func(int32, *uint32)
 	bindVertexArray         func(uint32)
-	vertexAttribPointer     func(uint32, int32, uint32, bool, int32, unsafe.Pointer)
+	vertexAttribPointer     func(uint32, int32, uint32, bool, int32, uintptr)
 	enableVertexAttribArray func(uint32)
 
 	// Shader operations
@@ -60,11 +86,25 @@ type openGL struct {
 	getUniformLocation func(uint32, *byte) int32
 	getAttribLocation  func(uint32, *byte) int32
 	uniform1i          func(int32, int32)
+	uniform1f          func(int32, float32)
+	uniform2f          func(int32, float32, float32)
 	uniform4f          func(int32, float32, float32, float32, float32)
 	uniformMatrix4fv   func(int32, int32, bool, *float32)
 
 	// Drawing
 	drawArrays func(uint32, int32, int32)
+
+	// Framebuffer operations
+	genFramebuffers         func(int32, *uint32)
+	deleteFramebuffers      func(int32, *uint32)
+	bindFramebuffer         func(uint32, uint32)
+	framebufferTexture2D    func(uint32, uint32, uint32, uint32, int32)
+	checkFramebufferStatus  func(uint32) uint32
+	genRenderbuffers        func(int32, *uint32)
+	deleteRenderbuffers     func(int32, *uint32)
+	bindRenderbuffer        func(uint32, uint32)
+	renderbufferStorage     func(uint32, uint32, int32, int32)
+	framebufferRenderbuffer func(uint32, uint32, uint32, uint32)
 }
 
 func (gl *openGL) ClearColor(r, g, b, a float32) {
@@ -75,10 +115,30 @@ func (gl *openGL) Clear(mask uint32) {
 	gl.clear(mask)
 }
 
+func (gl *openGL) Flush() {
+	gl.flush()
+}
+
+func (gl *openGL) Finish() {
+	gl.finish()
+}
+
 func (gl *openGL) Viewport(x, y, width, height int32) {
 	gl.viewport(x, y, width, height)
 }
 
+func (gl *openGL) Scissor(x, y, width, height int32) {
+	gl.scissor(x, y, width, height)
+}
+
+func (gl *openGL) LineWidth(width float32) {
+	gl.lineWidth(width)
+}
+
+func (gl *openGL) PointSize(size float32) {
+	gl.pointSize(size)
+}
+
 func (gl *openGL) Enable(cap uint32) {
 	gl.enable(cap)
 }
@@ -91,6 +151,10 @@ func (gl *openGL) GenTextures(n int32, textures *uint32) {
 	gl.genTextures(n, textures)
 }
 
+func (gl *openGL) DeleteTextures(n int32, textures *uint32) {
+	gl.deleteTextures(n, textures)
+}
+
 func (gl *openGL) BindTexture(target, texture uint32) {
 	gl.bindTexture(target, texture)
 }
@@ -107,6 +171,10 @@ func (gl *openGL) TexParameteri(target, pname uint32, param int32) {
 	gl.texParameteri(target, pname, param)
 }
 
+func (gl *openGL) TexParameterf(target, pname uint32, param float32) {
+	gl.texParameterf(target, pname, param)
+}
+
 func (gl *openGL) PixelStorei(pname uint32, param int32) {
 	gl.pixelStorei(pname, param)
 }
@@ -119,17 +187,61 @@ func (gl *openGL) BlendFunc(sfactor, dfactor uint32) {
 	gl.blendFunc(sfactor, dfactor)
 }
 
+func (gl *openGL) StencilFunc(fn uint32, ref int32, mask uint32) {
+	gl.stencilFunc(fn, ref, mask)
+}
+
+func (gl *openGL) StencilOp(fail, zfail, zpass uint32) {
+	gl.stencilOp(fail, zfail, zpass)
+}
+
+func (gl *openGL) StencilMask(mask uint32) {
+	gl.stencilMask(mask)
+}
+
+func (gl *openGL) ColorMask(r, g, b, a bool) {
+	gl.colorMask(r, g, b, a)
+}
+
+func (gl *openGL) DepthMask(flag bool) {
+	gl.depthMask(flag)
+}
+
+func (gl *openGL) ClearStencil(s int32) {
+	gl.clearStencil(s)
+}
+
+func (gl *openGL) DepthFunc(fn uint32) {
+	gl.depthFunc(fn)
+}
+
 func (gl *openGL) ReadPixels(x, y, width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
 	// Note: On macOS, glReadPixels reads from the lower-left corner,
 	// so we need to adjust the y coordinate accordingly.
 	gl.readPixels(x, y, width, height, format, xtype, pixels)
 }
 
+func (gl *openGL) GetTexImage(target uint32, level int32, format, xtype uint32, pixels unsafe.Pointer) {
+	gl.getTexImage(target, level, format, xtype, pixels)
+}
+
 func (gl *openGL) GetString(name uint32) string {
 	ptr := gl.getString(name)
 	return gostring((*byte)(unsafe.Pointer(ptr)))
 }
 
+func (gl *openGL) GetStringi(name, index uint32) string {
+	return gostring(gl.getStringi(name, index))
+}
+
+func (gl *openGL) GetIntegerv(pname uint32, data *int32) {
+	gl.getIntegerv(pname, data)
+}
+
+func (gl *openGL) GetFloatv(pname uint32, data *float32) {
+	gl.getFloatv(pname, data)
+}
+
 func (gl *openGL) GenBuffers(n int32, buffers *uint32) {
 	gl.genBuffers(n, buffers)
 }
@@ -150,6 +262,22 @@ func (gl *openGL) BufferSubData(target uint32, offset int, size int, data unsafe
 	gl.bufferSubData(target, offset, size, data)
 }
 
+func (gl *openGL) MapBufferRange(target uint32, offset int, length int, access uint32) unsafe.Pointer {
+	return gl.mapBufferRange(target, offset, length, access)
+}
+
+func (gl *openGL) UnmapBuffer(target uint32) bool {
+	return gl.unmapBuffer(target) != 0
+}
+
+func (gl *openGL) GenerateMipmap(target uint32) {
+	gl.generateMipmap(target)
+}
+
+func (gl *openGL) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha uint32) {
+	gl.blendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha)
+}
+
 func (gl *openGL) GenVertexArrays(n int32, arrays *uint32) {
 	gl.genVertexArrays(n, arrays)
 }
@@ -162,7 +290,7 @@ func (gl *openGL) BindVertexArray(array uint32) {
 	gl.bindVertexArray(array)
 }
 
-func (gl *openGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset unsafe.Pointer) {
+func (gl *openGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset uintptr) {
 	gl.vertexAttribPointer(index, size, xtype, normalized, stride, offset)
 }
 
@@ -255,6 +383,14 @@ func (gl *openGL) Uniform1i(location int32, v0 int32) {
 	gl.uniform1i(location, v0)
 }
 
+func (gl *openGL) Uniform1f(location int32, v0 float32) {
+	gl.uniform1f(location, v0)
+}
+
+func (gl *openGL) Uniform2f(location int32, v0, v1 float32) {
+	gl.uniform2f(location, v0, v1)
+}
+
 func (gl *openGL) Uniform4f(location int32, v0, v1, v2, v3 float32) {
 	gl.uniform4f(location, v0, v1, v2, v3)
 }
@@ -267,6 +403,46 @@ func (gl *openGL) DrawArrays(mode uint32, first int32, count int32) {
 	gl.drawArrays(mode, first, count)
 }
 
+func (gl *openGL) GenFramebuffers(n int32, framebuffers *uint32) {
+	gl.genFramebuffers(n, framebuffers)
+}
+
+func (gl *openGL) DeleteFramebuffers(n int32, framebuffers *uint32) {
+	gl.deleteFramebuffers(n, framebuffers)
+}
+
+func (gl *openGL) BindFramebuffer(target, framebuffer uint32) {
+	gl.bindFramebuffer(target, framebuffer)
+}
+
+func (gl *openGL) FramebufferTexture2D(target, attachment, textarget, texture uint32, level int32) {
+	gl.framebufferTexture2D(target, attachment, textarget, texture, level)
+}
+
+func (gl *openGL) CheckFramebufferStatus(target uint32) uint32 {
+	return gl.checkFramebufferStatus(target)
+}
+
+func (gl *openGL) GenRenderbuffers(n int32, renderbuffers *uint32) {
+	gl.genRenderbuffers(n, renderbuffers)
+}
+
+func (gl *openGL) DeleteRenderbuffers(n int32, renderbuffers *uint32) {
+	gl.deleteRenderbuffers(n, renderbuffers)
+}
+
+func (gl *openGL) BindRenderbuffer(target, renderbuffer uint32) {
+	gl.bindRenderbuffer(target, renderbuffer)
+}
+
+func (gl *openGL) RenderbufferStorage(target, internalformat uint32, width, height int32) {
+	gl.renderbufferStorage(target, internalformat, width, height)
+}
+
+func (gl *openGL) FramebufferRenderbuffer(target, attachment, renderbuffertarget, renderbuffer uint32) {
+	gl.framebufferRenderbuffer(target, attachment, renderbuffertarget, renderbuffer)
+}
+
 func Load() (OpenGL, error) {
 	handle, err := purego.Dlopen("/System/Library/Frameworks/OpenGL.framework/OpenGL", purego.RTLD_GLOBAL|purego.RTLD_LAZY)
 	if err != nil {
@@ -279,19 +455,37 @@ func Load() (OpenGL, error) {
 	gl := &openGL{}
 	register(&gl.clearColor, "glClearColor")
 	register(&gl.clear, "glClear")
+	register(&gl.flush, "glFlush")
+	register(&gl.finish, "glFinish")
 	register(&gl.viewport, "glViewport")
+	register(&gl.scissor, "glScissor")
+	register(&gl.lineWidth, "glLineWidth")
+	register(&gl.pointSize, "glPointSize")
 	register(&gl.enable, "glEnable")
 	register(&gl.disable, "glDisable")
 	register(&gl.genTextures, "glGenTextures")
+	register(&gl.deleteTextures, "glDeleteTextures")
 	register(&gl.bindTexture, "glBindTexture")
 	register(&gl.texImage2D, "glTexImage2D")
 	register(&gl.texSubImage2D, "glTexSubImage2D")
 	register(&gl.texParameteri, "glTexParameteri")
+	register(&gl.texParameterf, "glTexParameterf")
 	register(&gl.pixelStorei, "glPixelStorei")
 	register(&gl.activeTexture, "glActiveTexture")
 	register(&gl.blendFunc, "glBlendFunc")
+	register(&gl.stencilFunc, "glStencilFunc")
+	register(&gl.stencilOp, "glStencilOp")
+	register(&gl.stencilMask, "glStencilMask")
+	register(&gl.colorMask, "glColorMask")
+	register(&gl.depthMask, "glDepthMask")
+	register(&gl.clearStencil, "glClearStencil")
+	register(&gl.depthFunc, "glDepthFunc")
 	register(&gl.readPixels, "glReadPixels")
+	register(&gl.getTexImage, "glGetTexImage")
 	register(&gl.getString, "glGetString")
+	register(&gl.getStringi, "glGetStringi")
+	register(&gl.getIntegerv, "glGetIntegerv")
+	register(&gl.getFloatv, "glGetFloatv")
 
 	// GL3 functions
 	register(&gl.genBuffers, "glGenBuffers")
@@ -299,6 +493,10 @@ func Load() (OpenGL, error) {
 	register(&gl.bindBuffer, "glBindBuffer")
 	register(&gl.bufferData, "glBufferData")
 	register(&gl.bufferSubData, "glBufferSubData")
+	register(&gl.mapBufferRange, "glMapBufferRange")
+	register(&gl.unmapBuffer, "glUnmapBuffer")
+	register(&gl.generateMipmap, "glGenerateMipmap")
+	register(&gl.blendFuncSeparate, "glBlendFuncSeparate")
 	register(&gl.genVertexArrays, "glGenVertexArrays")
 	register(&gl.deleteVertexArrays, "glDeleteVertexArrays")
 	register(&gl.bindVertexArray, "glBindVertexArray")
@@ -320,9 +518,21 @@ func Load() (OpenGL, error) {
 	register(&gl.getUniformLocation, "glGetUniformLocation")
 	register(&gl.getAttribLocation, "glGetAttribLocation")
 	register(&gl.uniform1i, "glUniform1i")
+	register(&gl.uniform1f, "glUniform1f")
+	register(&gl.uniform2f, "glUniform2f")
 	register(&gl.uniform4f, "glUniform4f")
 	register(&gl.uniformMatrix4fv, "glUniformMatrix4fv")
 	register(&gl.drawArrays, "glDrawArrays")
+	register(&gl.genFramebuffers, "glGenFramebuffers")
+	register(&gl.deleteFramebuffers, "glDeleteFramebuffers")
+	register(&gl.bindFramebuffer, "glBindFramebuffer")
+	register(&gl.framebufferTexture2D, "glFramebufferTexture2D")
+	register(&gl.checkFramebufferStatus, "glCheckFramebufferStatus")
+	register(&gl.genRenderbuffers, "glGenRenderbuffers")
+	register(&gl.deleteRenderbuffers, "glDeleteRenderbuffers")
+	register(&gl.bindRenderbuffer, "glBindRenderbuffer")
+	register(&gl.renderbufferStorage, "glRenderbufferStorage")
+	register(&gl.framebufferRenderbuffer, "glFramebufferRenderbuffer")
 
 	return gl, nil
 }
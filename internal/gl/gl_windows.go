@@ -18,22 +18,43 @@ func (p openglProc) Call(args ...uintptr) (uintptr, uintptr, error) {
 	return syscall.SyscallN(uintptr(p), args...)
 }
 
+// openGL must provide every method graphics and text use.
+var _ OpenGL = (*openGL)(nil)
+
 type openGL struct {
-	clearColor    Proc
-	clear         Proc
-	viewport      Proc
-	enable        Proc
-	disable       Proc
-	genTextures   Proc
-	bindTexture   Proc
-	texImage2D    Proc
-	texSubImage2D Proc
-	texParameteri Proc
-	pixelStorei   Proc
-	activeTexture Proc
-	blendFunc     Proc
-	readPixels    Proc
-	getString     Proc
+	clearColor     Proc
+	clear          Proc
+	flush          Proc
+	finish         Proc
+	viewport       Proc
+	scissor        Proc
+	lineWidth      Proc
+	pointSize      Proc
+	enable         Proc
+	disable        Proc
+	genTextures    Proc
+	deleteTextures Proc
+	bindTexture    Proc
+	texImage2D     Proc
+	texSubImage2D  Proc
+	texParameteri  Proc
+	texParameterf  Proc
+	pixelStorei    Proc
+	activeTexture  Proc
+	blendFunc      Proc
+	stencilFunc    Proc
+	stencilOp      Proc
+	stencilMask    Proc
+	colorMask      Proc
+	depthMask      Proc
+	clearStencil   Proc
+	depthFunc      Proc
+	readPixels     Proc
+	getTexImage    Proc
+	getString      Proc
+	getStringi     Proc
+	getIntegerv    Proc
+	getFloatv      Proc
 
 	// Buffer operations
 	genBuffers    Proc
@@ -42,6 +63,11 @@ type openGL struct {
 	bufferData    Proc
 	bufferSubData Proc
 
+	mapBufferRange    Proc
+	unmapBuffer       Proc
+	generateMipmap    Proc
+	blendFuncSeparate Proc
+
 	// VAO operations
 	genVertexArrays         Proc
 	deleteVertexArrays      Proc
@@ -70,11 +96,25 @@ type openGL struct {
 	getUniformLocation Proc
 	getAttribLocation  Proc
 	uniform1i          Proc
+	uniform1f          Proc
+	uniform2f          Proc
 	uniform4f          Proc
 	uniformMatrix4fv   Proc
 
 	// Drawing
 	drawArrays Proc
+
+	// Framebuffer operations
+	genFramebuffers         Proc
+	deleteFramebuffers      Proc
+	bindFramebuffer         Proc
+	framebufferTexture2D    Proc
+	checkFramebufferStatus  Proc
+	genRenderbuffers        Proc
+	deleteRenderbuffers     Proc
+	bindRenderbuffer        Proc
+	renderbufferStorage     Proc
+	framebufferRenderbuffer Proc
 }
 
 func (gl *openGL) ClearColor(r, g, b, a float32) {
@@ -85,10 +125,30 @@ func (gl *openGL) Clear(mask uint32) {
 	gl.clear.Call(uintptr(mask))
 }
 
+func (gl *openGL) Flush() {
+	gl.flush.Call()
+}
+
+func (gl *openGL) Finish() {
+	gl.finish.Call()
+}
+
 func (gl *openGL) Viewport(x, y, width, height int32) {
 	gl.viewport.Call(uintptr(x), uintptr(y), uintptr(width), uintptr(height))
 }
 
+func (gl *openGL) Scissor(x, y, width, height int32) {
+	gl.scissor.Call(uintptr(x), uintptr(y), uintptr(width), uintptr(height))
+}
+
+func (gl *openGL) LineWidth(width float32) {
+	gl.lineWidth.Call(f32(width))
+}
+
+func (gl *openGL) PointSize(size float32) {
+	gl.pointSize.Call(f32(size))
+}
+
 func (gl *openGL) Enable(cap uint32) {
 	gl.enable.Call(uintptr(cap))
 }
@@ -101,6 +161,10 @@ func (gl *openGL) GenTextures(n int32, textures *uint32) {
 	gl.genTextures.Call(uintptr(n), uintptr(unsafe.Pointer(textures)))
 }
 
+func (gl *openGL) DeleteTextures(n int32, textures *uint32) {
+	gl.deleteTextures.Call(uintptr(n), uintptr(unsafe.Pointer(textures)))
+}
+
 func (gl *openGL) BindTexture(target, texture uint32) {
 	gl.bindTexture.Call(uintptr(target), uintptr(texture))
 }
@@ -117,6 +181,10 @@ func (gl *openGL) TexParameteri(target, pname uint32, param int32) {
 	gl.texParameteri.Call(uintptr(target), uintptr(pname), uintptr(param))
 }
 
+func (gl *openGL) TexParameterf(target, pname uint32, param float32) {
+	gl.texParameterf.Call(uintptr(target), uintptr(pname), f32(param))
+}
+
 func (gl *openGL) PixelStorei(pname uint32, param int32) {
 	gl.pixelStorei.Call(uintptr(pname), uintptr(param))
 }
@@ -129,13 +197,59 @@ func (gl *openGL) BlendFunc(sfactor, dfactor uint32) {
 	gl.blendFunc.Call(uintptr(sfactor), uintptr(dfactor))
 }
 
+func (gl *openGL) StencilFunc(fn uint32, ref int32, mask uint32) {
+	gl.stencilFunc.Call(uintptr(fn), uintptr(ref), uintptr(mask))
+}
+
+func (gl *openGL) StencilOp(fail, zfail, zpass uint32) {
+	gl.stencilOp.Call(uintptr(fail), uintptr(zfail), uintptr(zpass))
+}
+
+func (gl *openGL) StencilMask(mask uint32) {
+	gl.stencilMask.Call(uintptr(mask))
+}
+
+func (gl *openGL) ColorMask(r, g, b, a bool) {
+	gl.colorMask.Call(boolean(r), boolean(g), boolean(b), boolean(a))
+}
+
+func (gl *openGL) DepthMask(flag bool) {
+	gl.depthMask.Call(boolean(flag))
+}
+
+func (gl *openGL) ClearStencil(s int32) {
+	gl.clearStencil.Call(uintptr(s))
+}
+
+func (gl *openGL) DepthFunc(fn uint32) {
+	gl.depthFunc.Call(uintptr(fn))
+}
+
 func (gl *openGL) ReadPixels(x, y, width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
 	gl.readPixels.Call(uintptr(x), uintptr(y), uintptr(width), uintptr(height), uintptr(format), uintptr(xtype), uintptr(pixels))
 }
 
+func (gl *openGL) GetTexImage(target uint32, level int32, format, xtype uint32, pixels unsafe.Pointer) {
+	gl.getTexImage.Call(uintptr(target), uintptr(level), uintptr(format), uintptr(xtype), uintptr(pixels))
+}
+
 func (gl *openGL) GetString(name uint32) string {
-	ptr, _, _ := gl.getString.Call(uintptr(name))
-	return gostring((*byte)(unsafe.Pointer(ptr)))
+	// The string is owned by the driver, not Go, so the GC never moves it.
+	ret, _, _ := gl.getString.Call(uintptr(name))
+	return gostring((*byte)(unsafe.Pointer(ret)))
+}
+
+func (gl *openGL) GetStringi(name, index uint32) string {
+	ret, _, _ := gl.getStringi.Call(uintptr(name), uintptr(index))
+	return gostring((*byte)(unsafe.Pointer(ret)))
+}
+
+func (gl *openGL) GetIntegerv(pname uint32, data *int32) {
+	gl.getIntegerv.Call(uintptr(pname), uintptr(unsafe.Pointer(data)))
+}
+
+func (gl *openGL) GetFloatv(pname uint32, data *float32) {
+	gl.getFloatv.Call(uintptr(pname), uintptr(unsafe.Pointer(data)))
 }
 
 func (gl *openGL) GenBuffers(n int32, buffers *uint32) {
@@ -158,6 +272,25 @@ func (gl *openGL) BufferSubData(target uint32, offset int, size int, data unsafe
 	gl.bufferSubData.Call(uintptr(target), uintptr(offset), uintptr(size), uintptr(data))
 }
 
+func (gl *openGL) MapBufferRange(target uint32, offset int, length int, access uint32) unsafe.Pointer {
+	// The mapping is memory owned by the driver, outside the Go heap.
+	ret, _, _ := gl.mapBufferRange.Call(uintptr(target), uintptr(offset), uintptr(length), uintptr(access))
+	return unsafe.Pointer(ret)
+}
+
+func (gl *openGL) UnmapBuffer(target uint32) bool {
+	ret, _, _ := gl.unmapBuffer.Call(uintptr(target))
+	return uint8(ret) != 0
+}
+
+func (gl *openGL) GenerateMipmap(target uint32) {
+	gl.generateMipmap.Call(uintptr(target))
+}
+
+func (gl *openGL) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha uint32) {
+	gl.blendFuncSeparate.Call(uintptr(srcRGB), uintptr(dstRGB), uintptr(srcAlpha), uintptr(dstAlpha))
+}
+
 func (gl *openGL) GenVertexArrays(n int32, arrays *uint32) {
 	gl.genVertexArrays.Call(uintptr(n), uintptr(unsafe.Pointer(arrays)))
 }
@@ -170,12 +303,12 @@ func (gl *openGL) BindVertexArray(array uint32) {
 	gl.bindVertexArray.Call(uintptr(array))
 }
 
-func (gl *openGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset unsafe.Pointer) {
+func (gl *openGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset uintptr) {
 	var norm uintptr
 	if normalized {
 		norm = 1
 	}
-	gl.vertexAttribPointer.Call(uintptr(index), uintptr(size), uintptr(xtype), norm, uintptr(stride), uintptr(offset))
+	gl.vertexAttribPointer.Call(uintptr(index), uintptr(size), uintptr(xtype), norm, uintptr(stride), offset)
 }
 
 func (gl *openGL) EnableVertexAttribArray(index uint32) {
@@ -273,6 +406,14 @@ func (gl *openGL) Uniform1i(location int32, v0 int32) {
 	gl.uniform1i.Call(uintptr(location), uintptr(v0))
 }
 
+func (gl *openGL) Uniform1f(location int32, v0 float32) {
+	gl.uniform1f.Call(uintptr(location), f32(v0))
+}
+
+func (gl *openGL) Uniform2f(location int32, v0, v1 float32) {
+	gl.uniform2f.Call(uintptr(location), f32(v0), f32(v1))
+}
+
 func (gl *openGL) Uniform4f(location int32, v0, v1, v2, v3 float32) {
 	gl.uniform4f.Call(uintptr(location), f32(v0), f32(v1), f32(v2), f32(v3))
 }
@@ -289,6 +430,47 @@ func (gl *openGL) DrawArrays(mode uint32, first int32, count int32) {
 	gl.drawArrays.Call(uintptr(mode), uintptr(first), uintptr(count))
 }
 
+func (gl *openGL) GenFramebuffers(n int32, framebuffers *uint32) {
+	gl.genFramebuffers.Call(uintptr(n), uintptr(unsafe.Pointer(framebuffers)))
+}
+
+func (gl *openGL) DeleteFramebuffers(n int32, framebuffers *uint32) {
+	gl.deleteFramebuffers.Call(uintptr(n), uintptr(unsafe.Pointer(framebuffers)))
+}
+
+func (gl *openGL) BindFramebuffer(target, framebuffer uint32) {
+	gl.bindFramebuffer.Call(uintptr(target), uintptr(framebuffer))
+}
+
+func (gl *openGL) FramebufferTexture2D(target, attachment, textarget, texture uint32, level int32) {
+	gl.framebufferTexture2D.Call(uintptr(target), uintptr(attachment), uintptr(textarget), uintptr(texture), uintptr(level))
+}
+
+func (gl *openGL) CheckFramebufferStatus(target uint32) uint32 {
+	ret, _, _ := gl.checkFramebufferStatus.Call(uintptr(target))
+	return uint32(ret)
+}
+
+func (gl *openGL) GenRenderbuffers(n int32, renderbuffers *uint32) {
+	gl.genRenderbuffers.Call(uintptr(n), uintptr(unsafe.Pointer(renderbuffers)))
+}
+
+func (gl *openGL) DeleteRenderbuffers(n int32, renderbuffers *uint32) {
+	gl.deleteRenderbuffers.Call(uintptr(n), uintptr(unsafe.Pointer(renderbuffers)))
+}
+
+func (gl *openGL) BindRenderbuffer(target, renderbuffer uint32) {
+	gl.bindRenderbuffer.Call(uintptr(target), uintptr(renderbuffer))
+}
+
+func (gl *openGL) RenderbufferStorage(target, internalformat uint32, width, height int32) {
+	gl.renderbufferStorage.Call(uintptr(target), uintptr(internalformat), uintptr(width), uintptr(height))
+}
+
+func (gl *openGL) FramebufferRenderbuffer(target, attachment, renderbuffertarget, renderbuffer uint32) {
+	gl.framebufferRenderbuffer.Call(uintptr(target), uintptr(attachment), uintptr(renderbuffertarget), uintptr(renderbuffer))
+}
+
 func Load() (OpenGL, error) {
 	opengl32 := syscall.NewLazyDLL("opengl32.dll")
 	wglGetProcAddress := opengl32.NewProc("wglGetProcAddress")
@@ -308,21 +490,39 @@ func Load() (OpenGL, error) {
 	}
 
 	gl := &openGL{
-		clearColor:    opengl32.NewProc("glClearColor"),
-		clear:         opengl32.NewProc("glClear"),
-		viewport:      opengl32.NewProc("glViewport"),
-		enable:        opengl32.NewProc("glEnable"),
-		disable:       opengl32.NewProc("glDisable"),
-		genTextures:   opengl32.NewProc("glGenTextures"),
-		bindTexture:   opengl32.NewProc("glBindTexture"),
-		texImage2D:    opengl32.NewProc("glTexImage2D"),
-		texSubImage2D: opengl32.NewProc("glTexSubImage2D"),
-		texParameteri: opengl32.NewProc("glTexParameteri"),
-		pixelStorei:   opengl32.NewProc("glPixelStorei"),
-		activeTexture: loadProc("glActiveTexture"),
-		blendFunc:     opengl32.NewProc("glBlendFunc"),
-		readPixels:    opengl32.NewProc("glReadPixels"),
-		getString:     opengl32.NewProc("glGetString"),
+		clearColor:     opengl32.NewProc("glClearColor"),
+		clear:          opengl32.NewProc("glClear"),
+		flush:          opengl32.NewProc("glFlush"),
+		finish:         opengl32.NewProc("glFinish"),
+		viewport:       opengl32.NewProc("glViewport"),
+		scissor:        opengl32.NewProc("glScissor"),
+		lineWidth:      opengl32.NewProc("glLineWidth"),
+		pointSize:      opengl32.NewProc("glPointSize"),
+		enable:         opengl32.NewProc("glEnable"),
+		disable:        opengl32.NewProc("glDisable"),
+		genTextures:    opengl32.NewProc("glGenTextures"),
+		deleteTextures: opengl32.NewProc("glDeleteTextures"),
+		bindTexture:    opengl32.NewProc("glBindTexture"),
+		texImage2D:     opengl32.NewProc("glTexImage2D"),
+		texSubImage2D:  opengl32.NewProc("glTexSubImage2D"),
+		texParameteri:  opengl32.NewProc("glTexParameteri"),
+		texParameterf:  opengl32.NewProc("glTexParameterf"),
+		pixelStorei:    opengl32.NewProc("glPixelStorei"),
+		activeTexture:  loadProc("glActiveTexture"),
+		blendFunc:      opengl32.NewProc("glBlendFunc"),
+		stencilFunc:    opengl32.NewProc("glStencilFunc"),
+		stencilOp:      opengl32.NewProc("glStencilOp"),
+		stencilMask:    opengl32.NewProc("glStencilMask"),
+		colorMask:      opengl32.NewProc("glColorMask"),
+		depthMask:      opengl32.NewProc("glDepthMask"),
+		clearStencil:   opengl32.NewProc("glClearStencil"),
+		depthFunc:      opengl32.NewProc("glDepthFunc"),
+		readPixels:     opengl32.NewProc("glReadPixels"),
+		getTexImage:    opengl32.NewProc("glGetTexImage"),
+		getString:      opengl32.NewProc("glGetString"),
+		getStringi:     loadProc("glGetStringi"),
+		getIntegerv:    opengl32.NewProc("glGetIntegerv"),
+		getFloatv:      opengl32.NewProc("glGetFloatv"),
 
 		// GL3 functions via wglGetProcAddress
 		genBuffers:              loadProc("glGenBuffers"),
@@ -330,6 +530,10 @@ func Load() (OpenGL, error) {
 		bindBuffer:              loadProc("glBindBuffer"),
 		bufferData:              loadProc("glBufferData"),
 		bufferSubData:           loadProc("glBufferSubData"),
+		mapBufferRange:          loadProc("glMapBufferRange"),
+		unmapBuffer:             loadProc("glUnmapBuffer"),
+		generateMipmap:          loadProc("glGenerateMipmap"),
+		blendFuncSeparate:       loadProc("glBlendFuncSeparate"),
 		genVertexArrays:         loadProc("glGenVertexArrays"),
 		deleteVertexArrays:      loadProc("glDeleteVertexArrays"),
 		bindVertexArray:         loadProc("glBindVertexArray"),
@@ -351,9 +555,21 @@ func Load() (OpenGL, error) {
 		getUniformLocation:      loadProc("glGetUniformLocation"),
 		getAttribLocation:       loadProc("glGetAttribLocation"),
 		uniform1i:               loadProc("glUniform1i"),
+		uniform1f:               loadProc("glUniform1f"),
+		uniform2f:               loadProc("glUniform2f"),
 		uniform4f:               loadProc("glUniform4f"),
 		uniformMatrix4fv:        loadProc("glUniformMatrix4fv"),
 		drawArrays:              loadProc("glDrawArrays"),
+		genFramebuffers:         loadProc("glGenFramebuffers"),
+		deleteFramebuffers:      loadProc("glDeleteFramebuffers"),
+		bindFramebuffer:         loadProc("glBindFramebuffer"),
+		framebufferTexture2D:    loadProc("glFramebufferTexture2D"),
+		checkFramebufferStatus:  loadProc("glCheckFramebufferStatus"),
+		genRenderbuffers:        loadProc("glGenRenderbuffers"),
+		deleteRenderbuffers:     loadProc("glDeleteRenderbuffers"),
+		bindRenderbuffer:        loadProc("glBindRenderbuffer"),
+		renderbufferStorage:     loadProc("glRenderbufferStorage"),
+		framebufferRenderbuffer: loadProc("glFramebufferRenderbuffer"),
 	}
 	return gl, nil
 }
@@ -365,3 +581,10 @@ func f32(v float32) uintptr {
 func f64(v float64) uintptr {
 	return uintptr(math.Float64bits(v))
 }
+
+func boolean(v bool) uintptr {
+	if v {
+		return 1
+	}
+	return 0
+}
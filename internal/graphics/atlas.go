@@ -0,0 +1,141 @@
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// maxAtlasSize is the widest atlas Build will try, matching the largest
+// GL_MAX_TEXTURE_SIZE in common use. Build fails earlier if the context's
+// limit is lower.
+const maxAtlasSize = 16384
+
+// AtlasPacker collects images and packs them into a single texture, so
+// that many small sprites can be drawn without switching textures. Its
+// zero value is ready to use: Add the images, then Build the atlas.
+type AtlasPacker struct {
+	// Padding is the number of transparent pixels left around each image.
+	// With linear filtering or mipmaps, use at least 1 so neighbours don't
+	// bleed into each other's edges.
+	Padding int
+
+	images []image.Image
+}
+
+// AtlasRegion is where an image was placed in an atlas: Rect in pixels,
+// and the matching texture coordinates for RenderQuadUV.
+type AtlasRegion struct {
+	Rect           image.Rectangle
+	U0, V0, U1, V1 float32
+}
+
+// Atlas is a texture built by AtlasPacker.Build. Regions holds the
+// placement of each image, indexed by the value Add returned for it.
+type Atlas struct {
+	Texture Texture
+	Regions []AtlasRegion
+}
+
+// Add queues img for packing and returns its index in Atlas.Regions.
+func (p *AtlasPacker) Add(img image.Image) int {
+	p.images = append(p.images, img)
+	return len(p.images) - 1
+}
+
+// Len returns the number of images added.
+func (p *AtlasPacker) Len() int {
+	return len(p.images)
+}
+
+// Build packs the images added so far into one texture created on w with
+// opts. Images are placed on shelves, tallest first, in the narrowest
+// power-of-two width that keeps the atlas roughly square. Like other
+// texture creation it must be called on the Loop goroutine.
+func (p *AtlasPacker) Build(w Window, opts TextureOptions) (*Atlas, error) {
+	if len(p.images) == 0 {
+		return nil, errors.New("atlas has no images")
+	}
+
+	// Tallest first wastes the least space above shorter images on a shelf.
+	order := make([]int, len(p.images))
+	area := 0
+	widest := 0
+	for i, img := range p.images {
+		order[i] = i
+		b := img.Bounds()
+		area += (b.Dx() + p.Padding) * (b.Dy() + p.Padding)
+		widest = max(widest, b.Dx()+2*p.Padding)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return p.images[order[a]].Bounds().Dy() > p.images[order[b]].Bounds().Dy()
+	})
+
+	width := 1
+	for width < widest || width*width < area {
+		width *= 2
+	}
+	var rects []image.Rectangle
+	var height int
+	for ; width <= maxAtlasSize; width *= 2 {
+		rects, height = p.pack(order, width)
+		if height <= width {
+			break
+		}
+	}
+	if width > maxAtlasSize {
+		return nil, errors.New("images do not fit in the largest atlas texture")
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	regions := make([]AtlasRegion, len(p.images))
+	for i, img := range p.images {
+		r := rects[i]
+		draw.Draw(dst, r, img, img.Bounds().Min, draw.Src)
+		regions[i] = AtlasRegion{
+			Rect: r,
+			U0:   float32(r.Min.X) / float32(width),
+			V0:   float32(r.Min.Y) / float32(height),
+			U1:   float32(r.Max.X) / float32(width),
+			V1:   float32(r.Max.Y) / float32(height),
+		}
+	}
+
+	tex, err := w.NewTextureWithOptions(dst, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Atlas{Texture: tex, Regions: regions}, nil
+}
+
+// pack places the images in order on shelves width pixels wide and returns
+// their rectangles, indexed like p.images, and the height used.
+func (p *AtlasPacker) pack(order []int, width int) ([]image.Rectangle, int) {
+	rects := make([]image.Rectangle, len(p.images))
+	x, y := p.Padding, p.Padding
+	shelf := 0 // height of the current shelf
+	for _, i := range order {
+		b := p.images[i].Bounds()
+		if x+b.Dx()+p.Padding > width {
+			x = p.Padding
+			y += shelf + p.Padding
+			shelf = 0
+		}
+		rects[i] = image.Rect(x, y, x+b.Dx(), y+b.Dy())
+		x += b.Dx() + p.Padding
+		shelf = max(shelf, b.Dy())
+	}
+	return rects, y + shelf + p.Padding
+}
+
+// Draw draws the image at index i at (x, y) with the given size, tinted by
+// c as RenderQuad does. A zero width or height uses the image's own size.
+func (a *Atlas) Draw(f Frame, i int, x, y, width, height float32, c color.Color) {
+	r := a.Regions[i]
+	if width == 0 || height == 0 {
+		width, height = float32(r.Rect.Dx()), float32(r.Rect.Dy())
+	}
+	f.RenderQuadUV(x, y, width, height, a.Texture, r.U0, r.V0, r.U1, r.V1, c)
+}
@@ -0,0 +1,46 @@
+package graphics
+
+import "image/color"
+
+// SpriteBatch collects quads that sample the same texture, so that
+// Frame.DrawBatch can draw thousands of them in one draw call where
+// RenderQuad would issue one each. Reset and refill it every frame to reuse
+// its memory.
+type SpriteBatch struct {
+	tex      *glTexture
+	vertices []float32
+}
+
+// NewSpriteBatch returns an empty batch drawing with tex.
+func NewSpriteBatch(tex Texture) *SpriteBatch {
+	t, _ := tex.(*glTexture)
+	return &SpriteBatch{tex: t}
+}
+
+// Add queues a quad covering the whole texture, as RenderQuad draws.
+func (b *SpriteBatch) Add(x, y, width, height float32, c color.Color) {
+	b.vertices = appendQuad(b.vertices, x, y, 0, width, height, 0, 0, 1, 1, ColorToFloat32(c))
+}
+
+// AddUV queues a quad covering part of the texture, as RenderQuadUV draws.
+func (b *SpriteBatch) AddUV(x, y, width, height, u0, v0, u1, v1 float32, c color.Color) {
+	b.vertices = appendQuad(b.vertices, x, y, 0, width, height, u0, v0, u1, v1, ColorToFloat32(c))
+}
+
+// Len returns the number of quads queued.
+func (b *SpriteBatch) Len() int {
+	return len(b.vertices) / (6 * vertexFloats)
+}
+
+// Reset empties the batch, keeping its memory.
+func (b *SpriteBatch) Reset() {
+	b.vertices = b.vertices[:0]
+}
+
+// DrawBatch implements Frame.
+func (f glFrame) DrawBatch(b *SpriteBatch) {
+	if b.tex == nil || len(b.vertices) == 0 {
+		return
+	}
+	f.drawTriangles(b.tex, b.vertices)
+}
@@ -0,0 +1,120 @@
+package graphics
+
+import (
+	"image"
+	"math"
+
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+// zoomStep is how much one step passed to ZoomAtCursor zooms in.
+const zoomStep = 1.1
+
+// Camera2D pans and zooms a 2D scene, such as an image or a remote desktop,
+// by setting the view with Frame.SetView. The scene is drawn in world
+// coordinates; the camera decides which part of it fills the view. The
+// zero value shows the world unscaled from the origin.
+type Camera2D struct {
+	// X and Y are the world point at the top-left corner of the view.
+	X, Y float32
+	// Zoom is how many view units one world unit covers. Zero means 1.
+	Zoom float32
+	// MinZoom and MaxZoom limit Zoom when non-zero.
+	MinZoom, MaxZoom float32
+
+	// Bounds, if not empty, is the content in world coordinates. The
+	// camera doesn't pan past its edges, and centres it on any axis where
+	// it is smaller than the view.
+	Bounds image.Rectangle
+
+	dragging     bool
+	dragX, dragY float32
+}
+
+// Apply keeps the camera within Bounds and sets the frame's view to it.
+// Call it each frame before drawing the scene.
+func (c *Camera2D) Apply(f Frame) {
+	vw, vh := f.WindowSizeLogical()
+	c.clamp(float32(vw), float32(vh))
+	f.SetView(c.View())
+}
+
+// View returns the view matrix for the camera.
+func (c *Camera2D) View() [16]float32 {
+	z := c.zoom()
+	return PanZoom(-c.X*z, -c.Y*z, z)
+}
+
+// ToWorld converts a point in view coordinates, such as CursorPos, to the
+// world point the camera shows there.
+func (c *Camera2D) ToWorld(x, y float32) (float32, float32) {
+	z := c.zoom()
+	return c.X + x/z, c.Y + y/z
+}
+
+// ZoomAt multiplies Zoom by factor, within MinZoom and MaxZoom, keeping
+// the world point under the view point (x, y) where it is.
+func (c *Camera2D) ZoomAt(factor, x, y float32) {
+	wx, wy := c.ToWorld(x, y)
+	z := c.zoom() * factor
+	if c.MinZoom > 0 {
+		z = max(z, c.MinZoom)
+	}
+	if c.MaxZoom > 0 {
+		z = min(z, c.MaxZoom)
+	}
+	c.Zoom = z
+	c.X, c.Y = wx-x/z, wy-y/z
+}
+
+// ZoomAtCursor zooms in by steps, such as mouse wheel notches, towards the
+// cursor; negative steps zoom out.
+func (c *Camera2D) ZoomAtCursor(f Frame, steps float32) {
+	x, y := f.CursorPos()
+	c.ZoomAt(float32(math.Pow(zoomStep, float64(steps))), x, y)
+}
+
+// DragPan pans the camera with the cursor while button is held, starting
+// from a press inside the window, and reports whether a drag is under way.
+// Call it each frame before Apply.
+func (c *Camera2D) DragPan(f Frame, button window.Button) bool {
+	x, y := f.CursorPos()
+	switch {
+	case f.ButtonJustPressed(button) && f.MouseInWindow():
+		c.dragging = true
+	case !f.ButtonDown(button):
+		c.dragging = false
+	case c.dragging:
+		z := c.zoom()
+		c.X -= (x - c.dragX) / z
+		c.Y -= (y - c.dragY) / z
+	}
+	c.dragX, c.dragY = x, y
+	return c.dragging
+}
+
+func (c *Camera2D) zoom() float32 {
+	if c.Zoom == 0 {
+		return 1
+	}
+	return c.Zoom
+}
+
+// clamp moves the camera so a vw x vh view stays within Bounds.
+func (c *Camera2D) clamp(vw, vh float32) {
+	if c.Bounds.Empty() {
+		return
+	}
+	z := c.zoom()
+	c.X = clampAxis(c.X, vw/z, float32(c.Bounds.Min.X), float32(c.Bounds.Max.X))
+	c.Y = clampAxis(c.Y, vh/z, float32(c.Bounds.Min.Y), float32(c.Bounds.Max.Y))
+}
+
+// clampAxis returns the start of a span of size visible kept within lo..hi,
+// or centred on it if it is the larger of the two.
+func clampAxis(pos, visible, lo, hi float32) float32 {
+	if visible >= hi-lo {
+		return lo - (visible-(hi-lo))/2
+	}
+	return max(lo, min(pos, hi-visible))
+}
@@ -0,0 +1,26 @@
+package graphics
+
+import "time"
+
+// Clock is where Loop gets the time: frame timestamps for Frame.DeltaTime,
+// u_time for post-process shaders, and the pause between frames in
+// RedrawContinuous mode. Install one with Window.SetClock to step a loop
+// through synthetic time, e.g. in a test, without real sleeps.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// RealClock is the default Clock, backed by the time package.
+type RealClock struct{}
+
+func (RealClock) Now() time.Time        { return time.Now() }
+func (RealClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SetClock implements Window.
+func (w *glWindow) SetClock(c Clock) {
+	if c == nil {
+		c = RealClock{}
+	}
+	w.clock = c
+}
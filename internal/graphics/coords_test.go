@@ -0,0 +1,177 @@
+package graphics
+
+import (
+	"image"
+	"testing"
+)
+
+// coordCase sets up a window of the given backing size and scale, with a
+// logical size if logicalW is non-zero, and gives the view rectangle the
+// frame should be drawn into.
+type coordCase struct {
+	name               string
+	bw, bh             int
+	scale              float32
+	logicalW, logicalH int
+	mode               ScaleMode
+	wantView           image.Rectangle
+}
+
+var coordCases = []coordCase{
+	{name: "physical", bw: 800, bh: 600, scale: 1,
+		wantView: image.Rect(0, 0, 800, 600)},
+	{name: "hidpi", bw: 1600, bh: 1200, scale: 2,
+		wantView: image.Rect(0, 0, 1600, 1200)},
+	{name: "fit letterboxed", bw: 800, bh: 700, scale: 1, logicalW: 320, logicalH: 240, mode: ScaleFit,
+		wantView: image.Rect(0, 50, 800, 650)},
+	{name: "fit pillarboxed", bw: 1000, bh: 480, scale: 1, logicalW: 320, logicalH: 240, mode: ScaleFit,
+		wantView: image.Rect(180, 0, 820, 480)},
+	{name: "integer", bw: 800, bh: 700, scale: 1, logicalW: 320, logicalH: 240, mode: ScaleInteger,
+		wantView: image.Rect(80, 110, 720, 590)},
+	{name: "integer below 1", bw: 160, bh: 120, scale: 1, logicalW: 320, logicalH: 240, mode: ScaleInteger,
+		wantView: image.Rect(0, 0, 160, 120)},
+	{name: "stretch", bw: 800, bh: 700, scale: 1, logicalW: 320, logicalH: 240, mode: ScaleStretch,
+		wantView: image.Rect(0, 0, 800, 700)},
+}
+
+// setup returns a test window for c with a frame prepared.
+func (c coordCase) setup(t *testing.T) (*glWindow, *fakePlatform) {
+	t.Helper()
+	w, _ := newTestWindow(t, c.bw, c.bh)
+	p := w.platform.(*fakePlatform)
+	p.scale = c.scale
+	if c.logicalW > 0 {
+		w.SetLogicalSize(c.logicalW, c.logicalH, c.mode)
+	}
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	return w, p
+}
+
+func TestMapView(t *testing.T) {
+	for _, c := range coordCases {
+		t.Run(c.name, func(t *testing.T) {
+			w, _ := c.setup(t)
+			if w.viewRect != c.wantView {
+				t.Errorf("view = %v, want %v", w.viewRect, c.wantView)
+			}
+		})
+	}
+}
+
+func TestCursorPosAndToPixels(t *testing.T) {
+	tests := []struct {
+		coord  string
+		cx, cy float32 // cursor in backing pixels
+		wantX  float32 // CursorPos
+		wantY  float32
+	}{
+		{"physical", 100, 50, 100, 50},
+		{"hidpi", 200, 100, 100, 50},
+		{"fit letterboxed", 400, 350, 160, 120},
+		{"fit letterboxed", 0, 50, 0, 0},
+		{"fit letterboxed", 400, 10, 160, -16}, // in the top bar
+		{"fit pillarboxed", 180, 0, 0, 0},
+		{"integer", 80, 110, 0, 0},
+		{"integer", 720, 590, 320, 240},
+		{"stretch", 400, 350, 160, 120},
+	}
+	for _, tt := range tests {
+		c := findCoordCase(t, tt.coord)
+		t.Run(c.name, func(t *testing.T) {
+			w, p := c.setup(t)
+			f := glFrame{w: w}
+			p.cursorX, p.cursorY = tt.cx, tt.cy
+			if x, y := f.CursorPos(); x != tt.wantX || y != tt.wantY {
+				t.Errorf("CursorPos() with the cursor at (%g, %g) = (%g, %g), want (%g, %g)",
+					tt.cx, tt.cy, x, y, tt.wantX, tt.wantY)
+			}
+			// toPixels is the inverse.
+			if px, py := f.toPixels(tt.wantX, tt.wantY); px != int(tt.cx) || py != int(tt.cy) {
+				t.Errorf("toPixels(%g, %g) = (%d, %d), want (%g, %g)",
+					tt.wantX, tt.wantY, px, py, tt.cx, tt.cy)
+			}
+		})
+	}
+}
+
+func TestViewToBacking(t *testing.T) {
+	tests := []struct {
+		coord      string
+		x, y, w, h float32
+		want       image.Rectangle
+	}{
+		{"physical", 10, 20, 30, 40, image.Rect(10, 20, 40, 60)},
+		{"physical", -10, -10, 30, 30, image.Rect(0, 0, 20, 20)},
+		{"hidpi", 10, 20, 30, 40, image.Rect(20, 40, 80, 120)},
+		{"hidpi", 0.25, 0.25, 0.5, 0.5, image.Rect(0, 0, 2, 2)}, // covers partial pixels
+		{"fit letterboxed", 0, 0, 320, 240, image.Rect(0, 50, 800, 650)},
+		{"fit letterboxed", 10, 10, 20, 20, image.Rect(25, 75, 75, 125)},
+		{"fit letterboxed", 0, -100, 320, 100, image.Rectangle{}}, // all in the bar
+		{"integer", 0, 0, 1, 1, image.Rect(80, 110, 82, 112)},
+		{"stretch", 0, 0, 320, 240, image.Rect(0, 0, 800, 700)},
+	}
+	for _, tt := range tests {
+		c := findCoordCase(t, tt.coord)
+		t.Run(c.name, func(t *testing.T) {
+			win, _ := c.setup(t)
+			got := win.viewToBacking(tt.x, tt.y, tt.w, tt.h)
+			if got != tt.want && !(got.Empty() && tt.want.Empty()) {
+				t.Errorf("viewToBacking(%g, %g, %g, %g) = %v, want %v", tt.x, tt.y, tt.w, tt.h, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPixelAt(t *testing.T) {
+	tests := []struct {
+		coord   string
+		x, y    int
+		wantGL  [2]int32 // origin passed to ReadPixels, from the bottom
+		outside bool
+	}{
+		{coord: "physical", x: 0, y: 0, wantGL: [2]int32{0, 599}},
+		{coord: "physical", x: 799, y: 599, wantGL: [2]int32{799, 0}},
+		{coord: "physical", x: 800, y: 0, outside: true},
+		{coord: "physical", x: -1, y: 0, outside: true},
+		{coord: "hidpi", x: 10, y: 20, wantGL: [2]int32{20, 1159}},
+		{coord: "fit letterboxed", x: 0, y: 0, wantGL: [2]int32{0, 649}},
+		{coord: "fit letterboxed", x: 0, y: -20, wantGL: [2]int32{0, 699}}, // in the top bar
+		{coord: "fit letterboxed", x: 0, y: -21, outside: true},
+	}
+	for _, tt := range tests {
+		c := findCoordCase(t, tt.coord)
+		t.Run(c.name, func(t *testing.T) {
+			w, _ := c.setup(t)
+			gl := w.gl.(*fakeGL)
+			before := gl.calls["ReadPixels"]
+			_, err := glFrame{w: w}.PixelAt(tt.x, tt.y)
+			if tt.outside {
+				if err == nil {
+					t.Errorf("PixelAt(%d, %d) outside the window succeeded", tt.x, tt.y)
+				}
+				if gl.calls["ReadPixels"] != before {
+					t.Error("read pixels for a point outside the window")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PixelAt(%d, %d): %v", tt.x, tt.y, err)
+			}
+			if gl.readAt != tt.wantGL {
+				t.Errorf("PixelAt(%d, %d) read at %v, want %v", tt.x, tt.y, gl.readAt, tt.wantGL)
+			}
+		})
+	}
+}
+
+func findCoordCase(t *testing.T, name string) coordCase {
+	for _, c := range coordCases {
+		if c.name == name {
+			return c
+		}
+	}
+	t.Fatalf("no coordinate case %q", name)
+	return coordCase{}
+}
@@ -0,0 +1,46 @@
+package graphics
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var debugChecks atomic.Bool
+
+// SetDebug turns on checks for misuse that would otherwise crash or
+// corrupt GL state without a clear cause. Currently that means panicking
+// when a Window method that makes GL calls, such as NewTexture, is called
+// from a goroutine other than the one that created the window; use
+// EnqueueGL from other goroutines instead. The checks add a little cost to
+// each of those calls, so they are off by default.
+func SetDebug(enabled bool) {
+	debugChecks.Store(enabled)
+}
+
+// checkGoroutine panics, when debug checks are on, if it isn't called
+// from the goroutine that created w. method names the caller in the
+// message.
+func (w *glWindow) checkGoroutine(method string) {
+	if !debugChecks.Load() {
+		return
+	}
+	if id := goroutineID(); id != w.owner {
+		panic(fmt.Sprintf("graphics: %s called from goroutine %d, but the window was created on goroutine %d; "+
+			"GL calls must be made from that goroutine, e.g. via EnqueueGL", method, id, w.owner))
+	}
+}
+
+// goroutineID returns the runtime's ID for the calling goroutine, parsed
+// from the header of its stack trace ("goroutine 1 [running]:").
+func goroutineID() uint64 {
+	var buf [64]byte
+	s := strings.TrimPrefix(string(buf[:runtime.Stack(buf[:], false)]), "goroutine ")
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		id, _ := strconv.ParseUint(s[:i], 10, 64)
+		return id
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package graphics
+
+import (
+	"strings"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// loadExtensions returns the set of extensions supported by the current
+// context. Core profiles only offer the indexed GetStringi form, while legacy
+// contexts before 3.0 only have the single space-separated string.
+func loadExtensions(gl glpkg.OpenGL) map[string]bool {
+	exts := make(map[string]bool)
+
+	var n int32
+	gl.GetIntegerv(glpkg.NumExtensions, &n)
+	if n > 0 {
+		for i := uint32(0); i < uint32(n); i++ {
+			if name := gl.GetStringi(glpkg.Extensions, i); name != "" {
+				exts[name] = true
+			}
+		}
+		return exts
+	}
+
+	for _, name := range strings.Fields(gl.GetString(glpkg.Extensions)) {
+		exts[name] = true
+	}
+	return exts
+}
+
+// softwareRenderers are substrings of the GL_RENDERER strings of CPU
+// rasterizers, lowercased.
+var softwareRenderers = []string{
+	"llvmpipe",
+	"softpipe",
+	"software rasterizer",
+	"swiftshader",
+	"microsoft basic render",
+	"gdi generic",
+	"apple software renderer",
+}
+
+// isSoftwareRenderer reports whether renderer names a CPU rasterizer.
+func isSoftwareRenderer(renderer string) bool {
+	renderer = strings.ToLower(renderer)
+	for _, s := range softwareRenderers {
+		if strings.Contains(renderer, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Renderer implements Window.
+func (w *glWindow) Renderer() string {
+	return w.renderer
+}
+
+// IsSoftwareRenderer implements Window.
+func (w *glWindow) IsSoftwareRenderer() bool {
+	return isSoftwareRenderer(w.renderer)
+}
+
+// HasExtension implements Window.
+func (w *glWindow) HasExtension(name string) bool {
+	return w.extensions[name]
+}
@@ -0,0 +1,427 @@
+package graphics
+
+import (
+	"testing"
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+// fakeGL is an OpenGL that draws nothing but keeps the state the package
+// relies on, so tests can check what was left bound or enabled, and counts
+// the calls made through it.
+type fakeGL struct {
+	calls map[string]int
+
+	nextName uint32
+	texture  uint32 // bound to Texture2D
+	program  uint32
+	vao      uint32
+	buffers  map[uint32]uint32 // target -> bound buffer
+	enabled  map[uint32]bool
+	storage  map[uint32][]byte // buffer -> contents
+	mapped   bool
+
+	colorMask     [4]bool
+	depthMask     bool
+	stencilMask   uint32
+	stencilFunc   [3]uint32 // func, ref, mask
+	stencilOp     [3]uint32
+	locations     map[string]int32
+	uniform1f     map[int32]float32
+	deleted       map[uint32]bool // texture names
+	framebuffer   uint32
+	drawnVertices int
+	lastDraw      drawState
+	texels        []byte   // scratch for unpack
+	clears        []uint32 // masks passed to Clear
+	clearColor    [4]float32
+	readAt        [2]int32 // origin of the last ReadPixels
+
+	// Storage of textures allocated without data, such as render targets,
+	// which Clear fills while attached to the bound framebuffer.
+	texImages map[uint32][]byte
+	attached  map[uint32]uint32 // framebuffer -> color texture
+}
+
+// drawState is the state that affected the last DrawArrays.
+type drawState struct {
+	texture     uint32
+	framebuffer uint32
+	colorMask   [4]bool
+	depthMask   bool
+	stencilTest bool
+	blend       bool
+}
+
+func newFakeGL() *fakeGL {
+	return &fakeGL{
+		calls:       map[string]int{},
+		nextName:    1,
+		buffers:     map[uint32]uint32{},
+		enabled:     map[uint32]bool{},
+		storage:     map[uint32][]byte{},
+		deleted:     map[uint32]bool{},
+		locations:   map[string]int32{},
+		uniform1f:   map[int32]float32{},
+		colorMask:   [4]bool{true, true, true, true},
+		depthMask:   true,
+		stencilMask: 0xFF,
+		texImages:   map[uint32][]byte{},
+		attached:    map[uint32]uint32{},
+	}
+}
+
+func (g *fakeGL) call(name string) { g.calls[name]++ }
+
+func (g *fakeGL) gen(n int32, names *uint32) {
+	s := unsafe.Slice(names, n)
+	for i := range s {
+		s[i] = g.name()
+	}
+}
+
+func (g *fakeGL) ClearColor(r, gr, b, a float32) {
+	g.call("ClearColor")
+	g.clearColor = [4]float32{r, gr, b, a}
+}
+
+func (g *fakeGL) Clear(mask uint32) {
+	g.call("Clear")
+	g.clears = append(g.clears, mask)
+	if g.framebuffer == 0 || mask&glpkg.ColorBufferBit == 0 {
+		return
+	}
+	img := g.texImages[g.attached[g.framebuffer]]
+	for i := 0; i < len(img); i += 4 {
+		for c := range 4 {
+			img[i+c] = byte(g.clearColor[c]*255 + 0.5)
+		}
+	}
+}
+
+func (g *fakeGL) Flush()                                { g.call("Flush") }
+func (g *fakeGL) Finish()                               { g.call("Finish") }
+func (g *fakeGL) Viewport(x, y, width, height int32)    { g.call("Viewport") }
+func (g *fakeGL) Scissor(x, y, width, height int32)     { g.call("Scissor") }
+func (g *fakeGL) LineWidth(width float32)               { g.call("LineWidth") }
+func (g *fakeGL) PointSize(size float32)                { g.call("PointSize") }
+func (g *fakeGL) Enable(cap uint32)                     { g.call("Enable"); g.enabled[cap] = true }
+func (g *fakeGL) Disable(cap uint32)                    { g.call("Disable"); g.enabled[cap] = false }
+func (g *fakeGL) GenTextures(n int32, textures *uint32) { g.call("GenTextures"); g.gen(n, textures) }
+
+func (g *fakeGL) DeleteTextures(n int32, textures *uint32) {
+	g.call("DeleteTextures")
+	for _, t := range unsafe.Slice(textures, n) {
+		g.deleted[t] = true
+		if g.texture == t {
+			g.texture = 0
+		}
+	}
+}
+
+func (g *fakeGL) BindTexture(target, texture uint32) { g.call("BindTexture"); g.texture = texture }
+
+func (g *fakeGL) TexImage2D(target uint32, level, internalformat, width, height, border int32, format, xtype uint32, pixels unsafe.Pointer) {
+	g.call("TexImage2D")
+	if pixels == nil && format == glpkg.RGBA {
+		g.texImages[g.texture] = make([]byte, width*height*4)
+	}
+	g.unpack(width, height, format, xtype, pixels)
+}
+
+func (g *fakeGL) TexSubImage2D(target uint32, level, xoffset, yoffset, width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
+	g.call("TexSubImage2D")
+	g.unpack(width, height, format, xtype, pixels)
+}
+
+// unpack copies pixels uploaded from client memory, as a driver must before
+// TexImage2D returns, so benchmarks pay for it. With an unpack buffer bound
+// pixels is an offset into it and the copy happens on the GPU instead.
+func (g *fakeGL) unpack(width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
+	if pixels == nil || g.buffers[glpkg.PixelUnpackBuffer] != 0 {
+		return
+	}
+	bpp := 4
+	switch {
+	case xtype == glpkg.UnsignedShort565:
+		bpp = 2
+	case format == glpkg.Red:
+		bpp = 1
+	case format == glpkg.RGB:
+		bpp = 3
+	}
+	n := int(width) * int(height) * bpp
+	if len(g.texels) < n {
+		g.texels = make([]byte, n)
+	}
+	copy(g.texels, unsafe.Slice((*byte)(pixels), n))
+}
+
+func (g *fakeGL) TexParameteri(target, pname uint32, param int32)   { g.call("TexParameteri") }
+func (g *fakeGL) TexParameterf(target, pname uint32, param float32) { g.call("TexParameterf") }
+func (g *fakeGL) GenerateMipmap(target uint32)                      { g.call("GenerateMipmap") }
+func (g *fakeGL) PixelStorei(pname uint32, param int32)             { g.call("PixelStorei") }
+func (g *fakeGL) ActiveTexture(texture uint32)                      { g.call("ActiveTexture") }
+func (g *fakeGL) BlendFunc(sfactor, dfactor uint32)                 { g.call("BlendFunc") }
+
+func (g *fakeGL) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha uint32) {
+	g.call("BlendFuncSeparate")
+}
+
+func (g *fakeGL) StencilFunc(fn uint32, ref int32, mask uint32) {
+	g.call("StencilFunc")
+	g.stencilFunc = [3]uint32{fn, uint32(ref), mask}
+}
+
+func (g *fakeGL) StencilOp(fail, zfail, zpass uint32) {
+	g.call("StencilOp")
+	g.stencilOp = [3]uint32{fail, zfail, zpass}
+}
+
+func (g *fakeGL) StencilMask(mask uint32) { g.call("StencilMask"); g.stencilMask = mask }
+
+func (g *fakeGL) ColorMask(r, gr, b, a bool) {
+	g.call("ColorMask")
+	g.colorMask = [4]bool{r, gr, b, a}
+}
+
+func (g *fakeGL) DepthMask(flag bool)                 { g.call("DepthMask"); g.depthMask = flag }
+func (g *fakeGL) ClearStencil(s int32)                { g.call("ClearStencil") }
+func (g *fakeGL) DepthFunc(fn uint32)                 { g.call("DepthFunc") }
+func (g *fakeGL) GenBuffers(n int32, buffers *uint32) { g.call("GenBuffers"); g.gen(n, buffers) }
+func (g *fakeGL) BindBuffer(target uint32, buffer uint32) {
+	g.call("BindBuffer")
+	g.buffers[target] = buffer
+}
+
+func (g *fakeGL) DeleteBuffers(n int32, buffers *uint32) {
+	g.call("DeleteBuffers")
+	for _, b := range unsafe.Slice(buffers, n) {
+		delete(g.storage, b)
+	}
+}
+
+func (g *fakeGL) BufferData(target uint32, size int, data unsafe.Pointer, usage uint32) {
+	g.call("BufferData")
+	buf := g.storage[g.buffers[target]]
+	if len(buf) != size {
+		buf = make([]byte, size)
+	}
+	if data != nil {
+		copy(buf, unsafe.Slice((*byte)(data), size))
+	}
+	g.storage[g.buffers[target]] = buf
+}
+
+func (g *fakeGL) BufferSubData(target uint32, offset int, size int, data unsafe.Pointer) {
+	g.call("BufferSubData")
+	copy(g.storage[g.buffers[target]][offset:offset+size], unsafe.Slice((*byte)(data), size))
+}
+
+func (g *fakeGL) MapBufferRange(target uint32, offset int, length int, access uint32) unsafe.Pointer {
+	g.call("MapBufferRange")
+	buf := g.storage[g.buffers[target]]
+	if g.mapped || offset+length > len(buf) || length == 0 {
+		return nil
+	}
+	g.mapped = true
+	return unsafe.Pointer(&buf[offset])
+}
+
+func (g *fakeGL) UnmapBuffer(target uint32) bool {
+	g.call("UnmapBuffer")
+	ok := g.mapped
+	g.mapped = false
+	return ok
+}
+
+func (g *fakeGL) GenVertexArrays(n int32, arrays *uint32) {
+	g.call("GenVertexArrays")
+	g.gen(n, arrays)
+}
+func (g *fakeGL) DeleteVertexArrays(n int32, arrays *uint32) { g.call("DeleteVertexArrays") }
+func (g *fakeGL) BindVertexArray(array uint32)               { g.call("BindVertexArray"); g.vao = array }
+
+func (g *fakeGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset uintptr) {
+	g.call("VertexAttribPointer")
+}
+
+func (g *fakeGL) EnableVertexAttribArray(index uint32)      { g.call("EnableVertexAttribArray") }
+func (g *fakeGL) CreateShader(xtype uint32) uint32          { g.call("CreateShader"); return g.name() }
+func (g *fakeGL) ShaderSource(shader uint32, source string) { g.call("ShaderSource") }
+func (g *fakeGL) CompileShader(shader uint32)               { g.call("CompileShader") }
+
+func (g *fakeGL) GetShaderiv(shader uint32, pname uint32, params *int32) {
+	g.call("GetShaderiv")
+	*params = 1 // compiled
+}
+
+func (g *fakeGL) GetShaderInfoLog(shader uint32) string               { return "" }
+func (g *fakeGL) DeleteShader(shader uint32)                          { g.call("DeleteShader") }
+func (g *fakeGL) CreateProgram() uint32                               { g.call("CreateProgram"); return g.name() }
+func (g *fakeGL) AttachShader(program uint32, shader uint32)          { g.call("AttachShader") }
+func (g *fakeGL) LinkProgram(program uint32)                          { g.call("LinkProgram") }
+func (g *fakeGL) GetProgramInfoLog(program uint32) string             { return "" }
+func (g *fakeGL) UseProgram(program uint32)                           { g.call("UseProgram"); g.program = program }
+func (g *fakeGL) DeleteProgram(program uint32)                        { g.call("DeleteProgram") }
+func (g *fakeGL) GetAttribLocation(program uint32, name string) int32 { return 0 }
+
+func (g *fakeGL) GetProgramiv(program uint32, pname uint32, params *int32) {
+	g.call("GetProgramiv")
+	*params = 1 // linked
+}
+
+func (g *fakeGL) GetUniformLocation(program uint32, name string) int32 {
+	g.call("GetUniformLocation")
+	loc, ok := g.locations[name]
+	if !ok {
+		loc = int32(len(g.locations))
+		g.locations[name] = loc
+	}
+	return loc
+}
+
+func (g *fakeGL) Uniform1i(location int32, v0 int32) { g.call("Uniform1i") }
+
+func (g *fakeGL) Uniform1f(location int32, v0 float32) {
+	g.call("Uniform1f")
+	g.uniform1f[location] = v0
+}
+
+func (g *fakeGL) Uniform2f(location int32, v0, v1 float32)         { g.call("Uniform2f") }
+func (g *fakeGL) Uniform4f(location int32, v0, v1, v2, v3 float32) { g.call("Uniform4f") }
+
+func (g *fakeGL) UniformMatrix4fv(location int32, count int32, transpose bool, value *float32) {
+	g.call("UniformMatrix4fv")
+}
+
+func (g *fakeGL) DrawArrays(mode uint32, first int32, count int32) {
+	g.call("DrawArrays")
+	g.drawnVertices += int(count)
+	g.lastDraw = drawState{
+		texture:     g.texture,
+		framebuffer: g.framebuffer,
+		colorMask:   g.colorMask,
+		depthMask:   g.depthMask,
+		stencilTest: g.enabled[glpkg.StencilTest],
+		blend:       g.enabled[glpkg.Blend],
+	}
+}
+
+func (g *fakeGL) GenFramebuffers(n int32, framebuffers *uint32) {
+	g.call("GenFramebuffers")
+	g.gen(n, framebuffers)
+}
+
+func (g *fakeGL) DeleteFramebuffers(n int32, framebuffers *uint32) { g.call("DeleteFramebuffers") }
+
+func (g *fakeGL) BindFramebuffer(target, framebuffer uint32) {
+	g.call("BindFramebuffer")
+	g.framebuffer = framebuffer
+}
+
+func (g *fakeGL) FramebufferTexture2D(target, attachment, textarget, texture uint32, level int32) {
+	g.call("FramebufferTexture2D")
+	g.attached[g.framebuffer] = texture
+}
+
+func (g *fakeGL) CheckFramebufferStatus(target uint32) uint32 {
+	g.call("CheckFramebufferStatus")
+	return glpkg.FramebufferComplete
+}
+
+func (g *fakeGL) GenRenderbuffers(n int32, renderbuffers *uint32) {
+	g.call("GenRenderbuffers")
+	g.gen(n, renderbuffers)
+}
+
+func (g *fakeGL) DeleteRenderbuffers(n int32, renderbuffers *uint32) {
+	g.call("DeleteRenderbuffers")
+}
+
+func (g *fakeGL) BindRenderbuffer(target, renderbuffer uint32) { g.call("BindRenderbuffer") }
+
+func (g *fakeGL) RenderbufferStorage(target, internalformat uint32, width, height int32) {
+	g.call("RenderbufferStorage")
+}
+
+func (g *fakeGL) FramebufferRenderbuffer(target, attachment, renderbuffertarget, renderbuffer uint32) {
+	g.call("FramebufferRenderbuffer")
+}
+
+func (g *fakeGL) ReadPixels(x, y, width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
+	g.call("ReadPixels")
+	g.readAt = [2]int32{x, y}
+}
+
+func (g *fakeGL) GetTexImage(target uint32, level int32, format, xtype uint32, pixels unsafe.Pointer) {
+	g.call("GetTexImage")
+	img := g.texImages[g.texture]
+	copy(unsafe.Slice((*byte)(pixels), len(img)), img)
+}
+
+func (g *fakeGL) GetString(name uint32) string {
+	if name == glpkg.Version {
+		return "3.3 fake"
+	}
+	return "fake"
+}
+
+func (g *fakeGL) GetStringi(name, index uint32) string { return "" }
+
+func (g *fakeGL) GetIntegerv(pname uint32, data *int32) {
+	g.call("GetIntegerv")
+	switch pname {
+	case glpkg.MaxTextureSize:
+		*data = 16384
+	default:
+		*data = 0
+	}
+}
+
+func (g *fakeGL) GetFloatv(pname uint32, data *float32) { *data = 0 }
+
+func (g *fakeGL) name() uint32 {
+	n := g.nextName
+	g.nextName++
+	return n
+}
+
+// fakePlatform is a window.Window of a fixed size that never has events.
+// Methods a test doesn't need panic through the nil embedded interface.
+type fakePlatform struct {
+	window.Window
+
+	width, height int
+	scale         float32
+	cursorX       float32
+	cursorY       float32
+}
+
+func (p *fakePlatform) BackingSize() (int, int)    { return p.width, p.height }
+func (p *fakePlatform) Scale() float32             { return p.scale }
+func (p *fakePlatform) Cursor() (float32, float32) { return p.cursorX, p.cursorY }
+func (p *fakePlatform) Focused() bool              { return true }
+func (p *fakePlatform) Poll() bool                 { return true }
+func (p *fakePlatform) Swap()                      {}
+func (p *fakePlatform) Wake()                      {}
+func (p *fakePlatform) Close()                     {}
+
+// uniform returns the value last set with Uniform1f for the named uniform.
+func (g *fakeGL) uniform(name string) float32 {
+	return g.uniform1f[g.locations[name]]
+}
+
+// newTestWindow returns a window drawing with a fakeGL onto a width x height
+// fakePlatform at scale 1.
+func newTestWindow(tb testing.TB, width, height int) (*glWindow, *fakeGL) {
+	tb.Helper()
+	gl := newFakeGL()
+	w, err := newGLWindow(&fakePlatform{width: width, height: height, scale: 1}, gl, Options{})
+	if err != nil {
+		tb.Fatalf("newGLWindow: %v", err)
+	}
+	return w, gl
+}
@@ -0,0 +1,40 @@
+package graphics
+
+import "time"
+
+// fpsSmoothing is the weight given to each new frame time in the moving
+// average. Lower values react more slowly but jitter less.
+const fpsSmoothing = 0.1
+
+// FPSCounter measures the frame rate as an exponential moving average of
+// frame times. The zero value is ready to use.
+type FPSCounter struct {
+	last      time.Time
+	frameTime float64 // smoothed seconds per frame
+}
+
+// Tick records the end of a frame. Call it once per frame.
+func (c *FPSCounter) Tick() {
+	now := time.Now()
+	if !c.last.IsZero() {
+		c.add(now.Sub(c.last))
+	}
+	c.last = now
+}
+
+func (c *FPSCounter) add(dt time.Duration) {
+	if c.frameTime == 0 {
+		c.frameTime = dt.Seconds()
+		return
+	}
+	c.frameTime += (dt.Seconds() - c.frameTime) * fpsSmoothing
+}
+
+// FPS returns the smoothed frames per second, or 0 until two frames have
+// been recorded.
+func (c *FPSCounter) FPS() float32 {
+	if c.frameTime <= 0 {
+		return 0
+	}
+	return float32(1 / c.frameTime)
+}
@@ -0,0 +1,75 @@
+package graphics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFPSCounter(t *testing.T) {
+	const (
+		fps60 = time.Second / 60
+		fps30 = time.Second / 30
+	)
+	tests := []struct {
+		name   string
+		deltas []time.Duration
+		want   float64
+		tol    float64
+	}{
+		{name: "no frames", want: 0},
+		{name: "first frame", deltas: []time.Duration{fps60}, want: 60, tol: 0.01},
+		{name: "steady", deltas: repeat(fps30, 10), want: 30, tol: 0.01},
+		{
+			// One slow frame moves the average by fpsSmoothing of the
+			// difference in frame time, not all the way.
+			name:   "one spike",
+			deltas: append(repeat(fps60, 10), 100*time.Millisecond),
+			want:   1 / (1.0/60 + (0.1-1.0/60)*fpsSmoothing),
+			tol:    0.01,
+		},
+		{
+			// After enough frames at a new rate the old one has rolled out
+			// of the average.
+			name:   "rate change",
+			deltas: append(repeat(fps60, 100), repeat(fps30, 100)...),
+			want:   30,
+			tol:    0.01,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c FPSCounter
+			for _, dt := range tt.deltas {
+				c.add(dt)
+			}
+			if got := float64(c.FPS()); math.Abs(got-tt.want) > tt.tol {
+				t.Errorf("FPS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFPSCounterDecay(t *testing.T) {
+	// The weight of frames before a rate change decays by 1-fpsSmoothing
+	// per frame, so it halves in about 6.6 frames at 0.1.
+	var c FPSCounter
+	c.add(time.Second / 60)
+	old := 1.0 / 60
+	n := int(math.Ceil(math.Log(0.5) / math.Log(1-fpsSmoothing)))
+	for range n {
+		c.add(time.Second / 30)
+	}
+	remaining := (1/float64(c.FPS()) - 1.0/30) / (old - 1.0/30)
+	if remaining > 0.5 || remaining < 0.5*(1-fpsSmoothing) {
+		t.Errorf("after %d frames %.3f of the old frame time remains, want just under 0.5", n, remaining)
+	}
+}
+
+func repeat(dt time.Duration, n int) []time.Duration {
+	d := make([]time.Duration, n)
+	for i := range d {
+		d[i] = dt
+	}
+	return d
+}
@@ -0,0 +1,66 @@
+package graphics
+
+import (
+	"os"
+	"strings"
+)
+
+// PreferDiscreteGPU asks for the discrete GPU on machines that have an
+// integrated one as well. Call it before New; it has no effect on windows
+// that already exist.
+//
+// On Linux it sets DRI_PRIME=1, unless already set, which makes Mesa
+// render on the secondary (usually discrete) GPU. The NVIDIA proprietary
+// driver uses its own offload variables instead, which must be set before
+// the program starts: __NV_PRIME_RENDER_OFFLOAD=1 and
+// __GLX_VENDOR_LIBRARY_NAME=nvidia.
+//
+// On macOS the discrete GPU is already used, because the pixel format does
+// not allow offline renderers.
+//
+// On Windows the drivers look for the NvOptimusEnablement and
+// AmdPowerXpressRequestHighPerformance symbols exported from the
+// executable, which a Go program cannot do without cgo. Set the preference
+// for the executable in Settings > System > Display > Graphics instead.
+func PreferDiscreteGPU() {
+	if _, ok := os.LookupEnv("DRI_PRIME"); !ok {
+		os.Setenv("DRI_PRIME", "1")
+	}
+}
+
+// integratedGPUs are substrings of the GL_RENDERER strings of GPUs that
+// share memory with the CPU, lowercased. AMD APUs report a bare "Radeon
+// Graphics" or a Vega name without a model number.
+var integratedGPUs = []string{
+	"intel(r) hd",
+	"intel(r) uhd",
+	"intel(r) iris",
+	"intel hd",
+	"intel uhd",
+	"intel iris",
+	"mesa intel",
+	"radeon graphics",
+	"radeon(tm) graphics",
+	"radeon vega",
+}
+
+// isIntegratedGPU reports whether renderer names an integrated GPU.
+func isIntegratedGPU(renderer string) bool {
+	renderer = strings.ToLower(renderer)
+	for _, s := range integratedGPUs {
+		if strings.Contains(renderer, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Vendor implements Window.
+func (w *glWindow) Vendor() string {
+	return w.vendor
+}
+
+// IsIntegratedGPU implements Window.
+func (w *glWindow) IsIntegratedGPU() bool {
+	return isIntegratedGPU(w.renderer)
+}
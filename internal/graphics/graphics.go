@@ -1,8 +1,11 @@
 package graphics
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
+	"time"
 
 	"github.com/tinyrange/gowin/internal/window"
 )
@@ -32,42 +35,718 @@ var (
 	ColorGray      = color.RGBA{R: 128, G: 128, B: 128, A: 255}
 	ColorDarkGray  = color.RGBA{R: 64, G: 64, B: 64, A: 255}
 	ColorLightGray = color.RGBA{R: 192, G: 192, B: 192, A: 255}
+
+	// ColorTransparent clears to fully transparent; see Options.Transparent.
+	ColorTransparent = color.RGBA{}
+)
+
+// Options configures a Window created by NewWithOptions. The zero value is
+// usable: unset fields fall back to the defaults noted on each field.
+type Options struct {
+	// Title is the window title. Defaults to DefaultTitle.
+	Title string
+	// Width and Height are the initial window size in logical pixels.
+	// Default to DefaultWidth and DefaultHeight.
+	Width  int
+	Height int
+
+	// LegacyProfile requests a compatibility/legacy GL context instead of a
+	// core profile one on platforms that make the distinction.
+	LegacyProfile bool
+
+	// GLMajor and GLMinor request a specific OpenGL version; zero uses the
+	// platform default. See RequestGLVersion.
+	GLMajor int
+	GLMinor int
+
+	// Samples is the number of samples per pixel used for multisample
+	// anti-aliasing, typically 4 or 8. Zero disables it.
+	Samples int
+
+	// Transparent lets the desktop show through wherever the window's
+	// alpha is below one. The clear color defaults to ColorTransparent.
+	// The window holds premultiplied color, as compositors expect.
+	Transparent bool
+
+	// StartHidden creates the window hidden; show it with
+	// PlatformWindow().Show() once it is ready to draw.
+	StartHidden bool
+
+	// Monitor is the monitor to open the window on, as returned by
+	// Monitors. Nil leaves placement to the platform.
+	Monitor *window.Monitor
+
+	// ClearColor is the color each frame starts from, as later changed
+	// with SetClearColor. Nil means ColorBlack, or ColorTransparent for a
+	// Transparent window.
+	ClearColor color.Color
+
+	// NoClear starts with clearing turned off, as SetClear(false) does.
+	NoClear bool
+}
+
+// Monitors returns the monitors attached to the desktop, for choosing
+// Options.Monitor or, through Monitor.Modes, a fullscreen mode.
+func Monitors() ([]window.Monitor, error) {
+	return window.Monitors()
+}
+
+// RequestGLVersion asks for an OpenGL major.minor context, with a core
+// profile if core is true. Profiles only exist from 3.2, and macOS only
+// offers 3.2 and 4.1 core contexts besides its legacy one. If the context
+// can't be created the platform falls back to its default.
+func (o *Options) RequestGLVersion(major, minor int, core bool) {
+	o.GLMajor, o.GLMinor = major, minor
+	o.LegacyProfile = !core
+}
+
+// Defaults applied by NewWithOptions to unset Options fields.
+const (
+	DefaultTitle  = "gowin"
+	DefaultWidth  = 800
+	DefaultHeight = 600
 )
 
+// DefaultMaxDelta is the initial cap on Frame.DeltaTime, 100ms, i.e. a
+// simulation never advances more than that in one frame.
+const DefaultMaxDelta = 100 * time.Millisecond
+
+// DefaultFrameSleep is the initial pause after each continuous frame,
+// which holds Loop to at most about 120 frames per second.
+const DefaultFrameSleep = time.Second / 120
+
+func (o Options) withDefaults() Options {
+	if o.Title == "" {
+		o.Title = DefaultTitle
+	}
+	if o.Width <= 0 {
+		o.Width = DefaultWidth
+	}
+	if o.Height <= 0 {
+		o.Height = DefaultHeight
+	}
+	return o
+}
+
+// ErrStopLoop can be returned by the step function passed to Window.Loop to
+// stop the loop. Loop closes the window and returns nil.
+var ErrStopLoop = errors.New("graphics: stop loop")
+
+// ErrGLVersionTooLow is returned by New when the context it got is older
+// than OpenGL 3.0. Callers can check for it with errors.Is to fall back to
+// another renderer.
+var ErrGLVersionTooLow = errors.New("graphics: OpenGL 3.0+ required")
+
+// ErrContextCreation is returned by New when the window or its OpenGL
+// context can't be created. The platform's error is wrapped as well, so
+// errors.Is(err, window.ErrNoDisplay) still works.
+var ErrContextCreation = errors.New("graphics: failed to create OpenGL context")
+
+// ShaderCompileError reports a GLSL shader that failed to compile or link.
+type ShaderCompileError struct {
+	// Stage is "vertex", "fragment" or "link".
+	Stage string
+	// Log is the driver's info log.
+	Log string
+}
+
+func (e *ShaderCompileError) Error() string {
+	if e.Stage == "link" {
+		return "program linking failed: " + e.Log
+	}
+	return e.Stage + " shader compilation failed: " + e.Log
+}
+
+// Future is the pending result of an asynchronous readback. Its methods must
+// be called on the thread running Loop.
+type Future interface {
+	// Ready reports whether Wait can return without stalling.
+	Ready() bool
+	// Wait returns the image, blocking until the GPU has finished writing
+	// it if necessary. It may be called more than once.
+	Wait() (image.Image, error)
+}
+
+// RedrawMode controls how often Window.Loop calls its step function.
+type RedrawMode int
+
+const (
+	// RedrawContinuous draws frames continuously at a fixed rate.
+	RedrawContinuous RedrawMode = iota
+	// RedrawOnDemand blocks until an input or window event arrives or a
+	// redraw is requested, so an idle window uses no CPU.
+	RedrawOnDemand
+)
+
+// ScaleMode controls how a logical resolution set with SetLogicalSize is
+// mapped onto the window.
+type ScaleMode int
+
+const (
+	// ScaleFit scales uniformly to the largest size that fits, adding
+	// letterbox bars on the sides or top and bottom.
+	ScaleFit ScaleMode = iota
+	// ScaleInteger is ScaleFit restricted to whole-number factors, keeping
+	// pixel art crisp. Falls back to ScaleFit if the window is smaller than
+	// the logical size.
+	ScaleInteger
+	// ScaleStretch fills the window, distorting the aspect ratio.
+	ScaleStretch
+)
+
+// PanicError is returned by Window.Loop when the step function panics. The
+// window has already been closed when it is returned.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in loop step: %v\n%s", e.Value, e.Stack)
+}
+
 type Frame interface {
+	// WindowSize returns the size of the framebuffer in physical pixels.
 	WindowSize() (width, height int)
+	// WindowSizeLogical returns the size of the space RenderQuad and text
+	// draw in, rounded down: Window.ViewSize as integers.
+	WindowSizeLogical() (width, height int)
+	// CursorPos returns the mouse position in the coordinates RenderQuad
+	// draws in: logical pixels from the top-left corner of the window, or
+	// of the logical canvas when SetLogicalSize is in use. A quad drawn at
+	// (x, y) is under the cursor when CursorPos reports (x, y), on every
+	// platform.
 	CursorPos() (x, y float32)
+	// CursorPosPixels returns the mouse position in physical pixels from
+	// the top-left corner of the window, the unit of WindowSize.
+	CursorPosPixels() (x, y float32)
 
 	GetKeyState(key window.Key) window.KeyState
 	GetButtonState(button window.Button) window.ButtonState
 
+	// KeyJustPressed reports whether key went down this frame. Auto-repeat
+	// doesn't count; check for KeyStateRepeated to act on it.
+	KeyJustPressed(key window.Key) bool
+	// KeyJustReleased reports whether key went up this frame.
+	KeyJustReleased(key window.Key) bool
+	// KeyDown reports whether key is held, including the frame it went
+	// down in.
+	KeyDown(key window.Key) bool
+	// ButtonJustPressed, ButtonJustReleased and ButtonDown are the mouse
+	// button counterparts of the key predicates.
+	ButtonJustPressed(button window.Button) bool
+	ButtonJustReleased(button window.Button) bool
+	ButtonDown(button window.Button) bool
+
+	// Modifiers returns the modifier keys held down.
+	Modifiers() window.Modifiers
+	// ChordPressed reports whether key went down this frame while exactly
+	// mods were held, as for a shortcut such as Ctrl+S. Extra modifiers
+	// don't match, so Ctrl+Shift+S doesn't trigger Ctrl+S, and neither does
+	// pressing the modifier after the key or the key auto-repeating. A
+	// modifier pressed in the same frame as the key counts as held.
+	ChordPressed(mods window.Modifiers, key window.Key) bool
+
 	RenderQuad(x, y, width, height float32, tex Texture, color color.Color)
 
+	// RenderQuadZ is RenderQuad at depth z, in the range [-1, 1]. With depth
+	// testing enabled, quads with a higher z are drawn over lower ones
+	// regardless of draw order; quads at equal z layer in draw order.
+	RenderQuadZ(x, y, z, width, height float32, tex Texture, color color.Color)
+
+	// RenderQuadUV is RenderQuad drawing only the part of tex between the
+	// texture coordinates (u0, v0) and (u1, v1), where (0, 0) is the
+	// texture's top-left corner and (1, 1) its bottom-right. Use it to draw
+	// sprites out of an atlas.
+	RenderQuadUV(x, y, width, height float32, tex Texture, u0, v0, u1, v1 float32, color color.Color)
+
+	// RenderRect is RenderQuad filling dst.
+	RenderRect(dst image.Rectangle, tex Texture, color color.Color)
+
+	// RenderRectUV draws the texels of tex inside src, in pixels from its
+	// top-left corner, stretched over dst.
+	RenderRectUV(dst, src image.Rectangle, tex Texture, color color.Color)
+
+	// RenderQuadGradient fills a rectangle with colors interpolated between
+	// the ones given for its corners.
+	RenderQuadGradient(x, y, width, height float32, topLeft, topRight, bottomLeft, bottomRight color.Color)
+
+	// FillRoundedRect fills a rectangle with corners rounded to radius,
+	// which is clamped to half the shorter side. Corners are drawn as
+	// triangles fine enough to look round at the window's scale.
+	FillRoundedRect(x, y, width, height, radius float32, c color.Color)
+
+	// FillCircle fills a circle centered on (cx, cy) as a fan of segments
+	// triangles. Zero or less picks a count that looks round at the
+	// window's scale.
+	FillCircle(cx, cy, radius float32, segments int, c color.Color)
+
+	// DrawArc strokes the part of a circle from startAngle to endAngle with
+	// a line thickness wide, centered on radius. Angles are in radians,
+	// with zero pointing right and positive turning clockwise on screen,
+	// so 0 to 2π draws a ring. Segments works as for FillCircle.
+	DrawArc(cx, cy, radius, thickness, startAngle, endAngle float32, segments int, c color.Color)
+
+	// DrawBatch draws the quads added to b since it was last reset, in the
+	// order added, with as few draw calls as the vertex buffer allows.
+	DrawBatch(b *SpriteBatch)
+
+	// ClearRect clears the given rectangle of the view to c, and its depth
+	// when depth testing is enabled, without drawing a quad. Useful for
+	// repainting a single panel in RedrawOnDemand mode.
+	ClearRect(x, y, width, height float32, c color.Color)
+
+	// DeltaTime returns the time since the previous frame started, for
+	// advancing animation and physics. It is zero on the first frame and
+	// never exceeds the limit set with Window.SetMaxDelta.
+	DeltaTime() time.Duration
+
+	// IsFocused reports whether the window has keyboard focus. Apps that
+	// forward input elsewhere, such as a remote desktop, should stop while
+	// it doesn't.
+	IsFocused() bool
+	// MouseInWindow reports whether the cursor is over the window's content
+	// area.
+	MouseInWindow() bool
+
+	// RequestRedraw asks for another frame after this one when the window is
+	// in RedrawOnDemand mode, e.g. while an animation is running.
+	RequestRedraw()
+
+	// DrawCursor draws tex at its natural size at the cursor position, offset
+	// so that the point (hotX, hotY) within the texture sits under the cursor.
+	// Combined with hiding the OS cursor this allows arbitrary cursors without
+	// platform cursor APIs.
+	DrawCursor(tex Texture, hotX, hotY float32)
+
+	// BeginMask starts building a clip mask. Quads rendered until DrawMasked
+	// is called are not drawn; instead they mark the area later drawing is
+	// clipped to. Texels with alpha below one half are left out of the mask,
+	// so a textured quad can describe a non-rectangular shape.
+	BeginMask()
+	// DrawMasked ends the mask and clips subsequent quads to it.
+	DrawMasked()
+	// EndMask turns clipping off again.
+	EndMask()
+
+	// SetBlendMode selects how the quads drawn next are composited. It
+	// stays in effect, across frames too, until changed.
+	SetBlendMode(mode BlendMode)
+
+	// SetView transforms everything drawn after it this frame by matrix,
+	// column-major, ahead of the window's projection: PanZoom, for
+	// instance, pans and zooms the whole scene without changing any quad
+	// coordinates. Each frame starts with no view transform. CursorPos,
+	// ClearRect and PixelAt don't take it into account.
+	SetView(matrix [16]float32)
+
+	// PushTarget directs drawing into target until the matching PopTarget,
+	// clearing it first as set with RenderTarget.SetClear. Coordinates are
+	// the target's pixels from its top-left corner, the view starts out as
+	// the identity and the window's projection doesn't apply. Targets nest;
+	// any still pushed when the frame ends are popped. A mask in progress
+	// ends on both PushTarget and PopTarget. Screenshot, PixelAt and
+	// ClearRect address the window, so only call them with no target
+	// pushed, and don't draw a target into itself.
+	PushTarget(target RenderTarget)
+	// PopTarget returns to drawing into the target pushed before, or the
+	// window, restoring the view that was set there.
+	PopTarget()
+
+	// Screenshot returns the contents of the window in physical (backing)
+	// pixels, so on a display with Scale 2 an 800x600 window yields a
+	// 1600x1200 image. Its alpha is coverage and its color is
+	// premultiplied, as image.RGBA defines, whichever BlendMode was used.
 	Screenshot() (image.Image, error)
+
+	// PixelAt returns the color of the pixel under the logical coordinate
+	// (x, y), in the units of RenderQuad; truncate CursorPos to pick the
+	// color under the cursor. It reads what has been drawn so far this
+	// frame, so call it after drawing; like Screenshot the color is
+	// premultiplied. Points outside the window return an error.
+	PixelAt(x, y int) (color.RGBA, error)
+
+	// ScreenshotAsync starts reading back the window contents without
+	// waiting for the GPU. Resolve the result with Future.Wait, ideally once
+	// Ready reports true a frame or two later.
+	ScreenshotAsync() Future
+
+	// ScreenshotLogical returns the contents of the view downscaled to
+	// ViewSize, i.e. in the same units as RenderQuad coordinates. Letterbox
+	// bars from SetLogicalSize are not included.
+	ScreenshotLogical() (image.Image, error)
+}
+
+// Filter selects how texels are sampled when a texture is drawn at a size
+// other than its own.
+type Filter int
+
+const (
+	// FilterNearest picks the closest texel, keeping pixel art crisp.
+	FilterNearest Filter = iota
+	// FilterLinear blends the nearest texels for smooth scaling.
+	FilterLinear
+)
+
+// BlendMode is the alpha convention quads are composited with; see
+// Frame.SetBlendMode.
+type BlendMode int
+
+const (
+	// BlendAlpha treats texture and tint colors as straight
+	// (non-premultiplied) alpha. It is the default and matches NewTexture,
+	// which stores images that way, and the text package.
+	BlendAlpha BlendMode = iota
+	// BlendPremultiplied expects color already multiplied by alpha, as in
+	// textures created with TextureOptions.Premultiply. Linear filtering
+	// and mipmaps of such textures don't bleed the color of transparent
+	// texels into edges. Tint colors must be premultiplied too, e.g. 50%
+	// white is {0.5, 0.5, 0.5, 0.5}.
+	BlendPremultiplied
+	// BlendAdditive adds straight-alpha color, weighted by its alpha, to
+	// what is already drawn, so overlapping quads brighten towards white.
+	// It suits glows, sparks and other particle effects.
+	BlendAdditive
+)
+
+// TextureOptions configures a texture created by NewTextureWithOptions. The
+// zero value matches NewTexture.
+type TextureOptions struct {
+	// Filter is used for magnification, and for minification unless Mipmap
+	// is set.
+	Filter Filter
+
+	// Mipmap generates a mipmap chain and samples it trilinearly when the
+	// texture is drawn smaller than its size, which avoids aliasing when
+	// content is scaled down. The chain costs about a third more memory.
+	Mipmap bool
+
+	// Anisotropy is the maximum number of samples taken along the axis of
+	// greatest squeeze when the texture is drawn non-uniformly scaled, e.g.
+	// 4 or 16. It is clamped to what the GPU supports and ignored without
+	// the anisotropic filtering extension. Values of 1 or less disable it.
+	// It is most useful together with Mipmap.
+	Anisotropy float32
+
+	// Premultiply multiplies color by alpha on upload, for drawing with
+	// BlendPremultiplied. Without it textures hold straight alpha, for
+	// BlendAlpha. With it, StreamingTexture.Update copies *image.RGBA
+	// images, which are premultiplied already, without conversion.
+	Premultiply bool
+}
+
+// TextureOptionsVideo suits photographic or streamed content such as video
+// frames and remote desktops, which is usually drawn scaled: linear
+// filtering both ways, so upscaling is smooth rather than blocky. Mipmap is
+// left off since a streaming texture regenerates the chain on every
+// update; turn it on if the image is mostly shown much smaller than its
+// size.
+var TextureOptionsVideo = TextureOptions{Filter: FilterLinear}
+
+// PixelFormat is the layout of the bytes passed to NewTextureRaw.
+type PixelFormat int
+
+const (
+	// PixelFormatRGBA is 4 bytes per pixel, red first, with straight
+	// (non-premultiplied) alpha, like image.NRGBA.
+	PixelFormatRGBA PixelFormat = iota
+	// PixelFormatBGRA is 4 bytes per pixel, blue first, the native layout
+	// of many framebuffers and VNC servers.
+	PixelFormatBGRA
+	// PixelFormatRed is 1 byte per pixel. It samples as (r, 0, 0, 1), so it
+	// is mostly useful with a custom shader.
+	PixelFormatRed
+	// PixelFormatRGB565 is 2 bytes per pixel, a little-endian uint16 with
+	// 5 bits of red at the top, then 6 of green and 5 of blue, as sent by
+	// 16-bit VNC servers. It is opaque. It halves the bytes uploaded, though
+	// the driver may still store the texture at 8 bits per channel.
+	PixelFormatRGB565
+)
+
+// bytesPerPixel returns the size of one pixel in f.
+func (f PixelFormat) bytesPerPixel() int {
+	switch f {
+	case PixelFormatRed:
+		return 1
+	case PixelFormatRGB565:
+		return 2
+	}
+	return 4
 }
 
 type Texture interface {
 	Size() (width, height int)
+
+	// SetFilter changes how the texture is sampled when drawn smaller
+	// (minFilter) or larger (magFilter) than its size, replacing the
+	// TextureOptions.Filter it was created with. For a texture with Mipmap
+	// set, FilterLinear minification samples the mipmap chain and
+	// FilterNearest bypasses it.
+	SetFilter(minFilter, magFilter Filter)
+
+	// Delete frees the texture's GPU memory, along with the pixel buffer of
+	// a StreamingTexture or the framebuffer of a RenderTarget. Quads already
+	// drawn with it this frame are drawn first. The texture must not be
+	// used afterwards; deleting it again does nothing.
+	Delete()
+}
+
+// StreamingTexture is a texture whose whole contents are replaced often,
+// such as a video frame or remote framebuffer. Uploads go through a pixel
+// unpack buffer that is orphaned on every Update, so the copy overlaps with
+// rendering instead of stalling on draws still using the old contents. This
+// is much cheaper than creating a new texture each frame.
+type StreamingTexture interface {
+	Texture
+
+	// Update replaces the contents of the texture with img, which must be
+	// the same size. *image.NRGBA and *image.RGBA are copied directly;
+	// other image types are converted first.
+	Update(img image.Image) error
+
+	// UpdateRaw is Update for tightly packed pixel bytes, as accepted by
+	// NewTextureRaw. PixelFormatBGRA is swizzled by the GPU during the
+	// upload rather than on the CPU.
+	UpdateRaw(format PixelFormat, pixels []byte) error
+}
+
+// RenderTarget is a texture that can be drawn into, created by
+// Window.NewRenderTarget. Between Frame.PushTarget and Frame.PopTarget
+// everything drawn lands in it instead of the window; afterwards it is
+// drawn like any other texture. Its color is premultiplied, as the
+// window's is, so draw it with BlendPremultiplied.
+type RenderTarget interface {
+	Texture
+
+	// SetClear sets whether PushTarget clears the target, and to which
+	// color. Targets start out clearing to ColorTransparent. With clearing
+	// off, drawing adds to what the target already holds, except the first
+	// time it is pushed, when its contents are still undefined.
+	SetClear(enabled bool, c color.Color)
+
+	// Image reads the target's contents back from the GPU, the top row
+	// first. Like Screenshot it stalls until drawing into it has finished.
+	Image() (*image.RGBA, error)
+}
+
+// Shader is a custom fragment shader created by Window.NewShader.
+type Shader interface {
+	// SetFloat sets the float uniform name, applied whenever the shader is
+	// drawn with.
+	SetFloat(name string, v float32)
+}
+
+// SharedContext is a second GL context, created by Window.NewSharedContext,
+// that shares textures with the window's. It lets a worker upload textures
+// on its own thread instead of queueing the work onto Loop.
+type SharedContext interface {
+	// MakeCurrent makes the context current on the calling thread. Call
+	// runtime.LockOSThread first and keep the goroutine locked until
+	// ClearCurrent, as a context belongs to an OS thread, not a goroutine.
+	MakeCurrent() error
+	// ClearCurrent releases the context from the calling thread.
+	ClearCurrent()
+	// NewTexture is Window.NewTextureWithOptions for the thread the context
+	// is current on. It waits for the upload to finish, so the texture can
+	// be handed to the Loop goroutine, e.g. with EnqueueGL, and drawn
+	// straight away. From then on only use it there.
+	NewTexture(img image.Image, opts TextureOptions) (Texture, error)
+	// Close destroys the context. Textures created with it stay valid.
+	Close()
 }
 
 type Window interface {
 	// Return the platform-specific window implementation.
 	PlatformWindow() window.Window
 
-	// Create a new texture from an image.
+	// Create a new texture from an image. It stores straight alpha, for
+	// drawing with BlendAlpha. An *image.NRGBA, or an opaque *image.RGBA,
+	// is uploaded straight from its Pix without an intermediate copy.
 	NewTexture(image.Image) (Texture, error)
+	// Create a new texture from an image with the given sampling options.
+	NewTextureWithOptions(image.Image, TextureOptions) (Texture, error)
+	// Create a width x height texture straight from tightly packed rows of
+	// pixels, skipping the conversion NewTexture does. len(pixels) must be
+	// width*height times the format's bytes per pixel.
+	NewTextureRaw(width, height int, format PixelFormat, pixels []byte) (Texture, error)
+	// Create a width x height texture for contents that are re-uploaded
+	// every frame or so. It starts out fully transparent.
+	NewStreamingTexture(width, height int, opts TextureOptions) (StreamingTexture, error)
+	// Create a width x height texture to draw into with Frame.PushTarget.
+	// It has its own depth and stencil buffers for SetDepthTest and masks.
+	// opts.Mipmap is ignored.
+	NewRenderTarget(width, height int, opts TextureOptions) (RenderTarget, error)
+
+	// NewShader compiles a GLSL 1.30 fragment shader for use with
+	// SetPostProcess. It receives `in vec2 v_texCoord` and `in vec4 v_color`,
+	// must write `out vec4 fragColor`, and may declare the uniforms
+	// u_texture (sampler2D), u_resolution (vec2, in pixels) and u_time
+	// (float, in seconds) besides its own. Compile errors are returned as
+	// a *ShaderCompileError.
+	NewShader(fragmentSource string) (Shader, error)
+
+	// SetPostProcess renders each frame into an offscreen texture and then
+	// draws it to the window through shader, for effects such as blur or
+	// CRT emulation. Nil turns it off. Screenshots taken during the frame
+	// see the image before the effect, and the offscreen target is not
+	// multisampled.
+	SetPostProcess(shader Shader)
+
+	// SetRenderScale draws each frame into an offscreen target factor times
+	// the window's size in each direction and scales it to the window
+	// afterwards. At 2 this is 4x supersampling: edges, rotated textures and
+	// scaled content come out smoother. The cost grows with the square of
+	// factor in fill rate and memory, and above 2 the downscale skips some
+	// of the extra samples. Factors below 1 render at reduced resolution
+	// instead, trading sharpness for speed. 1, the default, draws straight
+	// to the window. It combines with SetPostProcess, whose shader then
+	// samples the larger target.
+	SetRenderScale(factor float32)
 
+	// SetOpacity sets the opacity of the whole window, from 0 to 1, e.g. for
+	// an overlay that fades when idle.
+	SetOpacity(opacity float32)
+
+	// SetFullscreenMode switches the monitor mode came from, via
+	// Monitor.Modes, to that mode and covers it with the window. Nil leaves fullscreen
+	// and restores the original mode, as closing the window does.
+	SetFullscreenMode(mode *window.DisplayMode) error
+
+	// SetClear turns clearing each frame to the clear color on or off. With
+	// it off, a frame drawn into the offscreen target of SetPostProcess or
+	// SetRenderScale starts from the previous frame's image, except that a
+	// target that was just created or resized is cleared first rather than
+	// starting with undefined contents.
 	SetClear(enabled bool)
 	SetClearColor(color color.Color)
 
+	// SetLogicalSize fixes the coordinate space used by RenderQuad and text
+	// to width x height regardless of the window size, scaled to the window
+	// according to mode. Areas outside it are filled with the clear color.
+	// A zero width or height restores the default of the window size in
+	// logical pixels.
+	SetLogicalSize(width, height int, mode ScaleMode)
+
+	// ViewSize returns the size of the coordinate space RenderQuad draws in:
+	// the logical size if one is set, otherwise the window size divided by
+	// Scale.
+	ViewSize() (width, height float32)
+
+	// SetDepthTest enables depth testing against the z of RenderQuadZ. The
+	// depth buffer is cleared at the start of each frame while it is enabled.
+	SetDepthTest(enabled bool)
+
+	// SetProjection replaces the projection that maps coordinates to the
+	// view, column-major, from the next frame on. The default is
+	// Ortho(0, width, 0, height) of ViewSize, so (0, 0) is the top-left
+	// corner and y points down. ResetProjection restores it.
+	SetProjection(matrix [16]float32)
+	ResetProjection()
+
+	// HasExtension reports whether the GL context supports the named
+	// extension, e.g. "GL_EXT_texture_filter_anisotropic". Platform
+	// extensions (GLX_*, WGL_*) are not included.
+	HasExtension(name string) bool
+
+	// Renderer returns the GL_RENDERER string, naming the GPU or software
+	// rasterizer the context draws with.
+	Renderer() string
+
+	// IsSoftwareRenderer reports whether the context draws on the CPU, as
+	// Mesa's llvmpipe does in VMs and on machines without a GPU driver.
+	// Texture uploads and fill rate are then far slower, so an app may want
+	// to lower its frame rate or resolution, or tell the user.
+	IsSoftwareRenderer() bool
+
+	// Vendor returns the GL_VENDOR string, the company behind the driver.
+	Vendor() string
+
+	// IsIntegratedGPU reports whether Renderer names a GPU built into the
+	// CPU, such as Intel HD or UHD Graphics. On a laptop that also has a
+	// discrete GPU this usually means PreferDiscreteGPU was not called or
+	// was not honoured. The check is by name, so unfamiliar GPUs report
+	// false.
+	IsIntegratedGPU() bool
+
 	// Scale returns the display scaling factor (e.g., 1.0 for 96 DPI, 2.0 for 192 DPI).
 	Scale() float32
 
+	// SetScaleChangeCallback registers fn to be called from Loop, before the
+	// frame is drawn, when the display scale factor changes, typically
+	// because the window moved to a monitor with a different DPI. ViewSize
+	// and the projection already reflect newScale when fn runs.
+	SetScaleChangeCallback(fn func(newScale float32))
+
 	// Call f for each frame until it returns an error.
 	Loop(func(f Frame) error) error
 
+	// SetCaptureCallback registers fn to receive every rendered frame, as
+	// returned by Frame.Screenshot. Frames are read back asynchronously and
+	// delivered in order a couple of frames after being presented. This
+	// still costs a full-window copy from the GPU per frame, so only enable
+	// it while recording. A nil fn disables capture.
+	SetCaptureCallback(fn func(image.Image))
+
+	// ProcessEvents handles pending window events without drawing, keeping
+	// the window responsive during long work inside a step function. Like
+	// all other methods except RequestRedraw it must be called on the thread
+	// running Loop; GL calls are not allowed from other goroutines. Key and
+	// button edge states (Pressed, Released) advance as they do each frame.
+	// It returns false once the window has been closed, in which case Loop
+	// returns after the current step.
+	ProcessEvents() bool
+
+	// SetMaxDelta caps Frame.DeltaTime, so a stall such as a window drag,
+	// a GC pause or an idle spell in RedrawOnDemand mode shows up as one
+	// long frame rather than a jump that breaks a simulation. The default
+	// is DefaultMaxDelta; zero or less removes the cap.
+	SetMaxDelta(d time.Duration)
+
+	// SetFrameSleep sets how long Loop sleeps after presenting each frame
+	// in RedrawContinuous mode. The default is DefaultFrameSleep; zero
+	// removes the sleep, for benchmarks or when vsync already paces frames.
+	SetFrameSleep(d time.Duration)
+
+	// SetClock replaces the clock Loop reads the time from and sleeps on,
+	// for driving frames with synthetic time. Nil restores RealClock.
+	SetClock(c Clock)
+
+	// SetRedrawMode selects between continuous and on-demand redrawing.
+	// The default is RedrawContinuous.
+	SetRedrawMode(mode RedrawMode)
+
+	// RequestRedraw wakes an on-demand Loop to draw a frame. Unlike the
+	// other methods it is safe to call from any goroutine.
+	RequestRedraw()
+
+	// EnqueueGL runs fn on the Loop goroutine before the next frame is
+	// drawn, and requests that frame. It is safe to call from any
+	// goroutine, so workers such as a network decoder can create or update
+	// textures without sharing state with the frame function. Functions
+	// run in the order queued.
+	EnqueueGL(fn func(Window))
+
+	// NewSharedContext creates a GL context for loading textures on another
+	// thread; see SharedContext. Like the other methods it must be called
+	// on the Loop goroutine, and the returned context must be closed before
+	// the window is.
+	NewSharedContext() (SharedContext, error)
+
 	// GetShaderProgram returns the graphics shader program ID for state restoration.
 	GetShaderProgram() uint32
+
+	// InvalidateState tells the window that GL state it relies on (program,
+	// vertex array, buffer or texture bindings) was changed by other code, so
+	// the next draw rebinds everything instead of taking the fast path.
+	// Call it after switching contexts with the platform window's
+	// ClearCurrent and MakeCurrent, too.
+	InvalidateState()
+
+	// Flush draws the quads and shapes queued so far. The Frame's drawing
+	// methods batch consecutive draws that use the same texture, so call
+	// Flush before drawing with GL directly, or switching contexts, for
+	// what was drawn earlier to end up underneath.
+	Flush()
 }
 
 // Each platform implements a New() method to return a Window.
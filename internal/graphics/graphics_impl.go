@@ -1,10 +1,15 @@
 package graphics
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
@@ -14,7 +19,7 @@ import (
 
 const (
 	vertexShaderSource = `#version 130
-in vec2 a_position;
+in vec3 a_position;
 in vec2 a_texCoord;
 in vec4 a_color;
 
@@ -24,7 +29,7 @@ out vec4 v_color;
 uniform mat4 u_proj;
 
 void main() {
-	gl_Position = u_proj * vec4(a_position, 0.0, 1.0);
+	gl_Position = u_proj * vec4(a_position, 1.0);
 	v_texCoord = a_texCoord;
 	v_color = a_color;
 }`
@@ -36,31 +41,152 @@ in vec4 v_color;
 out vec4 fragColor;
 
 uniform sampler2D u_texture;
+uniform float u_alphaCutoff;
 
 void main() {
-	fragColor = texture(u_texture, v_texCoord) * v_color;
+	vec4 color = texture(u_texture, v_texCoord) * v_color;
+	if (color.a < u_alphaCutoff) {
+		discard;
+	}
+	fragColor = color;
 }`
 )
 
+const (
+	// vertexFloats is the number of floats in one vertex: 3 pos + 2 tex + 4 color.
+	vertexFloats = 9
+	// vertexSize is the size in bytes of one vertex.
+	vertexSize = vertexFloats * 4
+	// quadBufferQuads is how many quads fit in the streaming vertex buffer
+	// before it is orphaned.
+	quadBufferQuads = 1024
+	// maskAlphaCutoff is the alpha below which fragments are left out of a
+	// stencil mask.
+	maskAlphaCutoff = 0.5
+)
+
 type glWindow struct {
 	platform window.Window
 	gl       glpkg.OpenGL
 
 	clearEnabled bool
 	clearColor   color.Color
+	depthTest    bool
 	scale        float32
 
+	blendMode BlendMode
+
+	onScaleChange func(newScale float32)
+
+	// Fixed logical resolution, if logicalW and logicalH are non-zero, and
+	// the backing-pixel rectangle (top-left origin) it was last mapped to.
+	logicalW  int
+	logicalH  int
+	scaleMode ScaleMode
+	viewRect  image.Rectangle
+
+	redrawMode      RedrawMode
+	redrawRequested atomic.Bool
+
+	// Functions from EnqueueGL waiting to run on the Loop goroutine.
+	glQueueMu sync.Mutex
+	glQueue   []func(Window)
+
+	// Time since the previous frame started, clamped to maxDelta, as told
+	// by clock.
+	clock     Clock
+	lastFrame time.Time
+	delta     time.Duration
+	maxDelta  time.Duration
+
+	// Pause after each frame in RedrawContinuous mode.
+	frameSleep time.Duration
+
+	capture  func(image.Image)
+	captures []Future
+
+	// frameCount counts frames drawn by Loop; freePBOs holds pixel pack
+	// buffers from resolved readbacks for reuse.
+	frameCount uint64
+	freePBOs   []uint32
+
 	// GL3 resources
 	shaderProgram uint32
 	vao           uint32
 	vbo           uint32
+	stream        *streamBuffer
 	projUniform   int32
+	texUniform    int32
+	cutoffUniform int32
+
+	// u_proj is projection, or the default ortho projection unless
+	// customProjection is set, times the view set for the current frame.
+	projection       [16]float32
+	customProjection bool
+	view             [16]float32
+
+	// Post-processing: while post is set, frames are drawn into postTarget
+	// and composited onto the window through it. framePost is the shader
+	// the current frame started with, in case step changes post.
+	post       *glShader
+	framePost  *glShader
+	postTarget *postTarget
+
+	// Supersampling: frames are drawn into postTarget at renderScale times
+	// the window size when it isn't 1, and copied down through resolve if
+	// there is no post shader. target is the offscreen target the current
+	// frame is drawn into, or nil when drawing straight to the window.
+	renderScale float32
+	resolve     *glShader
+	target      *postTarget
+
+	// Render targets pushed by the current frame, innermost last.
+	targets []pushedTarget
+
+	maxTextureSize int
+	maxAnisotropy  float32 // zero without anisotropic filtering
+	extensions     map[string]bool
+	renderer       string
+	vendor         string
+
+	// Cached binding state for the RenderQuad fast path. prepareFrame binds
+	// the program, VAO and VBO once per frame; stateDirty is set when
+	// something outside this package may have changed them.
+	boundTexture uint32
+	stateDirty   bool
+	quad         [6 * vertexFloats]float32 // RenderQuad's vertices
+
+	// Triangles queued by the drawing methods, all sampling batchTex, to be
+	// drawn together by flush. The buffer holds as much as stream does.
+	batch    []float32
+	batchTex *glTexture
+
+	// white is the texture untextured shapes are drawn with.
+	white *glTexture
+
+	// owner is the goroutine that created the window; see SetDebug.
+	owner uint64
 }
 
 type glTexture struct {
 	id uint32
 	w  int
 	h  int
+
+	win         *glWindow
+	mipmap      bool
+	premultiply bool
+
+	// Set for textures from NewStreamingTexture.
+	pbo uint32
+	pix []byte // conversion scratch for Update
+
+	// Set for textures from NewRenderTarget.
+	fbo        uint32
+	depth      uint32 // packed depth and stencil renderbuffer
+	noClear    bool
+	clearColor color.Color
+	fresh      bool // storage allocated but not cleared yet
 }
 
 type glFrame struct {
@@ -69,66 +195,135 @@ type glFrame struct {
 
 // Screenshot implements Frame.
 func (f glFrame) Screenshot() (image.Image, error) {
-	bw, bh := f.w.platform.BackingSize()
-	rgba := image.NewRGBA(image.Rect(0, 0, bw, bh))
-	f.w.gl.ReadPixels(0, 0, int32(bw), int32(bh), glpkg.RGBA, glpkg.UnsignedByte, unsafe.Pointer(&rgba.Pix[0]))
+	tw, th := f.w.targetSize()
+	rgba := image.NewRGBA(image.Rect(0, 0, tw, th))
+	// Make sure everything drawn so far has landed before reading it back.
+	f.w.flush()
+	f.w.gl.Finish()
+	f.w.gl.ReadPixels(0, 0, int32(tw), int32(th), glpkg.RGBA, glpkg.UnsignedByte, unsafe.Pointer(&rgba.Pix[0]))
 
 	// Flip the image vertically
-	flipped := image.NewRGBA(image.Rect(0, 0, bw, bh))
-	for y := 0; y < bh; y++ {
-		srcStart := y * rgba.Stride
-		srcEnd := srcStart + rgba.Stride
-		dstStart := (bh - 1 - y) * flipped.Stride
-		dstEnd := dstStart + flipped.Stride
-		copy(flipped.Pix[dstStart:dstEnd], rgba.Pix[srcStart:srcEnd])
-	}
+	flipped := image.NewRGBA(image.Rect(0, 0, tw, th))
+	flipRows(flipped.Pix, rgba.Pix, rgba.Stride)
 
+	if bw, bh := f.w.platform.BackingSize(); bw != tw || bh != th {
+		return resizeBox(flipped, bw, bh), nil
+	}
 	return flipped, nil
 }
 
+// ScreenshotLogical implements Frame.
+func (f glFrame) ScreenshotLogical() (image.Image, error) {
+	img, err := f.Screenshot()
+	if err != nil {
+		return nil, err
+	}
+	full := img.(*image.RGBA)
+	view := full.SubImage(f.w.viewRect.Intersect(full.Rect)).(*image.RGBA)
+
+	w, h := f.w.ViewSize()
+	lw, lh := int(w+0.5), int(h+0.5)
+	if view.Rect.Dx() == lw && view.Rect.Dy() == lh {
+		return view, nil
+	}
+	return resizeBox(view, lw, lh), nil
+}
+
 // New returns a Window backed by OpenGL implementation.
 func New(title string, width, height int) (Window, error) {
-	return newWithProfile(title, width, height, true)
+	return NewWithOptions(Options{Title: title, Width: width, Height: height})
 }
 
-func newWithProfile(title string, width, height int, useCoreProfile bool) (Window, error) {
-	platform, err := window.New(title, width, height, useCoreProfile)
+// NewWithOptions returns a Window backed by OpenGL configured by opts.
+func NewWithOptions(opts Options) (Window, error) {
+	opts = opts.withDefaults()
+
+	platform, err := window.New(opts.Title, opts.Width, opts.Height, window.Options{
+		CoreProfile: !opts.LegacyProfile,
+		GLMajor:     opts.GLMajor,
+		GLMinor:     opts.GLMinor,
+		Samples:     opts.Samples,
+		Transparent: opts.Transparent,
+		StartHidden: opts.StartHidden,
+		Monitor:     opts.Monitor,
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrContextCreation, err)
 	}
 	gl, err := platform.GL()
+	if err != nil {
+		platform.Close()
+		return nil, fmt.Errorf("%w: %w", ErrContextCreation, err)
+	}
+
+	w, err := newGLWindow(platform, gl, opts)
 	if err != nil {
 		platform.Close()
 		return nil, err
 	}
+	return w, nil
+}
 
+// newGLWindow sets up drawing with gl, the context of platform, which must
+// be current.
+func newGLWindow(platform window.Window, gl glpkg.OpenGL, opts Options) (*glWindow, error) {
 	// Check GL version
 	versionStr := gl.GetString(glpkg.Version)
 	var major, minor int
 	if _, err := fmt.Sscanf(versionStr, "%d.%d", &major, &minor); err != nil || major < 3 {
-		platform.Close()
-		return nil, fmt.Errorf("OpenGL 3.0+ required, got version: %s", versionStr)
+		return nil, fmt.Errorf("%w, got version: %s", ErrGLVersionTooLow, versionStr)
 	}
 
 	gl.Enable(glpkg.Blend)
-	gl.BlendFunc(glpkg.SrcAlpha, glpkg.OneMinusSrcAlpha)
+	if opts.Samples > 0 {
+		gl.Enable(glpkg.Multisample)
+	}
 
 	w := &glWindow{
 		platform:     platform,
 		gl:           gl,
-		clearEnabled: true,
-		clearColor:   ColorBlack,
+		clearEnabled: !opts.NoClear,
+		clearColor:   opts.ClearColor,
 		scale:        platform.Scale(),
+		maxDelta:     DefaultMaxDelta,
+		frameSleep:   DefaultFrameSleep,
+		clock:        RealClock{},
+		renderScale:  1,
+		owner:        goroutineID(),
+	}
+	if w.clearColor == nil {
+		w.clearColor = ColorBlack
+		if opts.Transparent {
+			w.clearColor = ColorTransparent
+		}
+	}
+	w.applyBlend()
+
+	var maxTextureSize int32
+	gl.GetIntegerv(glpkg.MaxTextureSize, &maxTextureSize)
+	w.maxTextureSize = int(maxTextureSize)
+
+	w.extensions = loadExtensions(gl)
+	w.renderer = gl.GetString(glpkg.Renderer)
+	w.vendor = gl.GetString(glpkg.Vendor)
+	if w.HasExtension("GL_EXT_texture_filter_anisotropic") || w.HasExtension("GL_ARB_texture_filter_anisotropic") {
+		gl.GetFloatv(glpkg.MaxTextureMaxAnisotropy, &w.maxAnisotropy)
 	}
 
 	// Create shader program
 	program, err := createShaderProgram(gl, vertexShaderSource, fragmentShaderSource)
 	if err != nil {
-		platform.Close()
-		return nil, fmt.Errorf("failed to create shader program: %v", err)
+		return nil, fmt.Errorf("failed to create shader program: %w", err)
 	}
 	w.shaderProgram = program
 	w.projUniform = gl.GetUniformLocation(program, "u_proj")
+	w.texUniform = gl.GetUniformLocation(program, "u_texture")
+	w.cutoffUniform = gl.GetUniformLocation(program, "u_alphaCutoff")
+
+	// The sampler always reads from texture unit 0.
+	gl.UseProgram(program)
+	gl.Uniform1i(w.texUniform, 0)
+	gl.Uniform1f(w.cutoffUniform, 0)
 
 	// Create VAO and VBO
 	var vao, vbo uint32
@@ -139,21 +334,23 @@ func newWithProfile(title string, width, height int, useCoreProfile bool) (Windo
 
 	gl.BindVertexArray(vao)
 	gl.BindBuffer(glpkg.ArrayBuffer, vbo)
-	// Allocate buffer for 6 vertices (2 triangles) * (2 pos + 2 tex + 4 color) floats
-	gl.BufferData(glpkg.ArrayBuffer, 6*8*4, nil, glpkg.DynamicDraw)
+	// Allocate a streaming buffer for quadBufferQuads quads of 6 vertices
+	// (2 triangles) each.
+	w.stream = newStreamBuffer(gl, quadBufferQuads*6*vertexSize)
+	w.batch = make([]float32, 0, quadBufferQuads*6*vertexFloats)
 
 	// Set up vertex attributes
-	// Position: 2 floats at offset 0
+	// Position: 3 floats at offset 0
 	posLoc := gl.GetAttribLocation(program, "a_position")
 	texLoc := gl.GetAttribLocation(program, "a_texCoord")
 	colLoc := gl.GetAttribLocation(program, "a_color")
-	gl.VertexAttribPointer(uint32(posLoc), 2, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(0)))
+	gl.VertexAttribPointer(uint32(posLoc), 3, glpkg.Float, false, vertexSize, 0)
 	gl.EnableVertexAttribArray(uint32(posLoc))
-	// TexCoord: 2 floats at offset 2*4 = 8
-	gl.VertexAttribPointer(uint32(texLoc), 2, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(8)))
+	// TexCoord: 2 floats at offset 3*4 = 12
+	gl.VertexAttribPointer(uint32(texLoc), 2, glpkg.Float, false, vertexSize, 12)
 	gl.EnableVertexAttribArray(uint32(texLoc))
-	// Color: 4 floats at offset 4*4 = 16
-	gl.VertexAttribPointer(uint32(colLoc), 4, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(16)))
+	// Color: 4 floats at offset 5*4 = 20
+	gl.VertexAttribPointer(uint32(colLoc), 4, glpkg.Float, false, vertexSize, 20)
 	gl.EnableVertexAttribArray(uint32(colLoc))
 
 	return w, nil
@@ -169,7 +366,7 @@ func createShaderProgram(gl glpkg.OpenGL, vertexSrc, fragmentSrc string) (uint32
 	if status == 0 {
 		log := gl.GetShaderInfoLog(vertexShader)
 		gl.DeleteShader(vertexShader)
-		return 0, fmt.Errorf("vertex shader compilation failed: %s", log)
+		return 0, &ShaderCompileError{Stage: "vertex", Log: log}
 	}
 
 	// Create and compile fragment shader
@@ -181,7 +378,7 @@ func createShaderProgram(gl glpkg.OpenGL, vertexSrc, fragmentSrc string) (uint32
 		log := gl.GetShaderInfoLog(fragmentShader)
 		gl.DeleteShader(vertexShader)
 		gl.DeleteShader(fragmentShader)
-		return 0, fmt.Errorf("fragment shader compilation failed: %s", log)
+		return 0, &ShaderCompileError{Stage: "fragment", Log: log}
 	}
 
 	// Create program and link
@@ -195,7 +392,7 @@ func createShaderProgram(gl glpkg.OpenGL, vertexSrc, fragmentSrc string) (uint32
 		gl.DeleteShader(vertexShader)
 		gl.DeleteShader(fragmentShader)
 		gl.DeleteProgram(program)
-		return 0, fmt.Errorf("program linking failed: %s", log)
+		return 0, &ShaderCompileError{Stage: "link", Log: log}
 	}
 
 	// Shaders can be deleted after linking
@@ -213,35 +410,191 @@ func (w *glWindow) Scale() float32 {
 	return w.scale
 }
 
+func (w *glWindow) SetScaleChangeCallback(fn func(newScale float32)) {
+	w.onScaleChange = fn
+}
+
 func (w *glWindow) GetShaderProgram() uint32 {
 	return w.shaderProgram
 }
 
 func (w *glWindow) NewTexture(img image.Image) (Texture, error) {
-	nrgba := image.NewNRGBA(img.Bounds())
-	draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return w.NewTextureWithOptions(img, TextureOptions{})
+}
 
-	var texID uint32
-	w.gl.GenTextures(1, &texID)
-	w.gl.BindTexture(glpkg.Texture2D, texID)
-	w.gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMinFilter, glpkg.Nearest)
-	w.gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMagFilter, glpkg.Nearest)
+func (w *glWindow) NewTextureWithOptions(img image.Image, opts TextureOptions) (Texture, error) {
+	w.checkGoroutine("NewTextureWithOptions")
+	t, err := w.createTexture(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	w.boundTexture = t.id
+	return t, nil
+}
+
+// createTexture creates a texture from img in whichever context is current,
+// leaving it bound there. It doesn't touch the window's binding cache, so
+// it is also used by shared contexts.
+func (w *glWindow) createTexture(img image.Image, opts TextureOptions) (*glTexture, error) {
+	b := img.Bounds()
+	if w.maxTextureSize > 0 && (b.Dx() > w.maxTextureSize || b.Dy() > w.maxTextureSize) {
+		return nil, fmt.Errorf("texture size %dx%d exceeds the GL maximum of %dx%d",
+			b.Dx(), b.Dy(), w.maxTextureSize, w.maxTextureSize)
+	}
 
-	if len(nrgba.Pix) > 0 {
+	pix := texturePixels(img, opts.Premultiply)
+	texID := w.genTexture(opts)
+	if len(pix) > 0 {
 		w.gl.TexImage2D(
 			glpkg.Texture2D,
 			0,
 			int32(glpkg.RGBA),
-			int32(nrgba.Rect.Dx()),
-			int32(nrgba.Rect.Dy()),
+			int32(b.Dx()),
+			int32(b.Dy()),
 			0,
 			glpkg.RGBA,
 			glpkg.UnsignedByte,
-			unsafe.Pointer(&nrgba.Pix[0]),
+			unsafe.Pointer(&pix[0]),
 		)
+		if opts.Mipmap {
+			w.gl.GenerateMipmap(glpkg.Texture2D)
+		}
 	}
 
-	return &glTexture{id: texID, w: nrgba.Rect.Dx(), h: nrgba.Rect.Dy()}, nil
+	return &glTexture{id: texID, w: b.Dx(), h: b.Dy(), win: w, mipmap: opts.Mipmap, premultiply: opts.Premultiply}, nil
+}
+
+// texturePixels returns the tightly packed RGBA bytes of img, premultiplied
+// if asked. The pixels of an *image.NRGBA or *image.RGBA with contiguous
+// rows are used in place when they already have the right alpha, as they
+// do whenever the image is opaque; anything else is copied.
+func texturePixels(img image.Image, premultiply bool) []byte {
+	b := img.Bounds()
+	stride := b.Dx() * 4
+	switch m := img.(type) {
+	case *image.NRGBA:
+		if m.Stride == stride && (!premultiply || m.Opaque()) {
+			return m.Pix[:stride*b.Dy()]
+		}
+	case *image.RGBA:
+		if m.Stride == stride && (premultiply || m.Opaque()) {
+			return m.Pix[:stride*b.Dy()]
+		}
+	}
+
+	// Drawing into an image.RGBA premultiplies; the bytes are uploaded the
+	// same way either way.
+	if premultiply {
+		rgba := image.NewRGBA(b)
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+		return rgba.Pix
+	}
+	nrgba := image.NewNRGBA(b)
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	return nrgba.Pix
+}
+
+func (w *glWindow) NewTextureRaw(width, height int, format PixelFormat, pixels []byte) (Texture, error) {
+	w.checkGoroutine("NewTextureRaw")
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("invalid texture size %dx%d", width, height)
+	}
+	if w.maxTextureSize > 0 && (width > w.maxTextureSize || height > w.maxTextureSize) {
+		return nil, fmt.Errorf("texture size %dx%d exceeds the GL maximum of %dx%d",
+			width, height, w.maxTextureSize, w.maxTextureSize)
+	}
+	if want := width * height * format.bytesPerPixel(); len(pixels) != want {
+		return nil, fmt.Errorf("got %d bytes of pixels for a %dx%d texture, want %d",
+			len(pixels), width, height, want)
+	}
+
+	texID := w.genTexture(TextureOptions{})
+	w.boundTexture = texID
+	if len(pixels) > 0 {
+		// Rows of 1- or 2-byte pixels aren't 4-byte aligned in general.
+		w.gl.PixelStorei(glpkg.UnpackAlignment, 1)
+		w.gl.TexImage2D(glpkg.Texture2D, 0, format.glInternalFormat(), int32(width), int32(height), 0,
+			format.glFormat(), format.glType(), unsafe.Pointer(&pixels[0]))
+		w.gl.PixelStorei(glpkg.UnpackAlignment, 4)
+	}
+	return &glTexture{id: texID, w: width, h: height, win: w}, nil
+}
+
+// glFormat returns the GL pixel transfer format for f.
+func (f PixelFormat) glFormat() uint32 {
+	switch f {
+	case PixelFormatBGRA:
+		return glpkg.BGRA
+	case PixelFormatRed:
+		return glpkg.Red
+	case PixelFormatRGB565:
+		return glpkg.RGB
+	}
+	return glpkg.RGBA
+}
+
+// glType returns the GL pixel data type for f.
+func (f PixelFormat) glType() uint32 {
+	if f == PixelFormatRGB565 {
+		return glpkg.UnsignedShort565
+	}
+	return glpkg.UnsignedByte
+}
+
+// glInternalFormat returns the GL format textures created from pixels in f
+// are stored in.
+func (f PixelFormat) glInternalFormat() int32 {
+	switch f {
+	case PixelFormatRed:
+		return glpkg.R8
+	case PixelFormatRGB565:
+		return glpkg.RGB
+	}
+	return glpkg.RGBA
+}
+
+func (f Filter) glFilter() int32 {
+	if f == FilterLinear {
+		return glpkg.Linear
+	}
+	return glpkg.Nearest
+}
+
+// genTexture creates a texture, binds it and sets its sampling parameters
+// from opts. Callers on the Loop goroutine record the binding in
+// boundTexture.
+func (w *glWindow) genTexture(opts TextureOptions) uint32 {
+	var texID uint32
+	w.gl.GenTextures(1, &texID)
+	w.gl.BindTexture(glpkg.Texture2D, texID)
+	minFilter := opts.Filter.glFilter()
+	if opts.Mipmap {
+		minFilter = glpkg.LinearMipmapLinear
+	}
+	w.gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMinFilter, minFilter)
+	w.gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMagFilter, opts.Filter.glFilter())
+	if opts.Anisotropy > 1 && w.maxAnisotropy > 1 {
+		w.gl.TexParameterf(glpkg.Texture2D, glpkg.TextureMaxAnisotropy, min(opts.Anisotropy, w.maxAnisotropy))
+	}
+	return texID
+}
+
+func (w *glWindow) InvalidateState() {
+	w.stateDirty = true
+}
+
+// Flush implements Window.
+func (w *glWindow) Flush() {
+	w.checkGoroutine("Flush")
+	w.flush()
+}
+
+func (w *glWindow) SetOpacity(opacity float32) {
+	w.platform.SetOpacity(opacity)
+}
+
+func (w *glWindow) SetFullscreenMode(mode *window.DisplayMode) error {
+	return w.platform.SetFullscreenMode(mode)
 }
 
 func (w *glWindow) SetClear(enabled bool) {
@@ -252,50 +605,303 @@ func (w *glWindow) SetClearColor(c color.Color) {
 	w.clearColor = c
 }
 
-func (w *glWindow) Loop(step func(f Frame) error) error {
+func (w *glWindow) SetLogicalSize(width, height int, mode ScaleMode) {
+	w.logicalW = width
+	w.logicalH = height
+	w.scaleMode = mode
+}
+
+func (w *glWindow) ViewSize() (float32, float32) {
+	if w.logicalW > 0 && w.logicalH > 0 {
+		return float32(w.logicalW), float32(w.logicalH)
+	}
+	bw, bh := w.platform.BackingSize()
+	return float32(bw) / w.scale, float32(bh) / w.scale
+}
+
+func (w *glWindow) SetDepthTest(enabled bool) {
+	w.flush()
+	w.depthTest = enabled
+	if enabled {
+		// LEqual rather than Less so quads at the same depth still layer in
+		// draw order.
+		w.gl.Enable(glpkg.DepthTest)
+		w.gl.DepthFunc(glpkg.LEqual)
+	} else {
+		w.gl.Disable(glpkg.DepthTest)
+	}
+}
+
+func (w *glWindow) Loop(step func(f Frame) error) (err error) {
+	w.checkGoroutine("Loop")
+	// Deferred first so it runs last: the window is closed and the OS thread
+	// unlocked by the time a panic in step is turned into an error.
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
 	defer w.platform.Close()
 	defer func() {
 		var vao, vbo uint32 = w.vao, w.vbo
 		w.gl.DeleteVertexArrays(1, &vao)
 		w.gl.DeleteBuffers(1, &vbo)
 		w.gl.DeleteProgram(w.shaderProgram)
+		w.captures = nil
+		w.deletePostTarget()
+		if len(w.freePBOs) > 0 {
+			w.gl.DeleteBuffers(int32(len(w.freePBOs)), &w.freePBOs[0])
+			w.freePBOs = nil
+		}
 	}()
 
 	frame := glFrame{w: w}
-	for w.platform.Poll() {
-		w.prepareFrame()
+	w.redrawRequested.Store(true) // always draw the first frame
+	for {
+		if w.redrawMode == RedrawOnDemand && !w.redrawRequested.Load() {
+			w.platform.Wait()
+		}
+		w.redrawRequested.Store(false)
+
+		if !w.platform.Poll() {
+			break
+		}
+
+		w.advanceClock(w.clock.Now())
+
+		if err := w.prepareFrame(); err != nil {
+			return err
+		}
 
 		if err := step(frame); err != nil {
+			if errors.Is(err, ErrStopLoop) {
+				return nil
+			}
 			return err
 		}
+		w.flush()
+		w.popTargets()
+
+		if w.framePost != nil {
+			w.drawPostProcess()
+		}
+
+		if w.capture != nil {
+			// The back buffer is undefined after Swap, so start reading it
+			// back first; the result is delivered a couple of frames later.
+			w.captures = append(w.captures, frame.ScreenshotAsync())
+		}
 
 		w.platform.Swap()
-		time.Sleep(time.Second / 120)
+		w.frameCount++
+		if err := w.deliverCaptures(); err != nil {
+			return err
+		}
+		if w.redrawMode == RedrawContinuous && w.frameSleep > 0 {
+			w.clock.Sleep(w.frameSleep)
+		}
+	}
+	return nil
+}
+
+func (w *glWindow) SetCaptureCallback(fn func(image.Image)) {
+	w.capture = fn
+}
+
+// deliverCaptures passes finished readbacks to the capture callback in
+// order, waiting on the oldest if too many are outstanding.
+func (w *glWindow) deliverCaptures() error {
+	for len(w.captures) > 0 {
+		next := w.captures[0]
+		if !next.Ready() && w.capture != nil && len(w.captures) <= readbackLatency {
+			break
+		}
+		img, err := next.Wait()
+		w.captures = w.captures[1:]
+		if err != nil {
+			return err
+		}
+		if w.capture != nil {
+			w.capture(img)
+		}
 	}
 	return nil
 }
 
-func (w *glWindow) prepareFrame() {
+func (w *glWindow) ProcessEvents() bool {
+	return w.platform.Poll()
+}
+
+func (w *glWindow) SetMaxDelta(d time.Duration) {
+	w.maxDelta = d
+}
+
+func (w *glWindow) SetFrameSleep(d time.Duration) {
+	w.frameSleep = d
+}
+
+// advanceClock starts a frame at now, updating the delta DeltaTime reports.
+func (w *glWindow) advanceClock(now time.Time) {
+	w.delta = 0
+	if !w.lastFrame.IsZero() {
+		w.delta = now.Sub(w.lastFrame)
+	}
+	w.lastFrame = now
+	if w.maxDelta > 0 {
+		w.delta = min(w.delta, w.maxDelta)
+	}
+}
+
+func (f glFrame) DeltaTime() time.Duration {
+	return f.w.delta
+}
+
+func (w *glWindow) SetRedrawMode(mode RedrawMode) {
+	w.redrawMode = mode
+}
+
+func (w *glWindow) RequestRedraw() {
+	w.redrawRequested.Store(true)
+	w.platform.Wake()
+}
+
+func (w *glWindow) EnqueueGL(fn func(Window)) {
+	w.glQueueMu.Lock()
+	w.glQueue = append(w.glQueue, fn)
+	w.glQueueMu.Unlock()
+	w.RequestRedraw()
+}
+
+// runGLQueue runs the functions queued by EnqueueGL. Any they queue
+// themselves wait for the next frame.
+func (w *glWindow) runGLQueue() {
+	w.glQueueMu.Lock()
+	queue := w.glQueue
+	w.glQueue = nil
+	w.glQueueMu.Unlock()
+	if len(queue) == 0 {
+		return
+	}
+	for _, fn := range queue {
+		fn(w)
+	}
+	// The functions may have bound textures or buffers behind our back.
+	w.stateDirty = true
+}
+
+func (w *glWindow) prepareFrame() error {
+	w.runGLQueue()
+
+	if s := w.platform.Scale(); s > 0 && s != w.scale {
+		w.scale = s
+		if w.onScaleChange != nil {
+			w.onScaleChange(s)
+		}
+	}
+
 	bw, bh := w.platform.BackingSize()
 
-	w.gl.Viewport(0, 0, int32(bw), int32(bh))
+	w.framePost = w.post
+	if w.framePost == nil && w.renderScale != 1 {
+		if err := w.ensureResolve(); err != nil {
+			return err
+		}
+		w.framePost = w.resolve
+	}
+	w.target = nil
+	if w.framePost != nil {
+		tw, th := w.scaledSize(bw, bh)
+		if err := w.bindPostTarget(tw, th); err != nil {
+			return err
+		}
+		w.target = w.postTarget
+	}
 
-	// Compute orthographic projection matrix
-	// Scale coordinates by scale factor
-	width := float32(bw) / w.scale
-	height := float32(bh) / w.scale
-	proj := orthoMatrix(0, width, height, 0, -1, 1)
+	w.viewRect = w.mapView(bw, bh)
+	w.gl.Viewport(w.glRect(w.viewRect))
 
 	// Use shader program and set projection matrix
-	w.gl.UseProgram(w.shaderProgram)
-	w.gl.BindVertexArray(w.vao)
-	w.gl.UniformMatrix4fv(w.projUniform, 1, false, &proj[0])
+	w.bindState()
+	w.view = identityMatrix
+	w.uploadProjection()
 
-	if w.clearEnabled {
+	// Don't let a mask left open by the previous frame leak into this one.
+	w.endMask()
+
+	// A target with new storage is cleared even with clearing off, since
+	// its contents are undefined.
+	fresh := false
+	if t := w.target; t != nil {
+		fresh, t.fresh = t.fresh, false
+	}
+	if w.clearEnabled || fresh {
 		rgba := ColorToFloat32(w.clearColor)
 		w.gl.ClearColor(rgba[0], rgba[1], rgba[2], rgba[3])
-		w.gl.Clear(glpkg.ColorBufferBit)
+		mask := uint32(glpkg.ColorBufferBit)
+		if w.depthTest {
+			mask |= glpkg.DepthBufferBit
+		}
+		w.gl.Clear(mask)
+	} else if w.depthTest {
+		w.gl.Clear(glpkg.DepthBufferBit)
+	}
+	return nil
+}
+
+// mapView returns the rectangle of the bw x bh backing buffer that the view
+// is drawn into.
+func (w *glWindow) mapView(bw, bh int) image.Rectangle {
+	full := image.Rect(0, 0, bw, bh)
+	if w.logicalW <= 0 || w.logicalH <= 0 || w.scaleMode == ScaleStretch {
+		return full
+	}
+
+	s := min(float64(bw)/float64(w.logicalW), float64(bh)/float64(w.logicalH))
+	if w.scaleMode == ScaleInteger && s >= 1 {
+		s = math.Floor(s)
 	}
+	vw := int(float64(w.logicalW) * s)
+	vh := int(float64(w.logicalH) * s)
+	x := (bw - vw) / 2
+	y := (bh - vh) / 2
+	return image.Rect(x, y, x+vw, y+vh)
+}
+
+// bindState binds the program, VAO, VBO and texture unit used by RenderQuad
+// and forgets which texture was bound.
+func (w *glWindow) bindState() {
+	w.gl.UseProgram(w.shaderProgram)
+	w.gl.BindVertexArray(w.vao)
+	w.gl.BindBuffer(glpkg.ArrayBuffer, w.vbo)
+	w.gl.ActiveTexture(glpkg.Texture0)
+	w.boundTexture = 0
+	w.stateDirty = false
+}
+
+// endMask disables stencil testing and restores normal blending. The program
+// must already be bound.
+func (w *glWindow) endMask() {
+	w.gl.Disable(glpkg.StencilTest)
+	w.gl.StencilMask(0xFF)
+	w.gl.ColorMask(true, true, true, true)
+	w.gl.DepthMask(true)
+	w.applyBlend()
+	w.gl.Uniform1f(w.cutoffUniform, 0)
+}
+
+// applyBlend sets the blend function for w.blendMode. Either way the
+// framebuffer ends up holding premultiplied color with alpha as coverage,
+// which is what compositors and image.RGBA expect.
+func (w *glWindow) applyBlend() {
+	switch w.blendMode {
+	case BlendPremultiplied:
+		w.gl.BlendFunc(glpkg.One, glpkg.OneMinusSrcAlpha)
+		return
+	case BlendAdditive:
+		w.gl.BlendFuncSeparate(glpkg.SrcAlpha, glpkg.One, glpkg.One, glpkg.One)
+		return
+	}
+	w.gl.BlendFuncSeparate(glpkg.SrcAlpha, glpkg.OneMinusSrcAlpha, glpkg.One, glpkg.OneMinusSrcAlpha)
 }
 
 // orthoMatrix creates an orthographic projection matrix (column-major)
@@ -313,13 +919,101 @@ func (f glFrame) WindowSize() (int, int) {
 	return f.w.platform.BackingSize()
 }
 
+func (f glFrame) WindowSizeLogical() (int, int) {
+	w, h := f.w.ViewSize()
+	return int(w), int(h)
+}
+
 func (f glFrame) CursorPos() (float32, float32) {
 	x, y := f.w.platform.Cursor()
+	if f.w.logicalW > 0 && f.w.logicalH > 0 && !f.w.viewRect.Empty() {
+		// Map from the letterboxed view rectangle to logical pixels.
+		vr := f.w.viewRect
+		return (x - float32(vr.Min.X)) * float32(f.w.logicalW) / float32(vr.Dx()),
+			(y - float32(vr.Min.Y)) * float32(f.w.logicalH) / float32(vr.Dy())
+	}
 	// Convert from physical pixel coordinates to logical coordinates
 	// by dividing by the scale factor
 	return x / f.w.scale, y / f.w.scale
 }
 
+// PixelAt implements Frame.
+func (f glFrame) PixelAt(x, y int) (color.RGBA, error) {
+	px, py := f.toPixels(float32(x), float32(y))
+	bw, bh := f.w.platform.BackingSize()
+	if px < 0 || py < 0 || px >= bw || py >= bh {
+		return color.RGBA{}, fmt.Errorf("point (%d, %d) is outside the window", x, y)
+	}
+	var pix [4]byte
+	f.w.flush()
+	gx, gy, _, _ := f.w.glRect(image.Rect(px, py, px+1, py+1))
+	f.w.gl.ReadPixels(gx, gy, 1, 1, glpkg.RGBA, glpkg.UnsignedByte, unsafe.Pointer(&pix[0]))
+	return color.RGBA{R: pix[0], G: pix[1], B: pix[2], A: pix[3]}, nil
+}
+
+// toPixels converts a logical coordinate to the physical pixel containing
+// it, counted from the top-left of the window; the inverse of CursorPos.
+func (f glFrame) toPixels(x, y float32) (int, int) {
+	if f.w.logicalW > 0 && f.w.logicalH > 0 && !f.w.viewRect.Empty() {
+		vr := f.w.viewRect
+		x = float32(vr.Min.X) + x*float32(vr.Dx())/float32(f.w.logicalW)
+		y = float32(vr.Min.Y) + y*float32(vr.Dy())/float32(f.w.logicalH)
+	} else {
+		x, y = x*f.w.scale, y*f.w.scale
+	}
+	return int(math.Floor(float64(x))), int(math.Floor(float64(y)))
+}
+
+func (f glFrame) CursorPosPixels() (float32, float32) {
+	return f.w.platform.Cursor()
+}
+
+func (f glFrame) IsFocused() bool {
+	return f.w.platform.Focused()
+}
+
+func (f glFrame) MouseInWindow() bool {
+	x, y := f.w.platform.Cursor()
+	bw, bh := f.w.platform.BackingSize()
+	return x >= 0 && y >= 0 && x < float32(bw) && y < float32(bh)
+}
+
+func (f glFrame) ClearRect(x, y, width, height float32, c color.Color) {
+	w := f.w
+	r := w.viewToBacking(x, y, width, height)
+	if r.Empty() {
+		return
+	}
+
+	w.flush()
+	w.gl.Enable(glpkg.ScissorTest)
+	w.gl.Scissor(w.glRect(r))
+	rgba := ColorToFloat32(c)
+	w.gl.ClearColor(rgba[0], rgba[1], rgba[2], rgba[3])
+	mask := uint32(glpkg.ColorBufferBit)
+	if w.depthTest {
+		mask |= glpkg.DepthBufferBit
+	}
+	w.gl.Clear(mask)
+	w.gl.Disable(glpkg.ScissorTest)
+}
+
+// viewToBacking converts a rectangle in view coordinates to the backing
+// pixels it covers, clipped to the view.
+func (w *glWindow) viewToBacking(x, y, width, height float32) image.Rectangle {
+	vw, vh := w.ViewSize()
+	vr := w.viewRect
+	sx := float32(vr.Dx()) / vw
+	sy := float32(vr.Dy()) / vh
+	r := image.Rect(
+		vr.Min.X+int(math.Floor(float64(x*sx))),
+		vr.Min.Y+int(math.Floor(float64(y*sy))),
+		vr.Min.X+int(math.Ceil(float64((x+width)*sx))),
+		vr.Min.Y+int(math.Ceil(float64((y+height)*sy))),
+	)
+	return r.Intersect(vr)
+}
+
 func (f glFrame) GetKeyState(key window.Key) window.KeyState {
 	return f.w.platform.GetKeyState(key)
 }
@@ -328,41 +1022,209 @@ func (f glFrame) GetButtonState(button window.Button) window.ButtonState {
 	return f.w.platform.GetButtonState(button)
 }
 
+func (f glFrame) KeyJustPressed(key window.Key) bool {
+	return f.GetKeyState(key) == window.KeyStatePressed
+}
+
+func (f glFrame) KeyJustReleased(key window.Key) bool {
+	return f.GetKeyState(key) == window.KeyStateReleased
+}
+
+func (f glFrame) KeyDown(key window.Key) bool {
+	return f.GetKeyState(key).IsDown()
+}
+
+func (f glFrame) ButtonJustPressed(button window.Button) bool {
+	return f.GetButtonState(button) == window.ButtonStatePressed
+}
+
+func (f glFrame) ButtonJustReleased(button window.Button) bool {
+	return f.GetButtonState(button) == window.ButtonStateReleased
+}
+
+func (f glFrame) ButtonDown(button window.Button) bool {
+	return f.GetButtonState(button).IsDown()
+}
+
+func (f glFrame) Modifiers() window.Modifiers {
+	var mods window.Modifiers
+	for k := window.KeyLeftShift; k <= window.KeyRightSuper; k++ {
+		if f.KeyDown(k) {
+			mods |= k.Modifier()
+		}
+	}
+	return mods
+}
+
+func (f glFrame) ChordPressed(mods window.Modifiers, key window.Key) bool {
+	if !f.KeyJustPressed(key) {
+		return false
+	}
+	// A modifier used as the chord's key is held by definition.
+	return f.Modifiers()&^key.Modifier() == mods&^key.Modifier()
+}
+
 func (f glFrame) RenderQuad(x, y, width, height float32, tex Texture, c color.Color) {
+	f.RenderQuadZ(x, y, 0, width, height, tex, c)
+}
+
+func (f glFrame) RenderQuadZ(x, y, z, width, height float32, tex Texture, c color.Color) {
+	f.renderQuad(x, y, z, width, height, tex, 0, 0, 1, 1, c)
+}
+
+func (f glFrame) RenderQuadUV(x, y, width, height float32, tex Texture, u0, v0, u1, v1 float32, c color.Color) {
+	f.renderQuad(x, y, 0, width, height, tex, u0, v0, u1, v1, c)
+}
+
+func (f glFrame) RenderRect(dst image.Rectangle, tex Texture, c color.Color) {
+	f.RenderQuad(float32(dst.Min.X), float32(dst.Min.Y), float32(dst.Dx()), float32(dst.Dy()), tex, c)
+}
+
+func (f glFrame) RenderRectUV(dst, src image.Rectangle, tex Texture, c color.Color) {
+	if tex == nil {
+		return
+	}
+	tw, th := tex.Size()
+	if tw == 0 || th == 0 {
+		return
+	}
+	f.RenderQuadUV(float32(dst.Min.X), float32(dst.Min.Y), float32(dst.Dx()), float32(dst.Dy()), tex,
+		float32(src.Min.X)/float32(tw), float32(src.Min.Y)/float32(th),
+		float32(src.Max.X)/float32(tw), float32(src.Max.Y)/float32(th), c)
+}
+
+func (f glFrame) renderQuad(x, y, z, width, height float32, tex Texture, u0, v0, u1, v1 float32, c color.Color) {
 	t, ok := tex.(*glTexture)
 	if !ok {
 		return
 	}
 
-	// Bind texture
-	f.w.gl.ActiveTexture(glpkg.Texture0)
-	f.w.gl.BindTexture(glpkg.Texture2D, t.id)
-	texUniform := f.w.gl.GetUniformLocation(f.w.shaderProgram, "u_texture")
-	f.w.gl.Uniform1i(texUniform, 0)
-
 	// Convert color to float32 RGBA
 	rgba := ColorToFloat32(c)
 
-	// Update vertex buffer with quad data (2 triangles)
-	vertices := [6 * 8]float32{
+	f.drawTriangles(t, appendQuad(f.w.quad[:0], x, y, z, width, height, u0, v0, u1, v1, rgba))
+}
+
+// appendQuad appends the two triangles of a quad to vertices.
+func appendQuad(vertices []float32, x, y, z, width, height, u0, v0, u1, v1 float32, rgba [4]float32) []float32 {
+	return append(vertices,
 		// Triangle 1
-		x, y, 0, 0, rgba[0], rgba[1], rgba[2], rgba[3], // top-left
-		x + width, y, 1, 0, rgba[0], rgba[1], rgba[2], rgba[3], // top-right
-		x, y + height, 0, 1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-left
+		x, y, z, u0, v0, rgba[0], rgba[1], rgba[2], rgba[3], // top-left
+		x+width, y, z, u1, v0, rgba[0], rgba[1], rgba[2], rgba[3], // top-right
+		x, y+height, z, u0, v1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-left
 		// Triangle 2
-		x + width, y, 1, 0, rgba[0], rgba[1], rgba[2], rgba[3], // top-right
-		x + width, y + height, 1, 1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-right
-		x, y + height, 0, 1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-left
+		x+width, y, z, u1, v0, rgba[0], rgba[1], rgba[2], rgba[3], // top-right
+		x+width, y+height, z, u1, v1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-right
+		x, y+height, z, u0, v1, rgba[0], rgba[1], rgba[2], rgba[3], // bottom-left
+	)
+}
+
+func (t *glTexture) Size() (int, int) {
+	return t.w, t.h
+}
+
+// SetFilter implements Texture.
+func (t *glTexture) SetFilter(minFilter, magFilter Filter) {
+	t.win.checkGoroutine("Texture.SetFilter")
+	glMin := minFilter.glFilter()
+	if t.mipmap && minFilter == FilterLinear {
+		glMin = glpkg.LinearMipmapLinear
 	}
+	t.win.flush()
+	gl := t.win.gl
+	gl.BindTexture(glpkg.Texture2D, t.id)
+	t.win.boundTexture = t.id
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMinFilter, glMin)
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMagFilter, magFilter.glFilter())
+}
 
-	f.w.gl.BindBuffer(glpkg.ArrayBuffer, f.w.vbo)
-	f.w.gl.BufferSubData(glpkg.ArrayBuffer, 0, len(vertices)*4, unsafe.Pointer(&vertices[0]))
+// Delete implements Texture.
+func (t *glTexture) Delete() {
+	w := t.win
+	w.checkGoroutine("Texture.Delete")
+	if t.id == 0 {
+		return
+	}
+	if w.batchTex == t {
+		w.flush()
+		w.batchTex = nil
+	}
+	gl := w.gl
+	gl.DeleteTextures(1, &t.id)
+	if w.boundTexture == t.id {
+		w.boundTexture = 0
+	}
+	if t.pbo != 0 {
+		gl.DeleteBuffers(1, &t.pbo)
+	}
+	if t.fbo != 0 {
+		gl.DeleteFramebuffers(1, &t.fbo)
+		gl.DeleteRenderbuffers(1, &t.depth)
+	}
+	t.id, t.pbo, t.fbo, t.depth = 0, 0, 0, 0
+	t.pix = nil
+}
 
-	// Draw
-	f.w.gl.BindVertexArray(f.w.vao)
-	f.w.gl.DrawArrays(glpkg.Triangles, 0, 6)
+func (f glFrame) RequestRedraw() {
+	f.w.redrawRequested.Store(true)
 }
 
-func (t *glTexture) Size() (int, int) {
-	return t.w, t.h
+func (f glFrame) DrawCursor(tex Texture, hotX, hotY float32) {
+	if tex == nil {
+		return
+	}
+	x, y := f.CursorPos()
+	w, h := tex.Size()
+	f.RenderQuad(x-hotX, y-hotY, float32(w), float32(h), tex, ColorWhite)
+}
+
+func (f glFrame) BeginMask() {
+	f.w.flush()
+	if f.w.stateDirty {
+		f.w.bindState()
+	}
+	gl := f.w.gl
+
+	gl.Enable(glpkg.StencilTest)
+	gl.StencilMask(0xFF)
+	gl.ClearStencil(0)
+	gl.Clear(glpkg.StencilBufferBit)
+	gl.StencilFunc(glpkg.Always, 1, 0xFF)
+	gl.StencilOp(glpkg.Keep, glpkg.Keep, glpkg.Replace)
+
+	// Leave the color and depth buffers untouched while the mask is drawn
+	// and drop transparent texels so they don't become part of the mask.
+	gl.ColorMask(false, false, false, false)
+	gl.DepthMask(false)
+	gl.Uniform1f(f.w.cutoffUniform, maskAlphaCutoff)
+}
+
+func (f glFrame) DrawMasked() {
+	f.w.flush()
+	if f.w.stateDirty {
+		f.w.bindState()
+	}
+	gl := f.w.gl
+
+	gl.StencilFunc(glpkg.Equal, 1, 0xFF)
+	gl.StencilOp(glpkg.Keep, glpkg.Keep, glpkg.Keep)
+	gl.StencilMask(0)
+
+	gl.ColorMask(true, true, true, true)
+	gl.DepthMask(true)
+	gl.Uniform1f(f.w.cutoffUniform, 0)
+}
+
+func (f glFrame) SetBlendMode(mode BlendMode) {
+	f.w.flush()
+	f.w.blendMode = mode
+	f.w.applyBlend()
+}
+
+func (f glFrame) EndMask() {
+	f.w.flush()
+	if f.w.stateDirty {
+		f.w.bindState()
+	}
+	f.w.endMask()
 }
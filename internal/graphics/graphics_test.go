@@ -0,0 +1,307 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+func TestNewTextureKeepsBindingCache(t *testing.T) {
+	w, gl := newTestWindow(t, 64, 64)
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	f := glFrame{w: w}
+
+	a, err := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.RenderQuad(0, 0, 4, 4, a, ColorWhite)
+	w.flush()
+
+	// Creating b binds it, so drawing a again must bind a.
+	for _, create := range []func() (Texture, error){
+		func() (Texture, error) { return w.NewTexture(image.NewRGBA(image.Rect(0, 0, 2, 2))) },
+		func() (Texture, error) { return w.NewTextureRaw(2, 2, PixelFormatRGBA, make([]byte, 16)) },
+		func() (Texture, error) { return w.NewStreamingTexture(2, 2, TextureOptions{}) },
+	} {
+		if _, err := create(); err != nil {
+			t.Fatal(err)
+		}
+		f.RenderQuad(0, 0, 4, 4, a, ColorWhite)
+		w.flush()
+		if want := a.(*glTexture).id; gl.texture != want {
+			t.Fatalf("texture %d bound while drawing, want %d", gl.texture, want)
+		}
+	}
+}
+
+func TestDeleteTexture(t *testing.T) {
+	tests := []struct {
+		name   string
+		create func(w *glWindow) (Texture, error)
+		// want is the number of calls to each delete function.
+		want map[string]int
+	}{
+		{
+			name:   "texture",
+			create: func(w *glWindow) (Texture, error) { return w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 2, 2))) },
+			want:   map[string]int{"DeleteTextures": 1, "DeleteBuffers": 0, "DeleteFramebuffers": 0},
+		},
+		{
+			name:   "streaming texture",
+			create: func(w *glWindow) (Texture, error) { return w.NewStreamingTexture(2, 2, TextureOptions{}) },
+			want:   map[string]int{"DeleteTextures": 1, "DeleteBuffers": 1, "DeleteFramebuffers": 0},
+		},
+		{
+			name:   "render target",
+			create: func(w *glWindow) (Texture, error) { return w.NewRenderTarget(2, 2, TextureOptions{}) },
+			want:   map[string]int{"DeleteTextures": 1, "DeleteBuffers": 0, "DeleteFramebuffers": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, gl := newTestWindow(t, 64, 64)
+			if err := w.prepareFrame(); err != nil {
+				t.Fatal(err)
+			}
+			tex, err := tt.create(w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := tex.(*glTexture).id
+			f := glFrame{w: w}
+			f.RenderQuad(0, 0, 4, 4, tex, ColorWhite)
+
+			tex.Delete()
+			if gl.calls["DrawArrays"] != 1 || gl.lastDraw.texture != id {
+				t.Error("quad queued with the texture not drawn before deleting it")
+			}
+			if !gl.deleted[id] {
+				t.Error("texture not deleted")
+			}
+			if w.boundTexture == id {
+				t.Error("deleted texture still cached as bound")
+			}
+			tex.Delete()
+			for name, want := range tt.want {
+				if got := gl.calls[name]; got != want {
+					t.Errorf("%s called %d times after deleting twice, want %d", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderQuadSkipsRebinds(t *testing.T) {
+	w, gl := newTestWindow(t, 64, 64)
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	f := glFrame{w: w}
+	tex, err := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := gl.calls["BindTexture"] + gl.calls["BindVertexArray"] + gl.calls["UseProgram"]
+	for range 10 {
+		f.RenderQuad(0, 0, 4, 4, tex, ColorWhite)
+		w.flush()
+	}
+	if after := gl.calls["BindTexture"] + gl.calls["BindVertexArray"] + gl.calls["UseProgram"]; after != before {
+		t.Errorf("%d binds drawing the bound texture, want none", after-before)
+	}
+
+	// After InvalidateState everything is bound again, once.
+	before = gl.calls["UseProgram"]
+	w.InvalidateState()
+	for range 10 {
+		f.RenderQuad(0, 0, 4, 4, tex, ColorWhite)
+		w.flush()
+	}
+	if got := gl.calls["UseProgram"] - before; got != 1 {
+		t.Errorf("UseProgram called %d times after InvalidateState, want 1", got)
+	}
+	if gl.texture != tex.(*glTexture).id {
+		t.Errorf("texture %d bound after InvalidateState, want %d", gl.texture, tex.(*glTexture).id)
+	}
+}
+
+// BenchmarkRenderQuad measures the CPU cost of RenderQuad against a GL that
+// does nothing, drawing tiles from one texture or alternating between two.
+func BenchmarkRenderQuad(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		textures int
+	}{
+		{"SameTexture", 1},
+		{"TwoTextures", 2},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			w, _ := newTestWindow(b, 1024, 768)
+			if err := w.prepareFrame(); err != nil {
+				b.Fatal(err)
+			}
+			f := glFrame{w: w}
+			var texs []Texture
+			for range bc.textures {
+				tex, err := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 32, 32)))
+				if err != nil {
+					b.Fatal(err)
+				}
+				texs = append(texs, tex)
+			}
+
+			var c color.Color = ColorWhite
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				x := float32(i%32) * 32
+				y := float32(i/32%24) * 32
+				f.RenderQuad(x, y, 32, 32, texs[i%len(texs)], c)
+			}
+		})
+	}
+}
+
+func TestTexturePixels(t *testing.T) {
+	opaqueRGBA := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range opaqueRGBA.Pix {
+		opaqueRGBA.Pix[i] = 0xff
+	}
+	clearRGBA := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	wide := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+
+	tests := []struct {
+		name        string
+		img         image.Image
+		premultiply bool
+		inPlace     bool
+	}{
+		{"NRGBA", nrgba, false, true},
+		{"NRGBA premultiplied", nrgba, true, false},
+		{"opaque RGBA", opaqueRGBA, false, true},
+		{"translucent RGBA", clearRGBA, false, false},
+		{"RGBA premultiplied", clearRGBA, true, true},
+		{"sub-image", wide.SubImage(image.Rect(2, 0, 6, 4)), false, false},
+		{"gray", image.NewGray(image.Rect(0, 0, 4, 4)), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pix := texturePixels(tt.img, tt.premultiply)
+			if len(pix) != 4*4*4 {
+				t.Fatalf("got %d bytes, want %d", len(pix), 4*4*4)
+			}
+			var src []byte
+			switch m := tt.img.(type) {
+			case *image.NRGBA:
+				src = m.Pix
+			case *image.RGBA:
+				src = m.Pix
+			}
+			if inPlace := len(src) > 0 && &pix[0] == &src[0]; inPlace != tt.inPlace {
+				t.Errorf("used in place = %v, want %v", inPlace, tt.inPlace)
+			}
+		})
+	}
+}
+
+// BenchmarkNewTexture creates a remote-desktop-sized texture from images
+// that can be uploaded in place and ones that must be copied first.
+func BenchmarkNewTexture(b *testing.B) {
+	const width, height = 1280, 800
+	opaque := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 3; i < len(opaque.Pix); i += 4 {
+		opaque.Pix[i] = 0xff
+	}
+	for _, bc := range []struct {
+		name string
+		img  image.Image
+	}{
+		{"NRGBA", image.NewNRGBA(image.Rect(0, 0, width, height))},
+		{"OpaqueRGBA", opaque},
+		{"TranslucentRGBA", image.NewRGBA(image.Rect(0, 0, width, height))},
+		{"Gray", image.NewGray(image.Rect(0, 0, width, height))},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			w, _ := newTestWindow(b, width, height)
+			b.SetBytes(width * height * 4)
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := w.NewTexture(bc.img); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMaskState(t *testing.T) {
+	w, gl := newTestWindow(t, 64, 64)
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	f := glFrame{w: w}
+	tex, err := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 4, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	draw := func() drawState {
+		t.Helper()
+		f.RenderQuad(0, 0, 4, 4, tex, ColorWhite)
+		w.Flush()
+		return gl.lastDraw
+	}
+	normal := draw()
+	if normal.stencilTest || normal.colorMask != [4]bool{true, true, true, true} || !normal.depthMask {
+		t.Fatalf("unmasked draw with %+v", normal)
+	}
+
+	f.BeginMask()
+	if got := draw(); !got.stencilTest || got.colorMask != [4]bool{} || got.depthMask {
+		t.Errorf("mask shape drawn with %+v, want stencil only", got)
+	}
+	if gl.stencilOp[2] != glpkg.Replace || gl.uniform("u_alphaCutoff") != maskAlphaCutoff {
+		t.Errorf("mask shape doesn't write the stencil: op %v, cutoff %v", gl.stencilOp, gl.uniform("u_alphaCutoff"))
+	}
+
+	f.DrawMasked()
+	if got := draw(); !got.stencilTest || got.colorMask != normal.colorMask || !got.depthMask {
+		t.Errorf("masked content drawn with %+v", got)
+	}
+	if gl.stencilFunc != [3]uint32{glpkg.Equal, 1, 0xFF} || gl.stencilMask != 0 {
+		t.Errorf("masked content stencil func %v, write mask %#x", gl.stencilFunc, gl.stencilMask)
+	}
+	if gl.uniform("u_alphaCutoff") != 0 {
+		t.Error("alpha cutoff left on while drawing masked content")
+	}
+
+	f.EndMask()
+	if got := draw(); got != normal {
+		t.Errorf("after EndMask drawn with %+v, want %+v", got, normal)
+	}
+	if gl.stencilMask != 0xFF || gl.uniform("u_alphaCutoff") != 0 {
+		t.Errorf("after EndMask stencil write mask %#x, cutoff %v", gl.stencilMask, gl.uniform("u_alphaCutoff"))
+	}
+}
+
+func TestMaskLeftOpenIsClosedNextFrame(t *testing.T) {
+	w, gl := newTestWindow(t, 64, 64)
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	glFrame{w: w}.BeginMask()
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if gl.enabled[glpkg.StencilTest] || gl.colorMask != [4]bool{true, true, true, true} || !gl.depthMask {
+		t.Errorf("mask state leaked into the next frame: stencil %v, color %v, depth %v",
+			gl.enabled[glpkg.StencilTest], gl.colorMask, gl.depthMask)
+	}
+}
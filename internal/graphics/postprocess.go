@@ -0,0 +1,272 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// glShader is a program built from the standard vertex shader and a user
+// fragment shader, with its own VAO since attribute locations are assigned
+// per program.
+type glShader struct {
+	w        *glWindow
+	program  uint32
+	vao      uint32
+	proj     int32
+	tex      int32
+	res      int32
+	time     int32
+	uniforms map[string]float32
+}
+
+// NewShader implements Window.
+func (w *glWindow) NewShader(fragmentSource string) (Shader, error) {
+	w.checkGoroutine("NewShader")
+	program, err := createShaderProgram(w.gl, vertexShaderSource, fragmentSource)
+	if err != nil {
+		return nil, err
+	}
+	s := &glShader{
+		w:        w,
+		program:  program,
+		proj:     w.gl.GetUniformLocation(program, "u_proj"),
+		tex:      w.gl.GetUniformLocation(program, "u_texture"),
+		res:      w.gl.GetUniformLocation(program, "u_resolution"),
+		time:     w.gl.GetUniformLocation(program, "u_time"),
+		uniforms: make(map[string]float32),
+	}
+
+	w.gl.GenVertexArrays(1, &s.vao)
+	w.gl.BindVertexArray(s.vao)
+	w.gl.BindBuffer(glpkg.ArrayBuffer, w.vbo)
+	for _, attr := range []struct {
+		name   string
+		size   int32
+		offset int
+	}{
+		{"a_position", 3, 0},
+		{"a_texCoord", 2, 12},
+		{"a_color", 4, 20},
+	} {
+		// Shaders that don't use an attribute may have it optimized away.
+		if loc := w.gl.GetAttribLocation(program, attr.name); loc >= 0 {
+			w.gl.VertexAttribPointer(uint32(loc), attr.size, glpkg.Float, false, vertexSize, uintptr(attr.offset))
+			w.gl.EnableVertexAttribArray(uint32(loc))
+		}
+	}
+	w.stateDirty = true
+	return s, nil
+}
+
+func (s *glShader) SetFloat(name string, v float32) {
+	s.uniforms[name] = v
+}
+
+// postTarget is the offscreen framebuffer a frame is drawn into while a
+// post-process shader or a render scale other than 1 is set.
+type postTarget struct {
+	fbo     uint32
+	color   uint32
+	depth   uint32 // packed depth and stencil, for depth testing and masks
+	width   int
+	height  int
+	started time.Time
+	fresh   bool // storage allocated but not cleared yet
+}
+
+// SetPostProcess implements Window. The offscreen target is kept when the
+// shader is removed so toggling an effect doesn't reallocate it.
+func (w *glWindow) SetPostProcess(shader Shader) {
+	s, _ := shader.(*glShader)
+	w.post = s
+}
+
+// bindPostTarget makes the offscreen framebuffer, resized to bw x bh, the
+// draw target for the frame.
+func (w *glWindow) bindPostTarget(bw, bh int) error {
+	gl := w.gl
+	t := w.postTarget
+	if t == nil {
+		t = &postTarget{started: w.clock.Now()}
+		gl.GenFramebuffers(1, &t.fbo)
+		gl.GenTextures(1, &t.color)
+		gl.GenRenderbuffers(1, &t.depth)
+		w.postTarget = t
+	}
+
+	gl.BindFramebuffer(glpkg.Framebuffer, t.fbo)
+	if t.width == bw && t.height == bh {
+		return nil
+	}
+	t.width, t.height = bw, bh
+	t.fresh = true
+
+	gl.BindTexture(glpkg.Texture2D, t.color)
+	w.boundTexture = t.color
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMinFilter, glpkg.Linear)
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureMagFilter, glpkg.Linear)
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureWrapS, glpkg.ClampToEdge)
+	gl.TexParameteri(glpkg.Texture2D, glpkg.TextureWrapT, glpkg.ClampToEdge)
+	gl.TexImage2D(glpkg.Texture2D, 0, glpkg.RGBA, int32(bw), int32(bh), 0, glpkg.RGBA, glpkg.UnsignedByte, nil)
+	gl.FramebufferTexture2D(glpkg.Framebuffer, glpkg.ColorAttachment0, glpkg.Texture2D, t.color, 0)
+
+	gl.BindRenderbuffer(glpkg.Renderbuffer, t.depth)
+	gl.RenderbufferStorage(glpkg.Renderbuffer, glpkg.Depth24Stencil8, int32(bw), int32(bh))
+	gl.FramebufferRenderbuffer(glpkg.Framebuffer, glpkg.DepthStencilAttachment, glpkg.Renderbuffer, t.depth)
+	gl.BindRenderbuffer(glpkg.Renderbuffer, 0)
+
+	if status := gl.CheckFramebufferStatus(glpkg.Framebuffer); status != glpkg.FramebufferComplete {
+		gl.BindFramebuffer(glpkg.Framebuffer, 0)
+		return fmt.Errorf("post-process framebuffer incomplete: status %#x", status)
+	}
+	return nil
+}
+
+// drawPostProcess composites the offscreen frame onto the window through
+// the post-process shader.
+func (w *glWindow) drawPostProcess() {
+	gl := w.gl
+	s, t := w.framePost, w.postTarget
+	gl.BindFramebuffer(glpkg.Framebuffer, 0)
+	w.target = nil
+	bw, bh := w.platform.BackingSize()
+	gl.Viewport(0, 0, int32(bw), int32(bh))
+	gl.Disable(glpkg.Blend)
+	gl.Disable(glpkg.DepthTest)
+	gl.Disable(glpkg.StencilTest)
+	// A mask left open by the step would otherwise hide the whole frame.
+	gl.ColorMask(true, true, true, true)
+	gl.DepthMask(true)
+
+	gl.UseProgram(s.program)
+	gl.BindVertexArray(s.vao)
+	gl.BindBuffer(glpkg.ArrayBuffer, w.vbo)
+	gl.ActiveTexture(glpkg.Texture0)
+	gl.BindTexture(glpkg.Texture2D, t.color)
+
+	// The quad spans 0..1 with texture coordinates matching, so the frame
+	// is sampled the right way up.
+	proj := orthoMatrix(0, 1, 0, 1, -1, 1)
+	gl.UniformMatrix4fv(s.proj, 1, false, &proj[0])
+	gl.Uniform1i(s.tex, 0)
+	gl.Uniform2f(s.res, float32(t.width), float32(t.height))
+	gl.Uniform1f(s.time, float32(w.clock.Now().Sub(t.started).Seconds()))
+	for name, v := range s.uniforms {
+		gl.Uniform1f(gl.GetUniformLocation(s.program, name), v)
+	}
+
+	vertices := [6 * vertexFloats]float32{
+		0, 0, 0, 0, 0, 1, 1, 1, 1,
+		1, 0, 0, 1, 0, 1, 1, 1, 1,
+		1, 1, 0, 1, 1, 1, 1, 1, 1,
+		0, 0, 0, 0, 0, 1, 1, 1, 1,
+		1, 1, 0, 1, 1, 1, 1, 1, 1,
+		0, 1, 0, 0, 1, 1, 1, 1, 1,
+	}
+	offset := w.stream.write(vertices[:])
+	gl.DrawArrays(glpkg.Triangles, int32(offset/vertexSize), 6)
+
+	gl.Enable(glpkg.Blend)
+	if w.depthTest {
+		gl.Enable(glpkg.DepthTest)
+	}
+	w.stateDirty = true
+}
+
+// resolveShaderSource copies the offscreen target to the window unchanged
+// when it is only there for SetRenderScale. Linear filtering averages 2x2
+// texels per pixel at a scale of 2.
+const resolveShaderSource = `#version 130
+in vec2 v_texCoord;
+in vec4 v_color;
+
+out vec4 fragColor;
+
+uniform sampler2D u_texture;
+
+void main() {
+	fragColor = texture(u_texture, v_texCoord) * v_color;
+}`
+
+// SetRenderScale implements Window.
+func (w *glWindow) SetRenderScale(factor float32) {
+	if factor <= 0 {
+		factor = 1
+	}
+	w.renderScale = factor
+}
+
+// ensureResolve compiles the resolve shader on first use.
+func (w *glWindow) ensureResolve() error {
+	if w.resolve != nil {
+		return nil
+	}
+	s, err := w.NewShader(resolveShaderSource)
+	if err != nil {
+		return err
+	}
+	w.resolve = s.(*glShader)
+	return nil
+}
+
+// scaledSize returns the offscreen target size for a bw x bh window at the
+// render scale, at least 1x1 and within the GL texture size limit.
+func (w *glWindow) scaledSize(bw, bh int) (int, int) {
+	if w.renderScale == 1 {
+		return bw, bh
+	}
+	scale := func(n int) int {
+		n = max(int(float32(n)*w.renderScale+0.5), 1)
+		if w.maxTextureSize > 0 {
+			n = min(n, w.maxTextureSize)
+		}
+		return n
+	}
+	return scale(bw), scale(bh)
+}
+
+// targetSize returns the size in pixels of the framebuffer the frame is
+// being drawn into: the offscreen target while there is one, otherwise the
+// window.
+func (w *glWindow) targetSize() (int, int) {
+	if t := w.target; t != nil {
+		return t.width, t.height
+	}
+	return w.platform.BackingSize()
+}
+
+// glRect converts r, in the window's backing pixels from the top left, to
+// the pixels of the framebuffer being drawn into, from GL's bottom-left
+// origin, as taken by Viewport, Scissor and ReadPixels.
+func (w *glWindow) glRect(r image.Rectangle) (x, y, width, height int32) {
+	bw, bh := w.platform.BackingSize()
+	tw, th := w.targetSize()
+	if bw != tw || bh != th {
+		sx := float64(tw) / float64(max(bw, 1))
+		sy := float64(th) / float64(max(bh, 1))
+		r = image.Rect(
+			int(math.Floor(float64(r.Min.X)*sx)),
+			int(math.Floor(float64(r.Min.Y)*sy)),
+			int(math.Ceil(float64(r.Max.X)*sx)),
+			int(math.Ceil(float64(r.Max.Y)*sy)),
+		)
+	}
+	return int32(r.Min.X), int32(th - r.Max.Y), int32(r.Dx()), int32(r.Dy())
+}
+
+// deletePostTarget frees the offscreen framebuffer and its attachments.
+func (w *glWindow) deletePostTarget() {
+	if t := w.postTarget; t != nil {
+		w.gl.DeleteFramebuffers(1, &t.fbo)
+		w.gl.DeleteTextures(1, &t.color)
+		w.gl.DeleteRenderbuffers(1, &t.depth)
+		if w.boundTexture == t.color {
+			w.boundTexture = 0
+		}
+		w.postTarget = nil
+	}
+}
@@ -0,0 +1,111 @@
+package graphics
+
+import (
+	"testing"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// newPostWindow returns a test window with a post-process shader set and a
+// frame prepared, so it is drawing into the offscreen target.
+func newPostWindow(t *testing.T) (*glWindow, *fakeGL) {
+	t.Helper()
+	w, gl := newTestWindow(t, 64, 48)
+	shader, err := w.NewShader(resolveShaderSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetPostProcess(shader)
+	if err := w.prepareFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if w.postTarget == nil || gl.framebuffer != w.postTarget.fbo {
+		t.Fatal("frame not drawn into the offscreen target")
+	}
+	return w, gl
+}
+
+func TestDrawPostProcessState(t *testing.T) {
+	tests := []struct {
+		name string
+		step func(f glFrame)
+	}{
+		{"plain", func(f glFrame) {}},
+		{"mask left open", func(f glFrame) { f.BeginMask() }},
+		{"drawing masked", func(f glFrame) { f.BeginMask(); f.DrawMasked() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, gl := newPostWindow(t)
+			tt.step(glFrame{w: w})
+			w.flush()
+			w.drawPostProcess()
+
+			want := drawState{
+				texture:   w.postTarget.color,
+				colorMask: [4]bool{true, true, true, true},
+				depthMask: true,
+			}
+			if gl.lastDraw != want {
+				t.Errorf("composited with %+v, want %+v", gl.lastDraw, want)
+			}
+			if !gl.enabled[glpkg.Blend] {
+				t.Error("blending left disabled after compositing")
+			}
+		})
+	}
+}
+
+func TestDeletePostTarget(t *testing.T) {
+	w, gl := newPostWindow(t)
+	color := w.postTarget.color
+	w.deletePostTarget()
+	if !gl.deleted[color] {
+		t.Error("color texture of the offscreen target not deleted")
+	}
+	if w.boundTexture == color {
+		t.Error("deleted texture still cached as bound")
+	}
+}
+
+func TestPostTargetClear(t *testing.T) {
+	tests := []struct {
+		name  string
+		clear bool
+		// Whether each of four frames clears the color buffer: the first,
+		// an unchanged one, one after a resize, and another unchanged one.
+		want [4]bool
+	}{
+		{"clearing", true, [4]bool{true, true, true, true}},
+		{"not clearing", false, [4]bool{true, false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, gl := newTestWindow(t, 64, 48)
+			w.SetClear(tt.clear)
+			w.SetClearColor(ColorRed)
+			shader, err := w.NewShader(resolveShaderSource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.SetPostProcess(shader)
+
+			for i, want := range tt.want {
+				if i == 2 {
+					w.platform.(*fakePlatform).width = 80
+				}
+				gl.clears = nil
+				if err := w.prepareFrame(); err != nil {
+					t.Fatal(err)
+				}
+				cleared := len(gl.clears) > 0 && gl.clears[0]&glpkg.ColorBufferBit != 0
+				if cleared != want {
+					t.Errorf("frame %d: color cleared = %v, want %v", i, cleared, want)
+				}
+				if cleared && gl.clearColor != [4]float32{1, 0, 0, 1} {
+					t.Errorf("frame %d: cleared to %v, want red", i, gl.clearColor)
+				}
+			}
+		})
+	}
+}
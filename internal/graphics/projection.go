@@ -0,0 +1,80 @@
+package graphics
+
+// identityMatrix leaves coordinates unchanged.
+var identityMatrix = [16]float32{
+	1, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 1,
+}
+
+// Ortho returns an orthographic projection, column-major as SetProjection
+// takes it, mapping left..right and top..bottom to the edges of the view.
+// The default projection is Ortho(0, width, 0, height) of ViewSize.
+func Ortho(left, right, top, bottom float32) [16]float32 {
+	return orthoMatrix(left, right, bottom, top, -1, 1)
+}
+
+// PanZoom returns a view matrix for Frame.SetView that scales coordinates
+// by zoom about the origin and then moves them by (x, y).
+func PanZoom(x, y, zoom float32) [16]float32 {
+	return [16]float32{
+		zoom, 0, 0, 0,
+		0, zoom, 0, 0,
+		0, 0, 1, 0,
+		x, y, 0, 1,
+	}
+}
+
+// mulMatrix returns a*b for column-major matrices, which applies b first.
+func mulMatrix(a, b [16]float32) [16]float32 {
+	var m [16]float32
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+			m[col*4+row] = sum
+		}
+	}
+	return m
+}
+
+// SetProjection implements Window.
+func (w *glWindow) SetProjection(matrix [16]float32) {
+	w.projection = matrix
+	w.customProjection = true
+}
+
+// ResetProjection implements Window.
+func (w *glWindow) ResetProjection() {
+	w.customProjection = false
+}
+
+// SetView implements Frame.
+func (f glFrame) SetView(matrix [16]float32) {
+	f.w.flush()
+	f.w.view = matrix
+	if f.w.stateDirty {
+		f.w.bindState()
+	}
+	f.w.uploadProjection()
+}
+
+// uploadProjection sets u_proj to the projection times the frame's view.
+// The standard program must be bound.
+func (w *glWindow) uploadProjection() {
+	proj := w.projection
+	if n := len(w.targets); n > 0 {
+		// Upside down, so the top row drawn is the texture's first and the
+		// target samples the right way up like any other texture.
+		t := w.targets[n-1].tex
+		proj = Ortho(0, float32(t.w), float32(t.h), 0)
+	} else if !w.customProjection {
+		width, height := w.ViewSize()
+		proj = Ortho(0, width, 0, height)
+	}
+	m := mulMatrix(proj, w.view)
+	w.gl.UniformMatrix4fv(w.projUniform, 1, false, &m[0])
+}
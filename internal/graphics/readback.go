@@ -0,0 +1,101 @@
+package graphics
+
+import (
+	"errors"
+	"image"
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// readbackLatency is how many frames an asynchronous readback is given
+// before Ready reports it can be resolved without stalling.
+const readbackLatency = 2
+
+var errReadbackMap = errors.New("failed to map pixel pack buffer")
+
+// pboReadback is a Future backed by a pixel pack buffer. ReadPixels into a
+// bound PixelPackBuffer returns immediately; the copy happens on the GPU and
+// is only waited for when the buffer is mapped.
+type pboReadback struct {
+	w      *glWindow
+	pbo    uint32
+	width  int
+	height int
+	frame  uint64
+
+	// Size of the window when read; the image is scaled to it if the
+	// frame was being drawn at a different render scale.
+	outWidth  int
+	outHeight int
+
+	done bool
+	img  image.Image
+	err  error
+}
+
+// ScreenshotAsync implements Frame.
+func (f glFrame) ScreenshotAsync() Future {
+	w := f.w
+	bw, bh := w.targetSize()
+	size := bw * bh * 4
+	w.flush()
+
+	var pbo uint32
+	if n := len(w.freePBOs); n > 0 {
+		pbo = w.freePBOs[n-1]
+		w.freePBOs = w.freePBOs[:n-1]
+	} else {
+		w.gl.GenBuffers(1, &pbo)
+	}
+
+	w.gl.BindBuffer(glpkg.PixelPackBuffer, pbo)
+	w.gl.BufferData(glpkg.PixelPackBuffer, size, nil, glpkg.StreamRead)
+	// With a pack buffer bound the pointer argument is an offset into it.
+	w.gl.ReadPixels(0, 0, int32(bw), int32(bh), glpkg.RGBA, glpkg.UnsignedByte, nil)
+	w.gl.BindBuffer(glpkg.PixelPackBuffer, 0)
+
+	ow, oh := w.platform.BackingSize()
+	return &pboReadback{w: w, pbo: pbo, width: bw, height: bh, frame: w.frameCount, outWidth: ow, outHeight: oh}
+}
+
+func (r *pboReadback) Ready() bool {
+	return r.done || r.w.frameCount >= r.frame+readbackLatency
+}
+
+func (r *pboReadback) Wait() (image.Image, error) {
+	if r.done {
+		return r.img, r.err
+	}
+	r.done = true
+
+	w := r.w
+	size := r.width * r.height * 4
+	w.gl.BindBuffer(glpkg.PixelPackBuffer, r.pbo)
+	ptr := w.gl.MapBufferRange(glpkg.PixelPackBuffer, 0, size, glpkg.MapReadBit)
+	if ptr == nil {
+		r.err = errReadbackMap
+	} else {
+		img := image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+		flipRows(img.Pix, unsafe.Slice((*byte)(ptr), size), img.Stride)
+		w.gl.UnmapBuffer(glpkg.PixelPackBuffer)
+		r.img = img
+		if r.outWidth != r.width || r.outHeight != r.height {
+			r.img = resizeBox(img, r.outWidth, r.outHeight)
+		}
+	}
+	w.gl.BindBuffer(glpkg.PixelPackBuffer, 0)
+
+	w.freePBOs = append(w.freePBOs, r.pbo)
+	r.pbo = 0
+	return r.img, r.err
+}
+
+// flipRows copies src to dst reversing the order of its rows, converting
+// between GL's bottom-up and image's top-down layouts.
+func flipRows(dst, src []byte, stride int) {
+	rows := len(src) / stride
+	for y := 0; y < rows; y++ {
+		copy(dst[(rows-1-y)*stride:(rows-y)*stride], src[y*stride:(y+1)*stride])
+	}
+}
@@ -0,0 +1,163 @@
+package graphics
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// pushedTarget is an entry of the PushTarget stack: the target drawn into
+// and the view in effect where it was pushed.
+type pushedTarget struct {
+	tex  *glTexture
+	view [16]float32
+}
+
+// NewRenderTarget implements Window.
+func (w *glWindow) NewRenderTarget(width, height int, opts TextureOptions) (RenderTarget, error) {
+	w.checkGoroutine("NewRenderTarget")
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid render target size %dx%d", width, height)
+	}
+	if w.maxTextureSize > 0 && (width > w.maxTextureSize || height > w.maxTextureSize) {
+		return nil, fmt.Errorf("render target size %dx%d exceeds the GL maximum of %dx%d",
+			width, height, w.maxTextureSize, w.maxTextureSize)
+	}
+
+	// Mipmaps would have to be regenerated after every PopTarget.
+	opts.Mipmap = false
+	gl := w.gl
+	t := &glTexture{
+		id:          w.genTexture(opts),
+		w:           width,
+		h:           height,
+		win:         w,
+		premultiply: true,
+		clearColor:  ColorTransparent,
+		fresh:       true,
+	}
+	w.boundTexture = t.id
+	gl.TexImage2D(glpkg.Texture2D, 0, glpkg.RGBA, int32(width), int32(height), 0, glpkg.RGBA, glpkg.UnsignedByte, nil)
+
+	gl.GenFramebuffers(1, &t.fbo)
+	gl.GenRenderbuffers(1, &t.depth)
+	gl.BindFramebuffer(glpkg.Framebuffer, t.fbo)
+	gl.FramebufferTexture2D(glpkg.Framebuffer, glpkg.ColorAttachment0, glpkg.Texture2D, t.id, 0)
+	gl.BindRenderbuffer(glpkg.Renderbuffer, t.depth)
+	gl.RenderbufferStorage(glpkg.Renderbuffer, glpkg.Depth24Stencil8, int32(width), int32(height))
+	gl.FramebufferRenderbuffer(glpkg.Framebuffer, glpkg.DepthStencilAttachment, glpkg.Renderbuffer, t.depth)
+	gl.BindRenderbuffer(glpkg.Renderbuffer, 0)
+	status := gl.CheckFramebufferStatus(glpkg.Framebuffer)
+	gl.BindFramebuffer(glpkg.Framebuffer, w.drawFramebuffer())
+
+	if status != glpkg.FramebufferComplete {
+		gl.DeleteFramebuffers(1, &t.fbo)
+		gl.DeleteRenderbuffers(1, &t.depth)
+		gl.DeleteTextures(1, &t.id)
+		w.boundTexture = 0
+		return nil, fmt.Errorf("render target framebuffer incomplete: status %#x", status)
+	}
+	return t, nil
+}
+
+// SetClear implements RenderTarget.
+func (t *glTexture) SetClear(enabled bool, c color.Color) {
+	if c == nil {
+		c = ColorTransparent
+	}
+	t.noClear = !enabled
+	t.clearColor = c
+}
+
+// Image implements RenderTarget.
+func (t *glTexture) Image() (*image.RGBA, error) {
+	if t.fbo == 0 {
+		return nil, errors.New("texture was not created by NewRenderTarget")
+	}
+	w := t.win
+	w.checkGoroutine("RenderTarget.Image")
+	// What is queued may be drawing into the target.
+	w.flush()
+	img := image.NewRGBA(image.Rect(0, 0, t.w, t.h))
+	w.gl.BindTexture(glpkg.Texture2D, t.id)
+	w.boundTexture = t.id
+	w.gl.GetTexImage(glpkg.Texture2D, 0, glpkg.RGBA, glpkg.UnsignedByte, unsafe.Pointer(&img.Pix[0]))
+	return img, nil
+}
+
+// PushTarget implements Frame.
+func (f glFrame) PushTarget(target RenderTarget) {
+	w := f.w
+	t, ok := target.(*glTexture)
+	if !ok || t.fbo == 0 {
+		return
+	}
+	w.flush()
+	w.targets = append(w.targets, pushedTarget{tex: t, view: w.view})
+	w.view = identityMatrix
+	w.bindDrawTarget()
+
+	if !t.noClear || t.fresh {
+		rgba := ColorToFloat32(t.clearColor)
+		w.gl.ClearColor(rgba[0], rgba[1], rgba[2], rgba[3])
+		w.gl.Clear(glpkg.ColorBufferBit | glpkg.DepthBufferBit | glpkg.StencilBufferBit)
+	}
+	t.fresh = false
+}
+
+// PopTarget implements Frame.
+func (f glFrame) PopTarget() {
+	w := f.w
+	n := len(w.targets)
+	if n == 0 {
+		return
+	}
+	w.flush()
+	w.view = w.targets[n-1].view
+	w.targets = w.targets[:n-1]
+	w.bindDrawTarget()
+}
+
+// popTargets pops any targets the frame left pushed.
+func (w *glWindow) popTargets() {
+	if len(w.targets) == 0 {
+		return
+	}
+	w.view = w.targets[0].view
+	w.targets = w.targets[:0]
+	w.bindDrawTarget()
+}
+
+// drawFramebuffer returns the framebuffer drawing goes to: the innermost
+// pushed target, else the frame's offscreen target, else the window.
+func (w *glWindow) drawFramebuffer() uint32 {
+	if n := len(w.targets); n > 0 {
+		return w.targets[n-1].tex.fbo
+	}
+	if w.target != nil {
+		return w.target.fbo
+	}
+	return 0
+}
+
+// bindDrawTarget binds the framebuffer, viewport and projection for
+// drawing into drawFramebuffer. The stencil state of a mask belongs to the
+// previous framebuffer, so any mask is ended.
+func (w *glWindow) bindDrawTarget() {
+	if w.stateDirty {
+		w.bindState()
+	}
+	w.gl.BindFramebuffer(glpkg.Framebuffer, w.drawFramebuffer())
+	if n := len(w.targets); n > 0 {
+		t := w.targets[n-1].tex
+		w.gl.Viewport(0, 0, int32(t.w), int32(t.h))
+	} else {
+		w.gl.Viewport(w.glRect(w.viewRect))
+	}
+	w.endMask()
+	w.uploadProjection()
+}
@@ -0,0 +1,142 @@
+package graphics
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestRenderTargetClear(t *testing.T) {
+	blue := color.RGBA{B: 255, A: 255}
+	tests := []struct {
+		name string
+		// set configures the target before each of two pushes.
+		set  [2]func(RenderTarget)
+		want [2]color.RGBA
+	}{
+		{
+			name: "default",
+			set:  [2]func(RenderTarget){func(RenderTarget) {}, func(RenderTarget) {}},
+			want: [2]color.RGBA{ColorTransparent, ColorTransparent},
+		},
+		{
+			name: "clearing",
+			set: [2]func(RenderTarget){
+				func(rt RenderTarget) { rt.SetClear(true, ColorRed) },
+				func(rt RenderTarget) { rt.SetClear(true, blue) },
+			},
+			want: [2]color.RGBA{ColorRed, blue},
+		},
+		{
+			// The first push clears anyway, since the storage is new; the
+			// second keeps what the target held.
+			name: "not clearing",
+			set: [2]func(RenderTarget){
+				func(rt RenderTarget) { rt.SetClear(false, ColorRed) },
+				func(rt RenderTarget) { rt.SetClear(false, blue) },
+			},
+			want: [2]color.RGBA{ColorRed, ColorRed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := newTestWindow(t, 64, 48)
+			if err := w.prepareFrame(); err != nil {
+				t.Fatal(err)
+			}
+			f := glFrame{w: w}
+			rt, err := w.NewRenderTarget(4, 2, TextureOptions{})
+			if err != nil {
+				t.Fatalf("NewRenderTarget: %v", err)
+			}
+
+			for i, want := range tt.want {
+				tt.set[i](rt)
+				f.PushTarget(rt)
+				f.PopTarget()
+				img, err := rt.Image()
+				if err != nil {
+					t.Fatalf("Image: %v", err)
+				}
+				if got := img.Bounds().Size(); got.X != 4 || got.Y != 2 {
+					t.Fatalf("image is %v, want 4x2", got)
+				}
+				for y := range 2 {
+					for x := range 4 {
+						if got := img.RGBAAt(x, y); got != want {
+							t.Fatalf("push %d: pixel (%d, %d) = %v, want %v", i, x, y, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPushTargetBinding(t *testing.T) {
+	for _, post := range []bool{false, true} {
+		name := "window"
+		if post {
+			name = "post-processed"
+		}
+		t.Run(name, func(t *testing.T) {
+			var (
+				w  *glWindow
+				gl *fakeGL
+			)
+			if post {
+				w, gl = newPostWindow(t)
+			} else {
+				w, gl = newTestWindow(t, 64, 48)
+				if err := w.prepareFrame(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			frameFBO := gl.framebuffer
+			f := glFrame{w: w}
+			outer, err := w.NewRenderTarget(8, 8, TextureOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			inner, err := w.NewRenderTarget(4, 4, TextureOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gl.framebuffer != frameFBO {
+				t.Fatal("NewRenderTarget left its framebuffer bound")
+			}
+			view := PanZoom(10, 20, 2)
+			f.SetView(view)
+
+			// draw renders a quad and reports which framebuffer it went to.
+			draw := func() uint32 {
+				f.RenderQuad(0, 0, 1, 1, w.whiteTexture(), ColorWhite)
+				w.flush()
+				return gl.lastDraw.framebuffer
+			}
+			f.PushTarget(outer)
+			if got := draw(); got != outer.(*glTexture).fbo {
+				t.Errorf("drew into framebuffer %d with the outer target pushed, want %d", got, outer.(*glTexture).fbo)
+			}
+			if w.view != identityMatrix {
+				t.Error("view carried into the target")
+			}
+			f.PushTarget(inner)
+			if got := draw(); got != inner.(*glTexture).fbo {
+				t.Errorf("drew into framebuffer %d with the inner target pushed, want %d", got, inner.(*glTexture).fbo)
+			}
+			f.PopTarget()
+			if got := draw(); got != outer.(*glTexture).fbo {
+				t.Errorf("drew into framebuffer %d after popping the inner target, want %d", got, outer.(*glTexture).fbo)
+			}
+
+			// The frame ends with the outer target still pushed.
+			w.popTargets()
+			if got := draw(); got != frameFBO {
+				t.Errorf("drew into framebuffer %d after the frame's targets were popped, want %d", got, frameFBO)
+			}
+			if w.view != view {
+				t.Error("view not restored after popping")
+			}
+		})
+	}
+}
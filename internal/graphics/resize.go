@@ -0,0 +1,41 @@
+package graphics
+
+import "image"
+
+// resizeBox resamples src to w x h by averaging the source pixels covered by
+// each destination pixel. It is intended for downscaling; upscaling falls
+// back to nearest-neighbor.
+func resizeBox(src *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sb := src.Bounds()
+	if w <= 0 || h <= 0 || sb.Empty() {
+		return dst
+	}
+
+	for dy := 0; dy < h; dy++ {
+		y0 := sb.Min.Y + dy*sb.Dy()/h
+		y1 := max(sb.Min.Y+(dy+1)*sb.Dy()/h, y0+1)
+		for dx := 0; dx < w; dx++ {
+			x0 := sb.Min.X + dx*sb.Dx()/w
+			x1 := max(sb.Min.X+(dx+1)*sb.Dx()/w, x0+1)
+
+			var sum [4]uint32
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(x0, sy):src.PixOffset(x1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += uint32(row[i+0])
+					sum[1] += uint32(row[i+1])
+					sum[2] += uint32(row[i+2])
+					sum[3] += uint32(row[i+3])
+				}
+			}
+			n := uint32((x1 - x0) * (y1 - y0))
+			o := dst.PixOffset(dx, dy)
+			dst.Pix[o+0] = uint8(sum[0] / n)
+			dst.Pix[o+1] = uint8(sum[1] / n)
+			dst.Pix[o+2] = uint8(sum[2] / n)
+			dst.Pix[o+3] = uint8(sum[3] / n)
+		}
+	}
+	return dst
+}
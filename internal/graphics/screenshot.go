@@ -0,0 +1,140 @@
+package graphics
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScreenshotOptions configures SaveScreenshotWithOptions.
+type ScreenshotOptions struct {
+	// JPEGQuality is the quality from 1 to 100 used for .jpg/.jpeg files.
+	// Zero selects jpeg.DefaultQuality.
+	JPEGQuality int
+
+	// Logical saves the ScreenshotLogical image instead of the full
+	// physical-pixel one.
+	Logical bool
+}
+
+// SaveScreenshot captures the frame and writes it to path, choosing the
+// format from the extension: .png, .jpg/.jpeg or .bmp.
+func SaveScreenshot(f Frame, path string) error {
+	return SaveScreenshotWithOptions(f, path, ScreenshotOptions{})
+}
+
+// SaveScreenshotWithOptions is SaveScreenshot with encoder options.
+func SaveScreenshotWithOptions(f Frame, path string, opts ScreenshotOptions) (err error) {
+	var encode func(io.Writer, image.Image) error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".png":
+		encode = png.Encode
+	case ".jpg", ".jpeg":
+		quality := opts.JPEGQuality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		encode = func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		}
+	case ".bmp":
+		encode = encodeBMP
+	default:
+		return fmt.Errorf("unsupported screenshot format %q", ext)
+	}
+
+	var img image.Image
+	if opts.Logical {
+		img, err = f.ScreenshotLogical()
+	} else {
+		img, err = f.Screenshot()
+	}
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	w := bufio.NewWriter(file)
+	if err := encode(w, img); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// encodeBMP writes img as an uncompressed 32-bit BMP.
+func encodeBMP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(b)
+		draw.Draw(rgba, b, img, b.Min, draw.Src)
+	}
+
+	const headerSize = 14 + 40
+	imageSize := b.Dx() * b.Dy() * 4
+
+	header := struct {
+		// BITMAPFILEHEADER
+		Magic      [2]byte
+		FileSize   uint32
+		Reserved   uint32
+		DataOffset uint32
+		// BITMAPINFOHEADER
+		InfoSize      uint32
+		Width         int32
+		Height        int32
+		Planes        uint16
+		BitCount      uint16
+		Compression   uint32
+		ImageSize     uint32
+		XPelsPerMeter int32
+		YPelsPerMeter int32
+		ColorsUsed    uint32
+		ColorsImp     uint32
+	}{
+		Magic:      [2]byte{'B', 'M'},
+		FileSize:   uint32(headerSize + imageSize),
+		DataOffset: headerSize,
+		InfoSize:   40,
+		Width:      int32(b.Dx()),
+		Height:     int32(b.Dy()), // positive: rows are stored bottom-up
+		Planes:     1,
+		BitCount:   32,
+		ImageSize:  uint32(imageSize),
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+
+	row := make([]byte, b.Dx()*4)
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		src := rgba.Pix[rgba.PixOffset(b.Min.X, y):]
+		for i := 0; i < len(row); i += 4 {
+			row[i+0] = src[i+2] // B
+			row[i+1] = src[i+1] // G
+			row[i+2] = src[i+0] // R
+			row[i+3] = src[i+3] // A
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
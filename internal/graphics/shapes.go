@@ -0,0 +1,213 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// maxCornerSegments bounds how many triangles approximate one rounded
+// corner, and maxArcSegments any other automatically divided arc.
+const (
+	maxCornerSegments = 32
+	maxArcSegments    = 256
+)
+
+// FillRoundedRect implements Frame.
+func (f glFrame) FillRoundedRect(x, y, width, height, radius float32, c color.Color) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	white := f.w.whiteTexture()
+	radius = min(radius, width/2, height/2)
+	if radius <= 0 {
+		f.RenderQuad(x, y, width, height, white, c)
+		return
+	}
+
+	segs := min(f.arcSegments(radius, math.Pi/2), maxCornerSegments)
+
+	corners := [4]struct{ cx, cy, start float32 }{
+		{x + radius, y + radius, math.Pi},                 // top-left
+		{x + width - radius, y + radius, 3 * math.Pi / 2}, // top-right
+		{x + width - radius, y + height - radius, 0},      // bottom-right
+		{x + radius, y + height - radius, math.Pi / 2},    // bottom-left
+	}
+	outline := make([][2]float32, 0, 4*(segs+1))
+	for _, k := range corners {
+		for i := 0; i <= segs; i++ {
+			a := float64(k.start) + float64(i)/float64(segs)*math.Pi/2
+			outline = append(outline, [2]float32{
+				k.cx + radius*float32(math.Cos(a)),
+				k.cy + radius*float32(math.Sin(a)),
+			})
+		}
+	}
+
+	// The shape is convex, so a fan from its center covers it.
+	rgba := ColorToFloat32(c)
+	cx, cy := x+width/2, y+height/2
+	vertices := make([]float32, 0, len(outline)*3*vertexFloats)
+	for i, p := range outline {
+		q := outline[(i+1)%len(outline)]
+		vertices = appendVertex(vertices, cx, cy, rgba)
+		vertices = appendVertex(vertices, p[0], p[1], rgba)
+		vertices = appendVertex(vertices, q[0], q[1], rgba)
+	}
+	f.drawTriangles(white, vertices)
+}
+
+// RenderQuadGradient implements Frame.
+func (f glFrame) RenderQuadGradient(x, y, width, height float32, topLeft, topRight, bottomLeft, bottomRight color.Color) {
+	tl := ColorToFloat32(topLeft)
+	tr := ColorToFloat32(topRight)
+	bl := ColorToFloat32(bottomLeft)
+	br := ColorToFloat32(bottomRight)
+
+	vertices := make([]float32, 0, 6*vertexFloats)
+	vertices = appendVertex(vertices, x, y, tl)
+	vertices = appendVertex(vertices, x+width, y, tr)
+	vertices = appendVertex(vertices, x, y+height, bl)
+	vertices = appendVertex(vertices, x+width, y, tr)
+	vertices = appendVertex(vertices, x+width, y+height, br)
+	vertices = appendVertex(vertices, x, y+height, bl)
+	f.drawTriangles(f.w.whiteTexture(), vertices)
+}
+
+// FillCircle implements Frame.
+func (f glFrame) FillCircle(cx, cy, radius float32, segments int, c color.Color) {
+	if radius <= 0 {
+		return
+	}
+	if segments <= 0 {
+		segments = f.arcSegments(radius, 2*math.Pi)
+	}
+	segments = max(segments, 3)
+
+	rgba := ColorToFloat32(c)
+	vertices := make([]float32, 0, segments*3*vertexFloats)
+	px, py := cx+radius, cy
+	for i := 1; i <= segments; i++ {
+		a := float64(i) / float64(segments) * 2 * math.Pi
+		qx := cx + radius*float32(math.Cos(a))
+		qy := cy + radius*float32(math.Sin(a))
+		vertices = appendVertex(vertices, cx, cy, rgba)
+		vertices = appendVertex(vertices, px, py, rgba)
+		vertices = appendVertex(vertices, qx, qy, rgba)
+		px, py = qx, qy
+	}
+	f.drawTriangles(f.w.whiteTexture(), vertices)
+}
+
+// DrawArc implements Frame.
+func (f glFrame) DrawArc(cx, cy, radius, thickness, startAngle, endAngle float32, segments int, c color.Color) {
+	sweep := float64(endAngle - startAngle)
+	if radius <= 0 || thickness <= 0 || sweep == 0 {
+		return
+	}
+	if segments <= 0 {
+		segments = f.arcSegments(radius, math.Abs(sweep))
+	}
+	segments = max(segments, 1)
+
+	inner := max(radius-thickness/2, 0)
+	outer := radius + thickness/2
+	rgba := ColorToFloat32(c)
+	vertices := make([]float32, 0, segments*6*vertexFloats)
+	var pin, pout [2]float32
+	for i := 0; i <= segments; i++ {
+		a := float64(startAngle) + float64(i)/float64(segments)*sweep
+		cos, sin := float32(math.Cos(a)), float32(math.Sin(a))
+		qin := [2]float32{cx + inner*cos, cy + inner*sin}
+		qout := [2]float32{cx + outer*cos, cy + outer*sin}
+		if i > 0 {
+			vertices = appendVertex(vertices, pin[0], pin[1], rgba)
+			vertices = appendVertex(vertices, pout[0], pout[1], rgba)
+			vertices = appendVertex(vertices, qout[0], qout[1], rgba)
+			vertices = appendVertex(vertices, pin[0], pin[1], rgba)
+			vertices = appendVertex(vertices, qout[0], qout[1], rgba)
+			vertices = appendVertex(vertices, qin[0], qin[1], rgba)
+		}
+		pin, pout = qin, qout
+	}
+	f.drawTriangles(f.w.whiteTexture(), vertices)
+}
+
+// arcSegments picks how many straight segments approximate an arc of
+// radius spanning sweep radians: about one per two physical pixels of arc,
+// which looks smooth at any scale without wasting vertices on small arcs.
+func (f glFrame) arcSegments(radius float32, sweep float64) int {
+	segs := 2
+	if vw, _ := f.w.ViewSize(); vw > 0 {
+		arc := float64(radius*float32(f.w.viewRect.Dx())/vw) * sweep
+		segs = max(int(math.Ceil(arc/2)), segs)
+	}
+	return min(segs, maxArcSegments)
+}
+
+// appendVertex appends an untextured vertex at (x, y) to vertices.
+func appendVertex(vertices []float32, x, y float32, rgba [4]float32) []float32 {
+	return append(vertices, x, y, 0, 0.5, 0.5, rgba[0], rgba[1], rgba[2], rgba[3])
+}
+
+// drawTriangles queues vertices, laid out as vertexFloats floats each, as a
+// triangle list sampling t. Consecutive calls with the same texture are
+// drawn together, in one draw call, when the batch is flushed.
+func (f glFrame) drawTriangles(t *glTexture, vertices []float32) {
+	w := f.w
+	if t != w.batchTex || len(w.batch)+len(vertices) > cap(w.batch) {
+		w.flush()
+		w.batchTex = t
+	}
+	if len(vertices) > cap(w.batch) {
+		w.draw(t, vertices)
+		return
+	}
+	w.batch = append(w.batch, vertices...)
+}
+
+// flush draws the queued triangles. Anything that changes GL state the
+// batch depends on, or reads back what was drawn, must flush first.
+func (w *glWindow) flush() {
+	if len(w.batch) == 0 {
+		return
+	}
+	w.draw(w.batchTex, w.batch)
+	w.batch = w.batch[:0]
+}
+
+// draw draws vertices as a triangle list sampling t right away. Lists too
+// long for the stream buffer are drawn in several calls.
+func (w *glWindow) draw(t *glTexture, vertices []float32) {
+	// Fast path: the program, VAO and VBO bound in prepareFrame are assumed
+	// to still be current, so only the texture needs rebinding.
+	if w.stateDirty {
+		w.bindState()
+	}
+	if w.boundTexture != t.id {
+		w.gl.BindTexture(glpkg.Texture2D, t.id)
+		w.boundTexture = t.id
+	}
+
+	chunk := w.stream.size / vertexSize / 3 * 3 * vertexFloats
+	for len(vertices) > 0 {
+		n := min(len(vertices), chunk)
+		offset := w.stream.write(vertices[:n])
+		w.gl.DrawArrays(glpkg.Triangles, int32(offset/vertexSize), int32(n/vertexFloats))
+		vertices = vertices[n:]
+	}
+}
+
+// whiteTexture returns a 1x1 opaque white texture for untextured shapes,
+// creating it on first use.
+func (w *glWindow) whiteTexture() *glTexture {
+	if w.white == nil {
+		img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		img.Pix[0], img.Pix[1], img.Pix[2], img.Pix[3] = 0xff, 0xff, 0xff, 0xff
+		tex, _ := w.NewTexture(img)
+		w.white = tex.(*glTexture)
+	}
+	return w.white
+}
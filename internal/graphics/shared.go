@@ -0,0 +1,50 @@
+package graphics
+
+import (
+	"image"
+
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+type glSharedContext struct {
+	w   *glWindow
+	ctx window.SharedContext
+}
+
+// NewSharedContext implements Window.
+func (w *glWindow) NewSharedContext() (SharedContext, error) {
+	w.checkGoroutine("NewSharedContext")
+	ctx, err := w.platform.NewSharedContext()
+	if err != nil {
+		return nil, err
+	}
+	return &glSharedContext{w: w, ctx: ctx}, nil
+}
+
+// MakeCurrent implements SharedContext.
+func (c *glSharedContext) MakeCurrent() error {
+	return c.ctx.MakeCurrent()
+}
+
+// ClearCurrent implements SharedContext.
+func (c *glSharedContext) ClearCurrent() {
+	c.ctx.ClearCurrent()
+}
+
+// NewTexture implements SharedContext. The window's GL function pointers
+// are valid here too, since the shared context was created with the same
+// pixel format and version.
+func (c *glSharedContext) NewTexture(img image.Image, opts TextureOptions) (Texture, error) {
+	t, err := c.w.createTexture(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Until it completes, the other context may sample a partial upload.
+	c.w.gl.Finish()
+	return t, nil
+}
+
+// Close implements SharedContext.
+func (c *glSharedContext) Close() {
+	c.ctx.Close()
+}
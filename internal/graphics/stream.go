@@ -0,0 +1,55 @@
+package graphics
+
+import (
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// streamBuffer streams vertex data into successive regions of a single VBO.
+//
+// Overwriting a region the GPU may still be reading from forces the driver to
+// stall until the previous draw completes. Instead each write goes to fresh
+// space in the buffer, mapped unsynchronized, and once the buffer is full it
+// is orphaned with BufferData(nil) so the driver can hand out new storage
+// while the old one drains. Callers batch their vertices so that a frame
+// maps the buffer once per draw call rather than once per quad.
+type streamBuffer struct {
+	gl     glpkg.OpenGL
+	size   int
+	offset int
+}
+
+// newStreamBuffer allocates size bytes of storage for the VBO bound to
+// ArrayBuffer.
+func newStreamBuffer(gl glpkg.OpenGL, size int) *streamBuffer {
+	gl.BufferData(glpkg.ArrayBuffer, size, nil, glpkg.StreamDraw)
+	return &streamBuffer{gl: gl, size: size}
+}
+
+// write copies data into the buffer, which must be bound to ArrayBuffer, and
+// returns the byte offset it was written at.
+func (s *streamBuffer) write(data []float32) int {
+	n := len(data) * 4
+	if s.offset+n > s.size {
+		// Orphan the current storage rather than waiting for the GPU.
+		s.gl.BufferData(glpkg.ArrayBuffer, s.size, nil, glpkg.StreamDraw)
+		s.offset = 0
+	}
+
+	offset := s.offset
+	s.offset += n
+
+	ptr := s.gl.MapBufferRange(glpkg.ArrayBuffer, offset, n,
+		glpkg.MapWriteBit|glpkg.MapInvalidateRangeBit|glpkg.MapUnsynchronizedBit)
+	if ptr != nil {
+		copy(unsafe.Slice((*float32)(ptr), len(data)), data)
+		if s.gl.UnmapBuffer(glpkg.ArrayBuffer) {
+			return offset
+		}
+	}
+
+	// Mapping failed or the contents were lost; fall back to a plain upload.
+	s.gl.BufferSubData(glpkg.ArrayBuffer, offset, n, unsafe.Pointer(&data[0]))
+	return offset
+}
@@ -0,0 +1,168 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawBatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		draw      func(f glFrame, a, b Texture)
+		wantDraws int
+		wantMaps  int
+		wantVerts int
+	}{
+		{
+			name: "same texture",
+			draw: func(f glFrame, a, b Texture) {
+				for range 100 {
+					f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+				}
+			},
+			wantDraws: 1, wantMaps: 1, wantVerts: 600,
+		},
+		{
+			name: "alternating textures",
+			draw: func(f glFrame, a, b Texture) {
+				for range 5 {
+					f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+					f.RenderQuad(0, 0, 1, 1, b, ColorWhite)
+				}
+			},
+			wantDraws: 10, wantMaps: 10, wantVerts: 60,
+		},
+		{
+			name: "state change",
+			draw: func(f glFrame, a, b Texture) {
+				f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+				f.SetBlendMode(BlendAdditive)
+				f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+			},
+			wantDraws: 2, wantMaps: 2, wantVerts: 12,
+		},
+		{
+			name: "shapes and quads",
+			draw: func(f glFrame, a, b Texture) {
+				f.FillCircle(10, 10, 5, 8, ColorWhite)
+				f.FillCircle(20, 10, 5, 8, ColorWhite)
+				f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+			},
+			wantDraws: 2, wantMaps: 2, wantVerts: 2*8*3 + 6,
+		},
+		{
+			name: "larger than the stream buffer",
+			draw: func(f glFrame, a, b Texture) {
+				batch := NewSpriteBatch(a)
+				for range quadBufferQuads + 1 {
+					batch.Add(0, 0, 1, 1, ColorWhite)
+				}
+				f.RenderQuad(0, 0, 1, 1, a, ColorWhite)
+				f.DrawBatch(batch)
+			},
+			wantDraws: 3, wantMaps: 3, wantVerts: 6 * (quadBufferQuads + 2),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, gl := newTestWindow(t, 64, 64)
+			if err := w.prepareFrame(); err != nil {
+				t.Fatal(err)
+			}
+			a, _ := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+			b, _ := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 1, 1)))
+
+			tt.draw(glFrame{w: w}, a, b)
+			w.Flush()
+			if got := gl.calls["DrawArrays"]; got != tt.wantDraws {
+				t.Errorf("%d draw calls, want %d", got, tt.wantDraws)
+			}
+			if got := gl.calls["MapBufferRange"]; got != tt.wantMaps {
+				t.Errorf("vertex buffer mapped %d times, want %d", got, tt.wantMaps)
+			}
+			if gl.drawnVertices != tt.wantVerts {
+				t.Errorf("%d vertices drawn, want %d", gl.drawnVertices, tt.wantVerts)
+			}
+		})
+	}
+}
+
+func TestFlushBeforeReadingOrUpdating(t *testing.T) {
+	tests := []struct {
+		name string
+		do   func(f glFrame, tex StreamingTexture)
+	}{
+		{"Screenshot", func(f glFrame, tex StreamingTexture) { f.Screenshot() }},
+		{"PixelAt", func(f glFrame, tex StreamingTexture) { f.PixelAt(0, 0) }},
+		{"ScreenshotAsync", func(f glFrame, tex StreamingTexture) { f.ScreenshotAsync() }},
+		{"ClearRect", func(f glFrame, tex StreamingTexture) { f.ClearRect(0, 0, 4, 4, ColorBlack) }},
+		{"SetView", func(f glFrame, tex StreamingTexture) { f.SetView(PanZoom(0, 0, 2)) }},
+		{"BeginMask", func(f glFrame, tex StreamingTexture) { f.BeginMask() }},
+		{"Update", func(f glFrame, tex StreamingTexture) {
+			tex.Update(image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+		}},
+		{"SetFilter", func(f glFrame, tex StreamingTexture) { tex.SetFilter(FilterNearest, FilterNearest) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, gl := newTestWindow(t, 64, 64)
+			if err := w.prepareFrame(); err != nil {
+				t.Fatal(err)
+			}
+			tex, err := w.NewStreamingTexture(2, 2, TextureOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := glFrame{w: w}
+			f.RenderQuad(0, 0, 4, 4, tex, ColorWhite)
+			if gl.calls["DrawArrays"] != 0 {
+				t.Fatal("quad drawn before a flush")
+			}
+			tt.do(f, tex)
+			if gl.calls["DrawArrays"] != 1 {
+				t.Errorf("queued quad not drawn by %s", tt.name)
+			}
+		})
+	}
+}
+
+// BenchmarkStreamBuffer draws tiles sampling one texture and reports how
+// often the vertex buffer is mapped per quad: once when every quad is
+// drawn on its own, as before batching, and once per draw call when they
+// are batched. Each unsynchronized mapping is a round trip into the driver;
+// with a real GL these are what stall the frame, which the fake GL used
+// here can't show, so compare maps/quad alongside ns/op.
+func BenchmarkStreamBuffer(b *testing.B) {
+	for _, bc := range []struct {
+		name       string
+		flushEvery int
+	}{
+		{"PerQuad", 1},
+		{"Batched", 1 << 30},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			w, gl := newTestWindow(b, 1024, 768)
+			if err := w.prepareFrame(); err != nil {
+				b.Fatal(err)
+			}
+			f := glFrame{w: w}
+			tex, err := w.NewTexture(image.NewNRGBA(image.Rect(0, 0, 32, 32)))
+			if err != nil {
+				b.Fatal(err)
+			}
+			var c color.Color = ColorWhite
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := range b.N {
+				f.RenderQuad(float32(i%32)*32, float32(i/32%24)*32, 32, 32, tex, c)
+				if (i+1)%bc.flushEvery == 0 {
+					w.flush()
+				}
+			}
+			w.flush()
+			b.ReportMetric(float64(gl.calls["MapBufferRange"])/float64(b.N), "maps/quad")
+		})
+	}
+}
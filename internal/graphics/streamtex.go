@@ -0,0 +1,148 @@
+package graphics
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+)
+
+// NewStreamingTexture implements Window.
+func (w *glWindow) NewStreamingTexture(width, height int, opts TextureOptions) (StreamingTexture, error) {
+	w.checkGoroutine("NewStreamingTexture")
+	tex, err := w.NewTextureWithOptions(image.NewNRGBA(image.Rect(0, 0, width, height)), opts)
+	if err != nil {
+		return nil, err
+	}
+	t := tex.(*glTexture)
+	w.gl.GenBuffers(1, &t.pbo)
+	return t, nil
+}
+
+// Update implements StreamingTexture.
+func (t *glTexture) Update(img image.Image) error {
+	if t.pbo == 0 {
+		return errors.New("texture was not created by NewStreamingTexture")
+	}
+	b := img.Bounds()
+	if b.Dx() != t.w || b.Dy() != t.h {
+		return fmt.Errorf("image size %dx%d does not match texture size %dx%d", b.Dx(), b.Dy(), t.w, t.h)
+	}
+	if t.w == 0 || t.h == 0 {
+		return nil
+	}
+
+	t.upload(t.pixels(img), PixelFormatRGBA)
+	return nil
+}
+
+// UpdateRaw implements StreamingTexture.
+func (t *glTexture) UpdateRaw(format PixelFormat, pixels []byte) error {
+	if t.pbo == 0 {
+		return errors.New("texture was not created by NewStreamingTexture")
+	}
+	if want := t.w * t.h * format.bytesPerPixel(); len(pixels) != want {
+		return fmt.Errorf("got %d bytes of pixels for a %dx%d texture, want %d",
+			len(pixels), t.w, t.h, want)
+	}
+	if len(pixels) == 0 {
+		return nil
+	}
+	t.upload(pixels, format)
+	return nil
+}
+
+// upload replaces the whole texture with pix, which is laid out as format.
+func (t *glTexture) upload(pix []byte, format PixelFormat) {
+	t.win.checkGoroutine("StreamingTexture.Update")
+	// Quads already queued must sample the old contents.
+	t.win.flush()
+	gl := t.win.gl
+	size := len(pix)
+
+	gl.BindTexture(glpkg.Texture2D, t.id)
+	t.win.boundTexture = t.id
+	gl.PixelStorei(glpkg.UnpackAlignment, 1)
+
+	// Orphan the buffer so a previous upload still in flight keeps its own
+	// storage, then fill the new storage without synchronizing.
+	gl.BindBuffer(glpkg.PixelUnpackBuffer, t.pbo)
+	gl.BufferData(glpkg.PixelUnpackBuffer, size, nil, glpkg.StreamDraw)
+	var src unsafe.Pointer // offset 0 into the unpack buffer
+	if ptr := gl.MapBufferRange(glpkg.PixelUnpackBuffer, 0, size,
+		glpkg.MapWriteBit|glpkg.MapInvalidateBufferBit); ptr == nil {
+		gl.BindBuffer(glpkg.PixelUnpackBuffer, 0)
+		src = unsafe.Pointer(&pix[0])
+	} else {
+		copy(unsafe.Slice((*byte)(ptr), size), pix)
+		if !gl.UnmapBuffer(glpkg.PixelUnpackBuffer) {
+			gl.BindBuffer(glpkg.PixelUnpackBuffer, 0)
+			src = unsafe.Pointer(&pix[0])
+		}
+	}
+
+	gl.TexSubImage2D(glpkg.Texture2D, 0, 0, 0, int32(t.w), int32(t.h),
+		format.glFormat(), format.glType(), src)
+	gl.BindBuffer(glpkg.PixelUnpackBuffer, 0)
+
+	if t.mipmap {
+		gl.GenerateMipmap(glpkg.Texture2D)
+	}
+}
+
+// pixels returns the tightly packed RGBA bytes of img, premultiplied if the
+// texture is, avoiding a copy where the image already has that layout.
+func (t *glTexture) pixels(img image.Image) []byte {
+	stride := t.w * 4
+	if t.premultiply {
+		if m, ok := img.(*image.RGBA); ok && m.Stride == stride {
+			return m.Pix[:stride*t.h]
+		}
+		t.ensureScratch()
+		dst := &image.RGBA{Pix: t.pix, Stride: stride, Rect: image.Rect(0, 0, t.w, t.h)}
+		draw.Draw(dst, dst.Rect, img, img.Bounds().Min, draw.Src)
+		return t.pix
+	}
+
+	switch m := img.(type) {
+	case *image.NRGBA:
+		if m.Stride == stride {
+			return m.Pix[:stride*t.h]
+		}
+	case *image.RGBA:
+		// Opaque images, the common case for video and remote desktops,
+		// need no un-premultiplying.
+		if m.Stride == stride && m.Opaque() {
+			return m.Pix[:stride*t.h]
+		}
+		// Un-premultiply. Opaque pixels are copied unchanged.
+		t.ensureScratch()
+		for y := 0; y < t.h; y++ {
+			src := m.Pix[y*m.Stride : y*m.Stride+stride]
+			dst := t.pix[y*stride : (y+1)*stride]
+			copy(dst, src)
+			for i := 0; i < stride; i += 4 {
+				if a := uint32(dst[i+3]); a != 0xff && a != 0 {
+					dst[i] = uint8(uint32(dst[i]) * 0xff / a)
+					dst[i+1] = uint8(uint32(dst[i+1]) * 0xff / a)
+					dst[i+2] = uint8(uint32(dst[i+2]) * 0xff / a)
+				}
+			}
+		}
+		return t.pix
+	}
+
+	t.ensureScratch()
+	dst := &image.NRGBA{Pix: t.pix, Stride: stride, Rect: image.Rect(0, 0, t.w, t.h)}
+	draw.Draw(dst, dst.Rect, img, img.Bounds().Min, draw.Src)
+	return t.pix
+}
+
+func (t *glTexture) ensureScratch() {
+	if len(t.pix) != t.w*t.h*4 {
+		t.pix = make([]byte, t.w*t.h*4)
+	}
+}
@@ -1,13 +1,23 @@
 package rfb
 
 import (
+	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"image"
 	"io"
 	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+// maxNameLength bounds the desktop name read from ServerInit. Longer names
+// are truncated rather than trusting the server with the allocation size.
+const maxNameLength = 4096
+
 type frameBufferRectangle struct {
 	XPos         uint16
 	YPos         uint16
@@ -65,6 +75,11 @@ type pointerEvent struct {
 	YPos        uint16
 }
 
+type pointerState struct {
+	buttons Buttons
+	x, y    uint16
+}
+
 type frameBufferUpdateRequest struct {
 	MessageType uint8
 	Incremental uint8
@@ -74,6 +89,28 @@ type frameBufferUpdateRequest struct {
 	Height      uint16
 }
 
+type setPixelFormat struct {
+	MessageType uint8
+	Padding     [3]byte
+	PixelFormat PixelFormat
+}
+
+// clientPixelFormat is the format the client asks every server for: 32-bit
+// little-endian true color, so pixels arrive as BGRX in memory. It is the
+// native layout of most servers, which then needn't convert, and fixes the
+// size of a raw pixel at 4 bytes whatever the server started with.
+var clientPixelFormat = PixelFormat{
+	BitsPerPixel:  32,
+	Depth:         24,
+	TrueColorFlag: 1,
+	RedMax:        255,
+	GreenMax:      255,
+	BlueMax:       255,
+	RedShift:      16,
+	GreenShift:    8,
+	BlueShift:     0,
+}
+
 type PixelFormat struct {
 	BitsPerPixel  uint8
 	Depth         uint8
@@ -97,6 +134,8 @@ type ServerInit struct {
 
 type ConnectedEvent struct {
 	ServerInit
+	// Name is the desktop name, converted to UTF-8. It may be truncated or
+	// empty if the server sent something unusable.
 	Name string
 }
 
@@ -110,6 +149,11 @@ type ErrorEvent struct {
 // eventTag implements RFBEvent.
 func (r *ErrorEvent) eventTag() { panic("unimplemented") }
 
+// UpdateRectangleEvent is one rectangle of a FrameBufferUpdateEvent.
+//
+// Earlier versions sent each rectangle on Events by itself; they now only
+// arrive inside a FrameBufferUpdateEvent, so a type switch on Events
+// matching *UpdateRectangleEvent no longer sees any.
 type UpdateRectangleEvent struct {
 	image.Image
 	BGRA bool
@@ -118,10 +162,50 @@ type UpdateRectangleEvent struct {
 // eventTag implements Event.
 func (u *UpdateRectangleEvent) eventTag() { panic("unimplemented") }
 
+// FrameBufferUpdateEvent carries every rectangle of one FramebufferUpdate
+// message, so a client can apply them together and request the next
+// update once.
+type FrameBufferUpdateEvent struct {
+	Rectangles []*UpdateRectangleEvent
+}
+
+// eventTag implements Event.
+func (f *FrameBufferUpdateEvent) eventTag() { panic("unimplemented") }
+
+// BellEvent is sent when the server rings the bell.
+type BellEvent struct{}
+
+// eventTag implements Event.
+func (b *BellEvent) eventTag() { panic("unimplemented") }
+
+// UnknownMessageEvent reports a server message this client doesn't
+// interpret. Messages whose length is known (SetColourMapEntries,
+// ServerCutText) are skipped and the connection carries on; for any other
+// type the stream can't be resynchronized, so an ErrorEvent follows and
+// the connection closes.
+type UnknownMessageEvent struct {
+	Type byte
+}
+
+// eventTag implements Event.
+func (u *UnknownMessageEvent) eventTag() { panic("unimplemented") }
+
+// DisconnectedEvent is the last event of a connection, sent once the
+// receive loop stops for any reason other than Close. The ErrorEvent
+// before it says why.
+type DisconnectedEvent struct{}
+
+// eventTag implements Event.
+func (d *DisconnectedEvent) eventTag() { panic("unimplemented") }
+
 var (
 	_ Event = &ErrorEvent{}
 	_ Event = &ConnectedEvent{}
 	_ Event = &UpdateRectangleEvent{}
+	_ Event = &FrameBufferUpdateEvent{}
+	_ Event = &BellEvent{}
+	_ Event = &UnknownMessageEvent{}
+	_ Event = &DisconnectedEvent{}
 )
 
 type Event interface {
@@ -129,42 +213,251 @@ type Event interface {
 }
 
 type Connection struct {
-	Conn        net.Conn
+	// Conn is the transport, usually a net.Conn. Reads go through an
+	// internal buffer, so don't read from it directly.
+	Conn        io.ReadWriteCloser
 	Events      chan Event
-	closed      bool
+	r           *bufio.Reader
 	serverInit  ServerInit
 	pixelFormat PixelFormat
+
+	versionMajor, versionMinor int
+	shared                     bool
+
+	// done is closed by the first Close; closeErr is what closing Conn
+	// returned then.
+	closeOnce sync.Once
+	done      chan struct{}
+	closeErr  error
+
+	// Incremental update requests are spaced at least minInterval apart;
+	// one arriving early is sent later by a timer, and further ones while
+	// it is pending are dropped.
+	requestMu      sync.Mutex
+	minInterval    time.Duration
+	lastRequest    time.Time
+	requestPending bool
+	keepAliveStop  chan struct{}
+
+	// The pointer state last sent, and the latest one asked for by
+	// SendPointerIfChanged while motion is being held back.
+	pointerMu       sync.Mutex
+	pointerInterval time.Duration
+	lastPointer     time.Time
+	pointerSent     bool
+	pointerPending  bool
+	pointer         pointerState
+	nextPointer     pointerState
+
+	// fb is the remote framebuffer with every update applied, in RGBA
+	// order. fbMu guards it against the receive loop.
+	fbMu sync.Mutex
+	fb   *image.RGBA
 }
 
-func (rfb *Connection) writeEvent(evt Event) {
-	if rfb.closed {
-		return
+// Size returns the remote framebuffer size, or zeros before the
+// ConnectedEvent.
+func (rfb *Connection) Size() (w, h int) {
+	rfb.fbMu.Lock()
+	defer rfb.fbMu.Unlock()
+	if rfb.fb == nil {
+		return 0, 0
 	}
+	return rfb.fb.Rect.Dx(), rfb.fb.Rect.Dy()
+}
 
-	rfb.Events <- evt
+// Framebuffer returns a copy of the remote framebuffer as of the last
+// update received, or nil before the ConnectedEvent. Areas the server
+// hasn't sent yet are transparent black.
+func (rfb *Connection) Framebuffer() *image.RGBA {
+	rfb.fbMu.Lock()
+	defer rfb.fbMu.Unlock()
+	if rfb.fb == nil {
+		return nil
+	}
+	img := image.NewRGBA(rfb.fb.Rect)
+	copy(img.Pix, rfb.fb.Pix)
+	return img
 }
 
-func (rfb *Connection) Close() error {
-	if !rfb.closed {
-		rfb.closed = true
-		close(rfb.Events)
-		return rfb.Conn.Close()
+// WithFramebuffer calls fn with the remote framebuffer, as Framebuffer
+// returns it but without the copy, and reports whether it did; before the
+// ConnectedEvent it doesn't. Updates wait while fn runs, so it should only
+// read or upload the pixels, and must not keep fb or call other Connection
+// methods.
+func (rfb *Connection) WithFramebuffer(fn func(fb *image.RGBA)) bool {
+	rfb.fbMu.Lock()
+	defer rfb.fbMu.Unlock()
+	if rfb.fb == nil {
+		return false
 	}
+	fn(rfb.fb)
+	return true
+}
 
-	return nil
+// applyUpdate copies the rectangles of an update into the framebuffer.
+func (rfb *Connection) applyUpdate(update *FrameBufferUpdateEvent) {
+	rfb.fbMu.Lock()
+	defer rfb.fbMu.Unlock()
+	for _, rect := range update.Rectangles {
+		src, ok := rect.Image.(*image.RGBA)
+		if !ok {
+			continue
+		}
+		r := src.Rect.Intersect(rfb.fb.Rect)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			from := src.Pix[src.PixOffset(r.Min.X, y):src.PixOffset(r.Max.X, y)]
+			to := rfb.fb.Pix[rfb.fb.PixOffset(r.Min.X, y):rfb.fb.PixOffset(r.Max.X, y)]
+			copy(to, from)
+			// The fourth byte is padding, not alpha.
+			for i := 0; i < len(to); i += 4 {
+				if rect.BGRA {
+					to[i], to[i+2] = to[i+2], to[i]
+				}
+				to[i+3] = 0xff
+			}
+		}
+	}
+}
+
+// SetMaxUpdateRate limits incremental update requests to fps per second,
+// so a fast server doesn't make the client decode frames faster than it
+// can show them. Zero or less removes the limit.
+func (rfb *Connection) SetMaxUpdateRate(fps int) {
+	rfb.requestMu.Lock()
+	defer rfb.requestMu.Unlock()
+	if fps <= 0 {
+		rfb.minInterval = 0
+	} else {
+		rfb.minInterval = time.Second / time.Duration(fps)
+	}
+}
+
+// SetKeepAlive keeps an idle connection from being dropped by NATs and
+// firewalls by sending an empty update request every interval, and turns
+// on TCP keepalives when the transport is a TCP connection. If a send
+// fails the connection is shut down, so a DisconnectedEvent follows
+// without waiting for the next user input. Zero or less turns it off.
+func (rfb *Connection) SetKeepAlive(interval time.Duration) {
+	if tcp, ok := rfb.Conn.(*net.TCPConn); ok {
+		tcp.SetKeepAlive(interval > 0)
+		if interval > 0 {
+			tcp.SetKeepAlivePeriod(interval)
+		}
+	}
+
+	rfb.requestMu.Lock()
+	defer rfb.requestMu.Unlock()
+	if rfb.keepAliveStop != nil {
+		close(rfb.keepAliveStop)
+		rfb.keepAliveStop = nil
+	}
+	if interval > 0 {
+		rfb.keepAliveStop = make(chan struct{})
+		go rfb.keepAlive(interval, rfb.keepAliveStop)
+	}
+}
+
+func (rfb *Connection) keepAlive(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-rfb.done:
+			return
+		case <-ticker.C:
+		}
+		// An incremental request for an empty area costs the server
+		// nothing to answer.
+		err := binary.Write(rfb.Conn, binary.BigEndian, &frameBufferUpdateRequest{
+			MessageType: 3,
+			Incremental: 1,
+		})
+		if err != nil {
+			// Closing the transport wakes the receive loop, which reports
+			// the failure.
+			rfb.Conn.Close()
+			return
+		}
+	}
+}
+
+// Version returns the protocol version negotiated with the server, e.g.
+// 3, 8 for RFB 3.8.
+func (rfb *Connection) Version() (major, minor int) {
+	return rfb.versionMajor, rfb.versionMinor
+}
+
+// writeEvent delivers evt unless the connection has been closed, without
+// blocking on a reader that has gone away.
+func (rfb *Connection) writeEvent(evt Event) {
+	select {
+	case <-rfb.done:
+		return
+	default:
+	}
+	select {
+	case rfb.Events <- evt:
+	case <-rfb.done:
+	}
+}
+
+// Close shuts the connection down. It is safe to call more than once and
+// from any goroutine; later calls return the first one's result. Events
+// is closed once the receive loop has stopped.
+func (rfb *Connection) Close() error {
+	rfb.closeOnce.Do(func() {
+		close(rfb.done)
+		rfb.closeErr = rfb.Conn.Close()
+	})
+	return rfb.closeErr
 }
 
 func (rfb *Connection) readBytes(count int) ([]byte, error) {
 	b := make([]byte, count)
 
-	if _, err := io.ReadFull(rfb.Conn, b); err != nil {
+	if _, err := io.ReadFull(rfb.r, b); err != nil {
 		return nil, err
 	}
 
 	return b, nil
 }
 
+// RequestUpdate asks the server for a framebuffer update. Incremental
+// requests are subject to SetMaxUpdateRate and may be sent later.
 func (rfb *Connection) RequestUpdate(incremental bool) error {
+	if !incremental {
+		return rfb.sendUpdateRequest(false)
+	}
+
+	rfb.requestMu.Lock()
+	if rfb.requestPending {
+		rfb.requestMu.Unlock()
+		return nil
+	}
+	wait := rfb.minInterval - time.Since(rfb.lastRequest)
+	if wait <= 0 {
+		rfb.lastRequest = time.Now()
+		rfb.requestMu.Unlock()
+		return rfb.sendUpdateRequest(true)
+	}
+	rfb.requestPending = true
+	rfb.requestMu.Unlock()
+
+	time.AfterFunc(wait, func() {
+		rfb.requestMu.Lock()
+		rfb.requestPending = false
+		rfb.lastRequest = time.Now()
+		rfb.requestMu.Unlock()
+		// A failed write also breaks the receive loop, which reports it.
+		rfb.sendUpdateRequest(true)
+	})
+	return nil
+}
+
+func (rfb *Connection) sendUpdateRequest(incremental bool) error {
 	var b uint8 = 0
 	if incremental {
 		b = 1
@@ -194,6 +487,12 @@ func (rfb *Connection) SendKeyEvent(down bool, sym uint32) error {
 }
 
 func (rfb *Connection) SendPointerEvent(buttons Buttons, xPos uint16, yPos uint16) error {
+	rfb.pointerMu.Lock()
+	rfb.pointer = pointerState{buttons, xPos, yPos}
+	rfb.pointerSent = true
+	rfb.lastPointer = time.Now()
+	rfb.pointerMu.Unlock()
+
 	return binary.Write(rfb.Conn, binary.BigEndian, &pointerEvent{
 		MessageType: 5,
 		ButtonMask:  buttons,
@@ -202,24 +501,93 @@ func (rfb *Connection) SendPointerEvent(buttons Buttons, xPos uint16, yPos uint1
 	})
 }
 
-func (rfb *Connection) receiveLoop() {
-	defer rfb.Close()
+// SetMaxPointerRate limits the motion sent by SendPointerIfChanged to hz
+// events per second. Zero or less removes the limit.
+func (rfb *Connection) SetMaxPointerRate(hz int) {
+	rfb.pointerMu.Lock()
+	defer rfb.pointerMu.Unlock()
+	if hz <= 0 {
+		rfb.pointerInterval = 0
+	} else {
+		rfb.pointerInterval = time.Second / time.Duration(hz)
+	}
+}
+
+// SendPointerIfChanged is SendPointerEvent for callers that poll the
+// pointer every frame. Nothing is sent if the state is the one last sent.
+// Button changes go out at once; motion alone is subject to
+// SetMaxPointerRate, and the latest position is sent when the limit
+// allows.
+func (rfb *Connection) SendPointerIfChanged(buttons Buttons, xPos uint16, yPos uint16) error {
+	state := pointerState{buttons, xPos, yPos}
+
+	rfb.pointerMu.Lock()
+	rfb.nextPointer = state
+	if rfb.pointerSent && state == rfb.pointer {
+		rfb.pointerMu.Unlock()
+		return nil
+	}
+	var wait time.Duration
+	if rfb.pointerSent && buttons == rfb.pointer.buttons {
+		wait = rfb.pointerInterval - time.Since(rfb.lastPointer)
+	}
+	if wait <= 0 {
+		rfb.pointerMu.Unlock()
+		return rfb.SendPointerEvent(buttons, xPos, yPos)
+	}
+	if rfb.pointerPending {
+		rfb.pointerMu.Unlock()
+		return nil
+	}
+	rfb.pointerPending = true
+	rfb.pointerMu.Unlock()
+
+	time.AfterFunc(wait, func() {
+		rfb.pointerMu.Lock()
+		rfb.pointerPending = false
+		next := rfb.nextPointer
+		changed := next != rfb.pointer
+		rfb.pointerMu.Unlock()
+		if changed {
+			// A failed write also breaks the receive loop, which reports it.
+			rfb.SendPointerEvent(next.buttons, next.x, next.y)
+		}
+	})
+	return nil
+}
+
+// negotiateSecurity performs the security handshake for the negotiated
+// version, selecting the None security type. Version 3.3 has the server
+// pick a type; 3.7 and later offer a list. Only 3.8 confirms None with a
+// SecurityResult.
+func (rfb *Connection) negotiateSecurity() error {
+	if rfb.versionMinor == 3 {
+		typBytes, err := rfb.readBytes(4)
+		if err != nil {
+			return err
+		}
+		switch binary.BigEndian.Uint32(typBytes) {
+		case 0:
+			return rfb.readFailure("failed to connect to server")
+		case 1:
+			return nil
+		default:
+			return fmt.Errorf("server requires a password")
+		}
+	}
 
 	securityTypeCount, err := rfb.readBytes(1)
 	if err != nil {
-		rfb.writeEvent(&ErrorEvent{error: err})
-		return
+		return err
 	}
 
 	if securityTypeCount[0] == 0 {
-		rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("failed to connect to server")})
-		return
+		return rfb.readFailure("failed to connect to server")
 	}
 
 	securityTypes, err := rfb.readBytes(int(securityTypeCount[0]))
 	if err != nil {
-		rfb.writeEvent(&ErrorEvent{error: err})
-		return
+		return err
 	}
 
 	acceptsNone := false
@@ -230,32 +598,64 @@ func (rfb *Connection) receiveLoop() {
 		}
 	}
 	if !acceptsNone {
-		rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("server requires a password")})
-		return
+		return fmt.Errorf("server requires a password")
 	}
 
 	if _, err := rfb.Conn.Write([]byte{0x01}); err != nil {
-		rfb.writeEvent(&ErrorEvent{error: err})
-		return
+		return err
+	}
+
+	if rfb.versionMinor < 8 {
+		return nil
 	}
 
 	// Check the result of the security init.
 	resBytes, err := rfb.readBytes(4)
 	if err != nil {
-		rfb.writeEvent(&ErrorEvent{error: err})
-		return
+		return err
+	}
+
+	if binary.BigEndian.Uint32(resBytes) != 0 {
+		return rfb.readFailure("security handshake failed")
 	}
+	return nil
+}
+
+// readFailure reads the reason string the server sends after refusing a
+// connection and returns it as an error prefixed with msg.
+func (rfb *Connection) readFailure(msg string) error {
+	lenBytes, err := rfb.readBytes(4)
+	if err != nil {
+		return errors.New(msg)
+	}
+	reason, err := rfb.readBytes(int(min(binary.BigEndian.Uint32(lenBytes), maxNameLength)))
+	if err != nil || len(reason) == 0 {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s: %s", msg, decodeName(reason))
+}
 
-	res := binary.BigEndian.Uint32(resBytes)
+func (rfb *Connection) receiveLoop() {
+	defer func() {
+		rfb.writeEvent(&DisconnectedEvent{})
+		// The receive loop is the only sender, so Events can only be
+		// closed here.
+		close(rfb.Events)
+		rfb.Close()
+	}()
 
-	if res != 0 {
-		rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("security handshake failed")})
+	if err := rfb.negotiateSecurity(); err != nil {
+		rfb.writeEvent(&ErrorEvent{error: err})
 		return
 	}
 
-	// Send the ClientInit message to the server.
-	// Kick out all the other clients.
-	if _, err := rfb.Conn.Write([]byte{0x00}); err != nil {
+	// Send the ClientInit message to the server. Unless shared, the server
+	// kicks out all the other clients.
+	var shared byte
+	if rfb.shared {
+		shared = 1
+	}
+	if _, err := rfb.Conn.Write([]byte{shared}); err != nil {
 		rfb.writeEvent(&ErrorEvent{error: err})
 		return
 	}
@@ -263,22 +663,39 @@ func (rfb *Connection) receiveLoop() {
 	// Get the ServerInit response from the server.
 	var serverInit ServerInit
 
-	if err := binary.Read(rfb.Conn, binary.BigEndian, &serverInit); err != nil {
+	if err := binary.Read(rfb.r, binary.BigEndian, &serverInit); err != nil {
 		rfb.writeEvent(&ErrorEvent{error: err})
 		return
 	}
 
-	nameBytes, err := rfb.readBytes(int(serverInit.NameLength))
+	nameBytes, err := rfb.readBytes(int(min(serverInit.NameLength, maxNameLength)))
 	if err != nil {
 		rfb.writeEvent(&ErrorEvent{error: err})
 		return
 	}
+	if serverInit.NameLength > maxNameLength {
+		if _, err := io.CopyN(io.Discard, rfb.r, int64(serverInit.NameLength-maxNameLength)); err != nil {
+			rfb.writeEvent(&ErrorEvent{error: err})
+			return
+		}
+	}
+
+	// Raw rectangles are decoded as 4 bytes a pixel, so don't leave the
+	// format to the server.
+	if err := binary.Write(rfb.Conn, binary.BigEndian, &setPixelFormat{PixelFormat: clientPixelFormat}); err != nil {
+		rfb.writeEvent(&ErrorEvent{error: err})
+		return
+	}
 
 	rfb.serverInit = serverInit
-	rfb.pixelFormat = serverInit.PixelFormat
+	rfb.pixelFormat = clientPixelFormat
+
+	rfb.fbMu.Lock()
+	rfb.fb = image.NewRGBA(image.Rect(0, 0, int(serverInit.FrameBufferWidth), int(serverInit.FrameBufferHeight)))
+	rfb.fbMu.Unlock()
 
 	// Post a RFBConnected message.
-	rfb.writeEvent(&ConnectedEvent{ServerInit: serverInit, Name: string(nameBytes)})
+	rfb.writeEvent(&ConnectedEvent{ServerInit: serverInit, Name: decodeName(nameBytes)})
 
 	// Start the main loop.
 	for {
@@ -297,67 +714,153 @@ func (rfb *Connection) receiveLoop() {
 			}
 
 			rectCount := binary.BigEndian.Uint16(updateHead[1:])
+			update := &FrameBufferUpdateEvent{Rectangles: make([]*UpdateRectangleEvent, 0, rectCount)}
 
 			for i := 0; i < int(rectCount); i++ {
 				var rectHead frameBufferRectangle
 
-				if err := binary.Read(rfb.Conn, binary.BigEndian, &rectHead); err != nil {
+				if err := binary.Read(rfb.r, binary.BigEndian, &rectHead); err != nil {
 					rfb.writeEvent(&ErrorEvent{error: err})
 					return
 				}
+				if int(rectHead.XPos)+int(rectHead.Width) > int(serverInit.FrameBufferWidth) ||
+					int(rectHead.YPos)+int(rectHead.Height) > int(serverInit.FrameBufferHeight) {
+					rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("rectangle %dx%d at (%d, %d) is outside the %dx%d framebuffer",
+						rectHead.Width, rectHead.Height, rectHead.XPos, rectHead.YPos,
+						serverInit.FrameBufferWidth, serverInit.FrameBufferHeight)})
+					return
+				}
 
 				switch rectHead.EncodingType {
 				case 0: // raw
-					buff, err := rfb.readBytes(int(rfb.pixelFormat.BitsPerPixel) / 8 * int(rectHead.Width) * int(rectHead.Height))
+					buff, err := rfb.readBytes(4 * int(rectHead.Width) * int(rectHead.Height))
 					if err != nil {
 						rfb.writeEvent(&ErrorEvent{error: err})
 						return
 					}
 
-					rfb.writeEvent(&UpdateRectangleEvent{
+					update.Rectangles = append(update.Rectangles, &UpdateRectangleEvent{
 						Image: &image.RGBA{
 							Pix:    buff,
 							Stride: int(rectHead.Width) * 4,
 							Rect: image.Rect(
 								int(rectHead.XPos),
 								int(rectHead.YPos),
-								int(rectHead.XPos+rectHead.Width),
-								int(rectHead.YPos+rectHead.Height),
+								int(rectHead.XPos)+int(rectHead.Width),
+								int(rectHead.YPos)+int(rectHead.Height),
 							),
 						},
 						BGRA: rfb.pixelFormat.BlueShift == 0,
 					})
 				default:
-					rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("unknown rectangle encoding: %d", msgType[0])})
+					rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("unknown rectangle encoding: %d", rectHead.EncodingType)})
 					return
 				}
 			}
+			rfb.applyUpdate(update)
+			rfb.writeEvent(update)
+		case 1: // set colour map entries; only used without true color
+			head, err := rfb.readBytes(5)
+			if err != nil {
+				rfb.writeEvent(&ErrorEvent{error: err})
+				return
+			}
+			if _, err := rfb.readBytes(6 * int(binary.BigEndian.Uint16(head[3:]))); err != nil {
+				rfb.writeEvent(&ErrorEvent{error: err})
+				return
+			}
+			rfb.writeEvent(&UnknownMessageEvent{Type: msgType[0]})
+		case 2: // bell
+			rfb.writeEvent(&BellEvent{})
+		case 3: // server cut text
+			head, err := rfb.readBytes(7)
+			if err != nil {
+				rfb.writeEvent(&ErrorEvent{error: err})
+				return
+			}
+			if _, err := io.CopyN(io.Discard, rfb.r, int64(binary.BigEndian.Uint32(head[3:]))); err != nil {
+				rfb.writeEvent(&ErrorEvent{error: err})
+				return
+			}
+			rfb.writeEvent(&UnknownMessageEvent{Type: msgType[0]})
 		default:
+			rfb.writeEvent(&UnknownMessageEvent{Type: msgType[0]})
 			rfb.writeEvent(&ErrorEvent{error: fmt.Errorf("unknown event: %d", msgType[0])})
 			return
 		}
 	}
 }
 
+// decodeName converts a desktop name to a Go string. The protocol says it
+// is Latin-1 but many servers send UTF-8, so valid UTF-8 is kept as is and
+// anything else is read as Latin-1. Trailing NULs from C servers are
+// dropped.
+func decodeName(b []byte) string {
+	var name string
+	if utf8.Valid(b) {
+		name = string(b)
+	} else {
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		name = string(runes)
+	}
+	return strings.TrimRight(name, "\x00")
+}
+
+// NewConn starts an RFB session over a network connection.
 func NewConn(conn net.Conn) (*Connection, error) {
+	return NewConnRW(conn)
+}
+
+// Options configures a connection made with NewConnWithOptions. The zero
+// value matches NewConn.
+type Options struct {
+	// Shared lets other clients stay connected to the server. Otherwise
+	// the server is asked to disconnect them, though it may refuse.
+	Shared bool
+}
+
+// NewConnRW starts an RFB session over any byte stream, such as an SSH
+// channel or a WebSocket bridge. The connection takes ownership of conn
+// and closes it when done.
+func NewConnRW(conn io.ReadWriteCloser) (*Connection, error) {
+	return NewConnWithOptions(conn, Options{})
+}
+
+// NewConnWithOptions is like NewConnRW but configured by opts.
+func NewConnWithOptions(conn io.ReadWriteCloser, opts Options) (*Connection, error) {
+	r := bufio.NewReader(conn)
+
 	// Get the version from the server.
 	version := make([]byte, 12)
 
-	if _, err := io.ReadFull(conn, version); err != nil {
+	if _, err := io.ReadFull(r, version); err != nil {
 		defer conn.Close()
 
 		return nil, err
 	}
 
-	// Check that the version is RFB 3.8
-	if string(version) != "RFB 003.008\n" {
+	// Pick the highest version we speak that the server supports: 3.3, 3.7
+	// or 3.8. Minor versions the spec doesn't define (e.g. 3.5) are to be
+	// treated as 3.3, and later ones (e.g. Apple's 3.889) as 3.8.
+	var major, minor int
+	if _, err := fmt.Sscanf(string(version), "RFB %03d.%03d\n", &major, &minor); err != nil || major != 3 || minor < 3 {
 		defer conn.Close()
 
-		return nil, fmt.Errorf("unknown version: %s", version)
+		return nil, fmt.Errorf("unknown version: %q", version)
+	}
+	switch {
+	case minor >= 8:
+		minor = 8
+	case minor == 7:
+	default:
+		minor = 3
 	}
 
-	// Write the same version to the server.
-	if _, err := conn.Write(version); err != nil {
+	// Reply with the chosen version.
+	if _, err := fmt.Fprintf(conn, "RFB %03d.%03d\n", 3, minor); err != nil {
 		defer conn.Close()
 
 		return nil, err
@@ -365,7 +868,7 @@ func NewConn(conn net.Conn) (*Connection, error) {
 
 	// And so thus ends Phase 1.
 
-	rfb := &Connection{Conn: conn, Events: make(chan Event)}
+	rfb := &Connection{Conn: conn, r: r, Events: make(chan Event), done: make(chan struct{}), versionMajor: 3, versionMinor: minor, shared: opts.Shared}
 
 	go rfb.receiveLoop()
 
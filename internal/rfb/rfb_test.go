@@ -0,0 +1,326 @@
+package rfb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nextEvent returns the next event from conn, failing the test if none
+// arrives in time or Events is closed.
+func nextEvent(t *testing.T, conn *Connection) Event {
+	t.Helper()
+	select {
+	case evt, ok := <-conn.Events:
+		if !ok {
+			t.Fatal("Events closed")
+		}
+		return evt
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
+
+// waitClosed fails the test unless Events is closed soon, discarding any
+// events still queued.
+func waitClosed(t *testing.T, conn *Connection) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-conn.Events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for Events to close")
+		}
+	}
+}
+
+func isBell(evt Event) bool {
+	_, ok := evt.(*BellEvent)
+	return ok
+}
+
+func isError(evt Event) bool {
+	_, ok := evt.(*ErrorEvent)
+	return ok
+}
+
+func isDisconnected(evt Event) bool {
+	_, ok := evt.(*DisconnectedEvent)
+	return ok
+}
+
+// connect starts srv and a client on it and reads the ConnectedEvent.
+func connect(t *testing.T, srv *TestServer) (*Connection, *ConnectedEvent) {
+	t.Helper()
+	conn, err := NewConn(srv.Start())
+	if err != nil {
+		t.Fatalf("NewConn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	evt := nextEvent(t, conn)
+	connected, ok := evt.(*ConnectedEvent)
+	if !ok {
+		t.Fatalf("first event is %T (%v), want *ConnectedEvent", evt, evt)
+	}
+	if err := srv.Wait(); err != nil {
+		t.Fatalf("server handshake: %v", err)
+	}
+	return conn, connected
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		offered   int
+		wantMinor int
+	}{
+		{offered: 3, wantMinor: 3},
+		{offered: 5, wantMinor: 3},
+		{offered: 7, wantMinor: 7},
+		{offered: 8, wantMinor: 8},
+		{offered: 889, wantMinor: 8},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("3.%d", tt.offered), func(t *testing.T) {
+			srv := NewTestServer(640, 480, "desk")
+			srv.Minor = tt.offered
+			conn, connected := connect(t, srv)
+
+			if major, minor := conn.Version(); major != 3 || minor != tt.wantMinor {
+				t.Errorf("Version() = %d.%d, want 3.%d", major, minor, tt.wantMinor)
+			}
+			if connected.Name != "desk" {
+				t.Errorf("Name = %q, want %q", connected.Name, "desk")
+			}
+			if w, h := conn.Size(); w != 640 || h != 480 {
+				t.Errorf("Size() = %dx%d, want 640x480", w, h)
+			}
+
+			// The client fixes the pixel format before anything else.
+			select {
+			case msg := <-srv.ClientMessages():
+				if msg[0] != 0 {
+					t.Fatalf("first client message has type %d, want SetPixelFormat", msg[0])
+				}
+				if bpp := msg[4]; bpp != 32 {
+					t.Errorf("SetPixelFormat asks for %d bits per pixel, want 32", bpp)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no SetPixelFormat from the client")
+			}
+		})
+	}
+}
+
+func TestSecurityFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		minor   int
+		reason  string
+		types   []byte
+		wantErr string
+	}{
+		{name: "3.3 refused", minor: 3, reason: "too many clients", wantErr: "too many clients"},
+		{name: "3.7 refused", minor: 7, reason: "go away", wantErr: "go away"},
+		{name: "3.8 refused", minor: 8, reason: "maintenance", wantErr: "maintenance"},
+		{name: "3.8 password", minor: 8, types: []byte{2}, wantErr: "requires a password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewTestServer(16, 16, "desk")
+			srv.Minor = tt.minor
+			srv.FailReason = tt.reason
+			srv.SecurityTypes = tt.types
+			conn, err := NewConn(srv.Start())
+			if err != nil {
+				t.Fatalf("NewConn: %v", err)
+			}
+			defer conn.Close()
+
+			evt := nextEvent(t, conn)
+			errEvt, ok := evt.(*ErrorEvent)
+			if !ok {
+				t.Fatalf("first event is %T, want *ErrorEvent", evt)
+			}
+			if !strings.Contains(errEvt.Error(), tt.wantErr) {
+				t.Errorf("error %q does not contain %q", errEvt.Error(), tt.wantErr)
+			}
+			if evt := nextEvent(t, conn); !isDisconnected(evt) {
+				t.Errorf("second event is %T, want *DisconnectedEvent", evt)
+			}
+			waitClosed(t, conn)
+		})
+	}
+}
+
+func TestServerMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(*TestServer) error
+		// check inspects the events that follow the message.
+		check func(*testing.T, *Connection)
+	}{
+		{
+			name: "raw update",
+			send: func(s *TestServer) error {
+				// Two BGRX pixels; the padding byte must not end up as alpha.
+				return s.SendRaw(image.Rect(1, 2, 3, 3), []byte{1, 2, 3, 0, 4, 5, 6, 0})
+			},
+			check: func(t *testing.T, conn *Connection) {
+				evt := nextEvent(t, conn)
+				update, ok := evt.(*FrameBufferUpdateEvent)
+				if !ok {
+					t.Fatalf("got %T, want *FrameBufferUpdateEvent", evt)
+				}
+				if len(update.Rectangles) != 1 {
+					t.Fatalf("got %d rectangles, want 1", len(update.Rectangles))
+				}
+				if got, want := update.Rectangles[0].Bounds(), image.Rect(1, 2, 3, 3); got != want {
+					t.Errorf("rectangle bounds = %v, want %v", got, want)
+				}
+				fb := conn.Framebuffer()
+				for _, px := range []struct {
+					x, y int
+					want color.RGBA
+				}{
+					{1, 2, color.RGBA{3, 2, 1, 0xff}},
+					{2, 2, color.RGBA{6, 5, 4, 0xff}},
+					{0, 0, color.RGBA{}},
+				} {
+					if got := fb.RGBAAt(px.x, px.y); got != px.want {
+						t.Errorf("pixel (%d, %d) = %v, want %v", px.x, px.y, got, px.want)
+					}
+				}
+				ok = conn.WithFramebuffer(func(live *image.RGBA) {
+					if !bytes.Equal(live.Pix, fb.Pix) {
+						t.Errorf("WithFramebuffer pixels differ from Framebuffer")
+					}
+				})
+				if !ok {
+					t.Errorf("WithFramebuffer didn't call fn after connecting")
+				}
+			},
+		},
+		{
+			name: "rectangle outside framebuffer",
+			send: func(s *TestServer) error {
+				// The client hangs up before reading the pixels, so the
+				// write fails.
+				s.SendRaw(image.Rect(15, 15, 17, 16), make([]byte, 8))
+				return nil
+			},
+			check: func(t *testing.T, conn *Connection) {
+				if evt := nextEvent(t, conn); !isError(evt) {
+					t.Fatalf("got %T, want *ErrorEvent", evt)
+				}
+				waitClosed(t, conn)
+			},
+		},
+		{
+			name: "bell",
+			send: (*TestServer).SendBell,
+			check: func(t *testing.T, conn *Connection) {
+				if evt := nextEvent(t, conn); !isBell(evt) {
+					t.Fatalf("got %T, want *BellEvent", evt)
+				}
+			},
+		},
+		{
+			name: "server cut text",
+			send: func(s *TestServer) error {
+				msg := []byte{3, 0, 0, 0}
+				msg = binary.BigEndian.AppendUint32(msg, 5)
+				if err := s.SendMessage(append(msg, "hello"...)); err != nil {
+					return err
+				}
+				return s.SendBell()
+			},
+			check: func(t *testing.T, conn *Connection) {
+				evt := nextEvent(t, conn)
+				if u, ok := evt.(*UnknownMessageEvent); !ok || u.Type != 3 {
+					t.Fatalf("got %T (%v), want *UnknownMessageEvent of type 3", evt, evt)
+				}
+				// The text was skipped, so the next message still parses.
+				if evt := nextEvent(t, conn); !isBell(evt) {
+					t.Fatalf("got %T after the cut text, want *BellEvent", evt)
+				}
+			},
+		},
+		{
+			name: "unknown type",
+			send: func(s *TestServer) error {
+				return s.SendMessage([]byte{99})
+			},
+			check: func(t *testing.T, conn *Connection) {
+				evt := nextEvent(t, conn)
+				if u, ok := evt.(*UnknownMessageEvent); !ok || u.Type != 99 {
+					t.Fatalf("got %T (%v), want *UnknownMessageEvent of type 99", evt, evt)
+				}
+				if evt := nextEvent(t, conn); !isError(evt) {
+					t.Fatalf("got %T, want *ErrorEvent", evt)
+				}
+				if evt := nextEvent(t, conn); !isDisconnected(evt) {
+					t.Errorf("got %T, want *DisconnectedEvent", evt)
+				}
+				waitClosed(t, conn)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewTestServer(16, 16, "desk")
+			conn, _ := connect(t, srv)
+			sent := make(chan error, 1)
+			go func() { sent <- tt.send(srv) }()
+			tt.check(t, conn)
+			if err := <-sent; err != nil {
+				t.Fatalf("send: %v", err)
+			}
+		})
+	}
+}
+
+func TestCloseWhileReading(t *testing.T) {
+	srv := NewTestServer(16, 16, "desk")
+	conn, _ := connect(t, srv)
+
+	// The receive loop is now blocked reading the next message. Close it
+	// from several goroutines at once; only one may do the work.
+	time.Sleep(10 * time.Millisecond)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	// Close is not a failure, so no DisconnectedEvent is expected, and
+	// Events must close rather than leave readers hanging.
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case evt, ok := <-conn.Events:
+			if !ok {
+				return
+			}
+			if isDisconnected(evt) {
+				t.Error("got DisconnectedEvent after Close")
+			}
+		case <-timeout:
+			t.Fatal("Events not closed after Close")
+		}
+	}
+}
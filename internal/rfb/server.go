@@ -0,0 +1,259 @@
+package rfb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+)
+
+// TestServer is a minimal scripted RFB server for exercising the client
+// without a real VNC server. It talks over a net.Pipe: NewTestServer hands
+// back the client end for NewConn, performs the handshake in the
+// background, and then sends whatever its methods are asked to.
+//
+// It serves a 32-bit little-endian true-color framebuffer, so pixels are
+// BGRA in memory, like most servers.
+type TestServer struct {
+	// Minor is the protocol version offered, 3, 7 or 8.
+	Minor int
+	// SecurityTypes are offered by 3.7 and 3.8 servers; 3.3 servers pick
+	// the first one. Defaults to just None.
+	SecurityTypes []byte
+	// FailReason, if set, makes the server refuse the connection during
+	// the security handshake, giving this reason.
+	FailReason string
+
+	width, height uint16
+	name          string
+
+	conn     net.Conn
+	ready    chan struct{}
+	err      error
+	messages chan []byte
+}
+
+// NewTestServer creates a server for a width x height desktop called name.
+// Set Minor and SecurityTypes before calling Start.
+func NewTestServer(width, height uint16, name string) *TestServer {
+	return &TestServer{
+		Minor:    8,
+		width:    width,
+		height:   height,
+		name:     name,
+		ready:    make(chan struct{}),
+		messages: make(chan []byte, 64),
+	}
+}
+
+// Start begins serving and returns the connection to pass to NewConn.
+func (s *TestServer) Start() net.Conn {
+	server, client := net.Pipe()
+	s.conn = server
+	go func() {
+		s.err = s.handshake()
+		close(s.ready)
+		if s.err == nil {
+			s.readClient()
+		}
+		close(s.messages)
+	}()
+	return client
+}
+
+// Wait blocks until the handshake is over and returns its error, if any.
+func (s *TestServer) Wait() error {
+	<-s.ready
+	return s.err
+}
+
+// ClientMessages returns the messages received from the client, one per
+// element, including the type byte. Messages arriving while 64 are
+// already queued are dropped. The channel closes with the connection.
+func (s *TestServer) ClientMessages() <-chan []byte {
+	return s.messages
+}
+
+// Close closes the server's end of the connection.
+func (s *TestServer) Close() error {
+	return s.conn.Close()
+}
+
+func (s *TestServer) handshake() error {
+	if _, err := fmt.Fprintf(s.conn, "RFB 003.%03d\n", s.Minor); err != nil {
+		return err
+	}
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(s.conn, version); err != nil {
+		return err
+	}
+	var major, minor int
+	_, err := fmt.Sscanf(string(version), "RFB %03d.%03d\n", &major, &minor)
+	if err != nil {
+		return fmt.Errorf("bad client version %q", version)
+	}
+
+	if s.FailReason != "" {
+		// Version 3.3 refuses with security type 0, later ones with an
+		// empty list of types.
+		if minor == 3 {
+			err = binary.Write(s.conn, binary.BigEndian, uint32(0))
+		} else {
+			_, err = s.conn.Write([]byte{0})
+		}
+		if err != nil {
+			return err
+		}
+		if err := binary.Write(s.conn, binary.BigEndian, uint32(len(s.FailReason))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(s.conn, s.FailReason); err != nil {
+			return err
+		}
+		return errors.New("refused: " + s.FailReason)
+	}
+
+	types := s.SecurityTypes
+	if len(types) == 0 {
+		types = []byte{1}
+	}
+	chosen := types[0]
+	if minor == 3 {
+		if err := binary.Write(s.conn, binary.BigEndian, uint32(chosen)); err != nil {
+			return err
+		}
+	} else {
+		if _, err := s.conn.Write(append([]byte{byte(len(types))}, types...)); err != nil {
+			return err
+		}
+		b := make([]byte, 1)
+		if _, err := io.ReadFull(s.conn, b); err != nil {
+			return err
+		}
+		chosen = b[0]
+	}
+	if chosen != 1 {
+		return errors.New("only the None security type is supported")
+	}
+	if minor >= 8 {
+		if err := binary.Write(s.conn, binary.BigEndian, uint32(0)); err != nil {
+			return err
+		}
+	}
+
+	clientInit := make([]byte, 1)
+	if _, err := io.ReadFull(s.conn, clientInit); err != nil {
+		return err
+	}
+
+	init := ServerInit{
+		FrameBufferWidth:  s.width,
+		FrameBufferHeight: s.height,
+		PixelFormat: PixelFormat{
+			BitsPerPixel:  32,
+			Depth:         24,
+			TrueColorFlag: 1,
+			RedMax:        255,
+			GreenMax:      255,
+			BlueMax:       255,
+			RedShift:      16,
+			GreenShift:    8,
+		},
+		NameLength: uint32(len(s.name)),
+	}
+	if err := binary.Write(s.conn, binary.BigEndian, &init); err != nil {
+		return err
+	}
+	_, err = io.WriteString(s.conn, s.name)
+	return err
+}
+
+// readClient splits the client's stream into messages. net.Pipe is
+// unbuffered, so the client's writes would block without it.
+func (s *TestServer) readClient() {
+	for {
+		typ := make([]byte, 1)
+		if _, err := io.ReadFull(s.conn, typ); err != nil {
+			return
+		}
+		var n int
+		switch typ[0] {
+		case 0: // SetPixelFormat
+			n = 19
+		case 2: // SetEncodings
+			n = 3
+		case 3: // FramebufferUpdateRequest
+			n = 9
+		case 4: // KeyEvent
+			n = 7
+		case 5: // PointerEvent
+			n = 5
+		case 6: // ClientCutText
+			n = 7
+		default:
+			return
+		}
+		msg := make([]byte, 1+n)
+		msg[0] = typ[0]
+		if _, err := io.ReadFull(s.conn, msg[1:]); err != nil {
+			return
+		}
+		var extra int
+		switch typ[0] {
+		case 2:
+			extra = 4 * int(binary.BigEndian.Uint16(msg[2:]))
+		case 6:
+			extra = int(binary.BigEndian.Uint32(msg[4:]))
+		}
+		if extra > 0 {
+			tail := make([]byte, extra)
+			if _, err := io.ReadFull(s.conn, tail); err != nil {
+				return
+			}
+			msg = append(msg, tail...)
+		}
+		select {
+		case s.messages <- msg:
+		default:
+		}
+	}
+}
+
+// SendRaw sends a framebuffer update of one raw-encoded rectangle. pix
+// holds its BGRA pixels, 4 bytes each, row by row.
+func (s *TestServer) SendRaw(r image.Rectangle, pix []byte) error {
+	if len(pix) != r.Dx()*r.Dy()*4 {
+		return fmt.Errorf("got %d bytes of pixels for a %dx%d rectangle", len(pix), r.Dx(), r.Dy())
+	}
+	head := []byte{0, 0}
+	head = binary.BigEndian.AppendUint16(head, 1)
+	if _, err := s.conn.Write(head); err != nil {
+		return err
+	}
+	rect := frameBufferRectangle{
+		XPos:   uint16(r.Min.X),
+		YPos:   uint16(r.Min.Y),
+		Width:  uint16(r.Dx()),
+		Height: uint16(r.Dy()),
+	}
+	if err := binary.Write(s.conn, binary.BigEndian, &rect); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(pix)
+	return err
+}
+
+// SendBell rings the client's bell.
+func (s *TestServer) SendBell() error {
+	_, err := s.conn.Write([]byte{2})
+	return err
+}
+
+// SendMessage writes b to the client as is, for messages the other
+// methods don't cover or deliberately malformed input.
+func (s *TestServer) SendMessage(b []byte) error {
+	_, err := s.conn.Write(b)
+	return err
+}
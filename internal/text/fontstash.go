@@ -73,14 +73,13 @@ type Stash struct {
 	yInverted  bool
 
 	// GL3 resources
-	shaderProgram  uint32
-	vao            uint32
-	vbo            uint32
-	projUniform    int32
-	viewportW      int32
-	viewportH      int32
-	scale          float32
-	graphicsShader uint32
+	shaderProgram uint32
+	vao           uint32
+	vbo           uint32
+	projUniform   int32
+	viewportW     int32
+	viewportH     int32
+	scale         float32
 }
 
 type Font struct {
@@ -141,6 +140,14 @@ func New(gl glpkg.OpenGL, cachew, cacheh int) *Stash {
 
 	stash.gl = gl
 
+	// Don't ask for a cache texture larger than the driver supports.
+	var maxSize int32
+	gl.GetIntegerv(glpkg.MaxTextureSize, &maxSize)
+	if maxSize > 0 {
+		cachew = min(cachew, int(maxSize))
+		cacheh = min(cacheh, int(maxSize))
+	}
+
 	// Create data for clearing the textures
 	stash.emptyData = make([]byte, cachew*cacheh)
 
@@ -180,17 +187,17 @@ func New(gl glpkg.OpenGL, cachew, cacheh int) *Stash {
 	gl.BindVertexArray(vao)
 	gl.BindBuffer(glpkg.ArrayBuffer, vbo)
 	// Allocate buffer for VERT_COUNT vertices * 8 floats (2 pos + 2 tex + 4 color)
-	gl.BufferData(glpkg.ArrayBuffer, VERT_COUNT*8*4, nil, glpkg.DynamicDraw)
+	gl.BufferData(glpkg.ArrayBuffer, VERT_COUNT*8*4, nil, glpkg.StreamDraw)
 
 	// Set up vertex attributes
 	posLoc := gl.GetAttribLocation(program, "a_position")
 	texLoc := gl.GetAttribLocation(program, "a_texCoord")
 	colLoc := gl.GetAttribLocation(program, "a_color")
-	gl.VertexAttribPointer(uint32(posLoc), 2, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(0)))
+	gl.VertexAttribPointer(uint32(posLoc), 2, glpkg.Float, false, 8*4, 0)
 	gl.EnableVertexAttribArray(uint32(posLoc))
-	gl.VertexAttribPointer(uint32(texLoc), 2, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(8)))
+	gl.VertexAttribPointer(uint32(texLoc), 2, glpkg.Float, false, 8*4, 8)
 	gl.EnableVertexAttribArray(uint32(texLoc))
-	gl.VertexAttribPointer(uint32(colLoc), 4, glpkg.Float, false, 8*4, unsafe.Pointer(uintptr(16)))
+	gl.VertexAttribPointer(uint32(colLoc), 4, glpkg.Float, false, 8*4, 16)
 	gl.EnableVertexAttribArray(uint32(colLoc))
 
 	return stash
@@ -438,10 +445,6 @@ func (s *Stash) SetScale(scale float32) {
 	s.scale = scale
 }
 
-func (s *Stash) SetGraphicsShader(shader uint32) {
-	s.graphicsShader = shader
-}
-
 func (s *Stash) GetQuad(fnt *Font, glyph *Glyph, isize int16, x, y float64) (float64, float64, *Quad) {
 	q := &Quad{}
 	scale := float64(1)
@@ -492,9 +495,22 @@ func (s *Stash) FlushDraw() {
 	}
 	proj := orthoMatrix(0, width, height, 0, -1, 1)
 
-	// Save current shader program to restore later
-	// Note: We can't easily query the current program in GL3, so we'll rely on
-	// the graphics system to reset it in prepareFrame() each frame
+	// Save the state we're about to change so quad rendering isn't disturbed.
+	var program, vao, vbo, activeTexture, texture2D int32
+	s.gl.GetIntegerv(glpkg.CurrentProgram, &program)
+	s.gl.GetIntegerv(glpkg.VertexArrayBinding, &vao)
+	s.gl.GetIntegerv(glpkg.ArrayBufferBinding, &vbo)
+	s.gl.GetIntegerv(glpkg.ActiveTexture, &activeTexture)
+	s.gl.ActiveTexture(glpkg.Texture0)
+	s.gl.GetIntegerv(glpkg.TextureBinding2D, &texture2D)
+	defer func() {
+		s.gl.UseProgram(uint32(program))
+		s.gl.BindVertexArray(uint32(vao))
+		s.gl.BindBuffer(glpkg.ArrayBuffer, uint32(vbo))
+		s.gl.BindTexture(glpkg.Texture2D, uint32(texture2D))
+		s.gl.ActiveTexture(uint32(activeTexture))
+	}()
+
 	s.gl.UseProgram(s.shaderProgram)
 	s.gl.UniformMatrix4fv(s.projUniform, 1, false, &proj[0])
 	s.gl.BindVertexArray(s.vao)
@@ -504,7 +520,6 @@ func (s *Stash) FlushDraw() {
 	tt := true
 	for {
 		if texture.nverts > 0 {
-			s.gl.ActiveTexture(glpkg.Texture0)
 			s.gl.BindTexture(glpkg.Texture2D, texture.id)
 			texUniform := s.gl.GetUniformLocation(s.shaderProgram, "u_texture")
 			s.gl.Uniform1i(texUniform, 0)
@@ -547,6 +562,9 @@ func (s *Stash) FlushDraw() {
 			}
 
 			s.gl.BindBuffer(glpkg.ArrayBuffer, s.vbo)
+			// Orphan the previous contents so the upload doesn't wait for the
+			// GPU to finish drawing the last batch.
+			s.gl.BufferData(glpkg.ArrayBuffer, VERT_COUNT*8*4, nil, glpkg.StreamDraw)
 			s.gl.BufferSubData(glpkg.ArrayBuffer, 0, len(vertices)*4, unsafe.Pointer(&vertices[0]))
 
 			s.gl.DrawArrays(glpkg.Triangles, 0, int32(vertexCount))
@@ -575,10 +593,6 @@ func (s *Stash) FlushDraw() {
 		}
 	}
 
-	// Restore graphics shader program after all text rendering is complete
-	if s.graphicsShader != 0 {
-		s.gl.UseProgram(s.graphicsShader)
-	}
 }
 
 func (s *Stash) BeginDraw() {
@@ -0,0 +1,155 @@
+package text
+
+import (
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tinyrange/gowin/internal/graphics"
+)
+
+// Rect is a box in the coordinate space text is drawn in.
+type Rect struct {
+	X, Y          float32
+	Width, Height float32
+}
+
+// Align is the horizontal alignment of each line within a box.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// VAlign is the vertical alignment of the block of lines within a box.
+type VAlign int
+
+const (
+	VAlignTop VAlign = iota
+	VAlignMiddle
+	VAlignBottom
+)
+
+// LayoutOptions configures RenderTextBox. The zero value draws white,
+// top-left aligned text with the font's natural line spacing.
+type LayoutOptions struct {
+	// Color defaults to white.
+	Color color.Color
+	Align Align
+	// VAlign also decides which lines are kept when the text doesn't fit:
+	// the last ones for VAlignBottom, as a log panel wants, otherwise the
+	// first ones.
+	VAlign VAlign
+	// LineSpacing multiplies the font's line height. Zero means 1.
+	LineSpacing float32
+}
+
+// RenderTextBox draws s inside rect, wrapping lines at spaces (or anywhere
+// in words too long for a line on their own) and honouring explicit
+// newlines. Only whole lines that fit in the box are drawn. It returns the
+// height of the lines drawn and whether any had to be left out.
+func (r *Renderer) RenderTextBox(rect Rect, s string, size float64, opts LayoutOptions) (height float32, overflowed bool) {
+	if r == nil || r.stash == nil {
+		return 0, false
+	}
+	r.followWindow()
+
+	ascender, _, lineHeight := r.stash.VMetrics(r.font, size)
+	lh := float32(lineHeight)
+	if opts.LineSpacing > 0 {
+		lh *= opts.LineSpacing
+	}
+	if lh <= 0 {
+		return 0, false
+	}
+
+	lines := r.wrap(s, size, rect.Width)
+	fit := max(int(rect.Height/lh), 0)
+	if len(lines) > fit {
+		overflowed = true
+		if opts.VAlign == VAlignBottom {
+			lines = lines[len(lines)-fit:]
+		} else {
+			lines = lines[:fit]
+		}
+	}
+	height = lh * float32(len(lines))
+
+	y := rect.Y
+	switch opts.VAlign {
+	case VAlignMiddle:
+		y += (rect.Height - height) / 2
+	case VAlignBottom:
+		y += rect.Height - height
+	}
+
+	c := opts.Color
+	if c == nil {
+		c = color.White
+	}
+	rgba := graphics.ColorToFloat32(c)
+	r.win.Flush()
+	r.stash.BeginDraw()
+	for i, line := range lines {
+		x := rect.X
+		if opts.Align != AlignLeft {
+			free := rect.Width - r.advance(line, size)
+			if opts.Align == AlignCenter {
+				free /= 2
+			}
+			x += free
+		}
+		baseline := y + float32(i)*lh + float32(ascender)
+		r.stash.DrawText(r.font, size, float64(x), float64(baseline), line, rgba)
+	}
+	r.stash.EndDraw()
+	return height, overflowed
+}
+
+// wrap splits s into lines no wider than width.
+func (r *Renderer) wrap(s string, size float64, width float32) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && r.advance(line+" "+word, size) <= width {
+				line += " " + word
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Break words that don't fit on a line of their own.
+			for word != "" && r.advance(word, size) > width {
+				n := r.fitRunes(word, size, width)
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fitRunes returns the byte length of the longest prefix of word that fits
+// in width, and always at least one rune so wrapping makes progress.
+func (r *Renderer) fitRunes(word string, size float64, width float32) int {
+	n := 0
+	for i := range word {
+		if i > 0 && r.advance(word[:i], size) > width {
+			break
+		}
+		n = i
+	}
+	if n == 0 {
+		_, n = utf8.DecodeRuneInString(word)
+	}
+	return n
+}
+
+func (r *Renderer) advance(s string, size float64) float32 {
+	return float32(r.stash.GetAdvance(r.font, size, s))
+}
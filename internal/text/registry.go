@@ -0,0 +1,47 @@
+package text
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultFont is the name of the embedded font used by Load.
+const DefaultFont = "Roboto Mono"
+
+var (
+	fontsMu sync.RWMutex
+	fonts   = map[string][]byte{
+		DefaultFont: EMBEDDED_FONT,
+	}
+)
+
+// RegisterFont makes TrueType font data available to LoadNamed under name,
+// replacing any font already registered with that name.
+func RegisterFont(name string, data []byte) {
+	fontsMu.Lock()
+	defer fontsMu.Unlock()
+	fonts[name] = data
+}
+
+// Fonts returns the names of all registered fonts in sorted order.
+func Fonts() []string {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+	names := make([]string, 0, len(fonts))
+	for name := range fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupFont(name string) ([]byte, error) {
+	fontsMu.RLock()
+	defer fontsMu.RUnlock()
+	data, ok := fonts[name]
+	if !ok {
+		return nil, fmt.Errorf("text: unknown font %q", name)
+	}
+	return data, nil
+}
@@ -1,8 +1,13 @@
+// Package text draws TrueType text on a graphics.Window. Renderer is the
+// package's only text API: it rasterizes glyphs on demand into a shared
+// atlas (Stash) and draws them in the same coordinate space as RenderQuad.
+// Fonts other than the embedded default are added with RegisterFont.
 package text
 
 import (
 	_ "embed"
 	"image/color"
+	"strings"
 
 	"github.com/tinyrange/gowin/internal/graphics"
 )
@@ -11,13 +16,27 @@ import (
 var EMBEDDED_FONT []byte
 
 type Renderer struct {
-	stash          *Stash
-	font           int
-	scale          float32
-	graphicsShader uint32
+	win   graphics.Window
+	stash *Stash
+	font  int
+
+	// manual is set once SetViewport is called; until then the viewport
+	// follows the window on every draw.
+	manual bool
 }
 
+// Load returns a Renderer using DefaultFont.
 func Load(win graphics.Window) (*Renderer, error) {
+	return LoadNamed(win, DefaultFont)
+}
+
+// LoadNamed returns a Renderer using a font added with RegisterFont.
+func LoadNamed(win graphics.Window, name string) (*Renderer, error) {
+	data, err := lookupFont(name)
+	if err != nil {
+		return nil, err
+	}
+
 	gl, err := win.PlatformWindow().GL()
 	if err != nil {
 		return nil, err
@@ -25,16 +44,15 @@ func Load(win graphics.Window) (*Renderer, error) {
 
 	stash := New(gl, 1024, 1024)
 	stash.SetYInverted(true)
-	fontIdx, err := stash.AddFontFromMemory(EMBEDDED_FONT)
+	fontIdx, err := stash.AddFontFromMemory(data)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Renderer{
-		stash:          stash,
-		font:           fontIdx,
-		scale:          win.Scale(),
-		graphicsShader: win.GetShaderProgram(),
+		win:   win,
+		stash: stash,
+		font:  fontIdx,
 	}, nil
 }
 
@@ -43,6 +61,8 @@ func (r *Renderer) RenderText(s string, x, y float32, size float64, c color.Colo
 		return x
 	}
 
+	r.followWindow()
+	r.win.Flush()
 	r.stash.BeginDraw()
 	rgba := graphics.ColorToFloat32(c)
 	next := r.stash.DrawText(r.font, size, float64(x), float64(y), s, rgba)
@@ -50,13 +70,54 @@ func (r *Renderer) RenderText(s string, x, y float32, size float64, c color.Colo
 	return float32(next)
 }
 
+// Measure returns the size of s as RenderText draws it at size: the width of
+// its widest line and the line height times the number of lines. Use it to
+// center or right-align text.
+func (r *Renderer) Measure(s string, size float64) (width, height float32) {
+	if r == nil || r.stash == nil || s == "" {
+		return 0, 0
+	}
+	r.followWindow()
+	_, _, lineHeight := r.stash.VMetrics(r.font, size)
+	lines := strings.Split(s, "\n")
+	for _, line := range lines {
+		width = max(width, float32(r.stash.GetAdvance(r.font, size, line)))
+	}
+	return width, float32(lineHeight) * float32(len(lines))
+}
+
+// followWindow lays text out against the window's current ViewSize and
+// scale, unless SetViewport has taken over.
+func (r *Renderer) followWindow() {
+	if r.manual {
+		return
+	}
+	w, h := r.win.ViewSize()
+	r.stash.SetViewport(int32(w), int32(h))
+	r.stash.SetScale(r.win.Scale())
+}
+
+// SetViewport sets the window size in physical pixels that text is laid out
+// against. When it matches the window, text uses the window's ViewSize so it
+// shares the coordinate space of RenderQuad, including any logical size.
+//
+// It is rarely needed: by default the Renderer follows the window it was
+// loaded for. Once called, the viewport is only changed by further calls.
 func (r *Renderer) SetViewport(width, height int32) {
 	if r != nil && r.stash != nil {
-		// Apply scale factor to match the graphics system's coordinate system
-		scaledWidth := float32(width) / r.scale
-		scaledHeight := float32(height) / r.scale
+		r.manual = true
+		// Apply scale factor to match the graphics system's coordinate system.
+		// The scale is read each time as it changes when the window moves
+		// between displays.
+		scale := r.win.Scale()
+		scaledWidth := float32(width) / scale
+		scaledHeight := float32(height) / scale
+		// Given the window size, follow the graphics coordinate space, which
+		// differs from it under SetLogicalSize.
+		if bw, bh := r.win.PlatformWindow().BackingSize(); int32(bw) == width && int32(bh) == height {
+			scaledWidth, scaledHeight = r.win.ViewSize()
+		}
 		r.stash.SetViewport(int32(scaledWidth), int32(scaledHeight))
-		r.stash.SetScale(r.scale)
-		r.stash.SetGraphicsShader(r.graphicsShader)
+		r.stash.SetScale(scale)
 	}
 }
@@ -0,0 +1,133 @@
+package text
+
+import (
+	"testing"
+	"unsafe"
+
+	glpkg "github.com/tinyrange/gowin/internal/gl"
+	"github.com/tinyrange/gowin/internal/graphics"
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+// drawLog records the order things reach the screen in.
+type drawLog []string
+
+// fakeGL implements the calls the font stash makes, appending "text" to
+// the log for each draw. Anything else panics through the nil embedded
+// interface.
+type fakeGL struct {
+	glpkg.OpenGL
+	log  *drawLog
+	next uint32
+}
+
+func (g *fakeGL) name() uint32 { g.next++; return g.next }
+
+func (g *fakeGL) gen(n int32, names *uint32) {
+	s := unsafe.Slice(names, n)
+	for i := range s {
+		s[i] = g.name()
+	}
+}
+
+func (g *fakeGL) GenTextures(n int32, textures *uint32)   { g.gen(n, textures) }
+func (g *fakeGL) GenBuffers(n int32, buffers *uint32)     { g.gen(n, buffers) }
+func (g *fakeGL) GenVertexArrays(n int32, arrays *uint32) { g.gen(n, arrays) }
+func (g *fakeGL) CreateShader(xtype uint32) uint32        { return g.name() }
+func (g *fakeGL) CreateProgram() uint32                   { return g.name() }
+func (g *fakeGL) GetShaderiv(shader, pname uint32, params *int32) {
+	*params = 1
+}
+func (g *fakeGL) GetProgramiv(program, pname uint32, params *int32) {
+	*params = 1
+}
+func (g *fakeGL) GetIntegerv(pname uint32, data *int32)                { *data = 0 }
+func (g *fakeGL) GetAttribLocation(program uint32, name string) int32  { return 0 }
+func (g *fakeGL) GetUniformLocation(program uint32, name string) int32 { return 0 }
+
+func (g *fakeGL) ShaderSource(shader uint32, source string)       {}
+func (g *fakeGL) CompileShader(shader uint32)                     {}
+func (g *fakeGL) AttachShader(program, shader uint32)             {}
+func (g *fakeGL) LinkProgram(program uint32)                      {}
+func (g *fakeGL) DeleteShader(shader uint32)                      {}
+func (g *fakeGL) DeleteProgram(program uint32)                    {}
+func (g *fakeGL) UseProgram(program uint32)                       {}
+func (g *fakeGL) ActiveTexture(texture uint32)                    {}
+func (g *fakeGL) BindTexture(target, texture uint32)              {}
+func (g *fakeGL) BindBuffer(target, buffer uint32)                {}
+func (g *fakeGL) BindVertexArray(array uint32)                    {}
+func (g *fakeGL) EnableVertexAttribArray(index uint32)            {}
+func (g *fakeGL) TexParameteri(target, pname uint32, param int32) {}
+func (g *fakeGL) PixelStorei(pname uint32, param int32)           {}
+func (g *fakeGL) Uniform1i(location int32, v0 int32)              {}
+func (g *fakeGL) UniformMatrix4fv(location, count int32, transpose bool, value *float32) {
+}
+func (g *fakeGL) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset uintptr) {
+}
+func (g *fakeGL) BufferData(target uint32, size int, data unsafe.Pointer, usage uint32) {}
+func (g *fakeGL) BufferSubData(target uint32, offset, size int, data unsafe.Pointer)    {}
+func (g *fakeGL) TexImage2D(target uint32, level, internalformat, width, height, border int32, format, xtype uint32, pixels unsafe.Pointer) {
+}
+func (g *fakeGL) TexSubImage2D(target uint32, level, xoffset, yoffset, width, height int32, format, xtype uint32, pixels unsafe.Pointer) {
+}
+
+func (g *fakeGL) DrawArrays(mode uint32, first, count int32) {
+	*g.log = append(*g.log, "text")
+}
+
+type fakePlatform struct {
+	window.Window
+	gl *fakeGL
+}
+
+func (p *fakePlatform) GL() (glpkg.OpenGL, error)        { return p.gl, nil }
+func (p *fakePlatform) BackingSize() (width, height int) { return 800, 600 }
+
+// fakeWindow stands in for a graphics window with a quad queued, as
+// drawing a box behind the text leaves it, which only reaches the screen
+// when the window is flushed.
+type fakeWindow struct {
+	graphics.Window
+	platform *fakePlatform
+	log      *drawLog
+	queued   bool
+}
+
+func (w *fakeWindow) PlatformWindow() window.Window     { return w.platform }
+func (w *fakeWindow) ViewSize() (width, height float32) { return 800, 600 }
+func (w *fakeWindow) Scale() float32                    { return 1 }
+
+func (w *fakeWindow) Flush() {
+	if w.queued {
+		*w.log = append(*w.log, "background")
+		w.queued = false
+	}
+}
+
+func TestTextDrawnOverQueuedQuads(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(*Renderer)
+	}{
+		{"RenderText", func(r *Renderer) { r.RenderText("hello", 10, 20, 16, graphics.ColorWhite) }},
+		{"RenderTextBox", func(r *Renderer) {
+			r.RenderTextBox(Rect{X: 10, Y: 10, Width: 200, Height: 100}, "hello", 16, LayoutOptions{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log drawLog
+			win := &fakeWindow{platform: &fakePlatform{gl: &fakeGL{log: &log}}, log: &log}
+			r, err := Load(win)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			win.queued = true
+			tt.render(r)
+			if len(log) < 2 || log[0] != "background" || log[1] != "text" {
+				t.Errorf("drawn in order %v, want the background before the text", log)
+			}
+		})
+	}
+}
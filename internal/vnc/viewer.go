@@ -0,0 +1,456 @@
+// Package vnc is a VNC viewer component: it connects to a server with the
+// rfb package, shows the remote desktop scaled to fit a graphics.Window and
+// forwards the window's pointer and keyboard input.
+package vnc
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tinyrange/gowin/internal/graphics"
+	"github.com/tinyrange/gowin/internal/rfb"
+	"github.com/tinyrange/gowin/internal/text"
+	"github.com/tinyrange/gowin/internal/window"
+)
+
+// Viewer shows one VNC session. Create it with NewViewer and call Render
+// every frame; until the server's desktop arrives it draws the connection
+// progress or error instead.
+//
+// F8, the customary VNC viewer key, is kept for the viewer rather than
+// forwarded: it toggles smooth scaling of the desktop.
+type Viewer struct {
+	gfx  graphics.Window
+	font *text.Renderer
+
+	// mu guards everything below, which the connection goroutines update.
+	mu         sync.Mutex
+	conn       *rfb.Connection
+	connecting bool
+	progress   float32
+	err        error
+	serverName string
+	fbSize     image.Point // zero until the ConnectedEvent
+	dirty      bool        // conn's framebuffer changed since the upload
+
+	// Only touched by Render.
+	fbTexture graphics.StreamingTexture
+	smooth    bool
+
+	// Input the server believes is held, to let go of when focus is lost.
+	heldKeys    map[uint32]bool
+	heldButtons rfb.Buttons
+	pointerX    uint16
+	pointerY    uint16
+}
+
+// keysyms maps the non-alphanumeric keys forwarded to the server to X11
+// keysyms.
+var keysyms = map[window.Key]uint32{
+	window.KeySpace:     0x0020,
+	window.KeyEnter:     0xFF0D,
+	window.KeyEscape:    0xFF1B,
+	window.KeyBackspace: 0xFF08,
+	window.KeyTab:       0xFF09,
+	window.KeyUp:        0xFF52,
+	window.KeyDown:      0xFF54,
+	window.KeyLeft:      0xFF51,
+	window.KeyRight:     0xFF53,
+	window.KeyF1:        0xFFBE,
+	window.KeyF2:        0xFFBF,
+	window.KeyF3:        0xFFC0,
+	window.KeyF4:        0xFFC1,
+	window.KeyF5:        0xFFC2,
+	window.KeyF6:        0xFFC3,
+	window.KeyF7:        0xFFC4,
+	window.KeyF9:        0xFFC6,
+	window.KeyF10:       0xFFC7,
+	window.KeyF11:       0xFFC8,
+	window.KeyF12:       0xFFC9,
+}
+
+// hotkeySmooth toggles smooth scaling. It isn't forwarded to the server.
+const hotkeySmooth = window.KeyF8
+
+// NewViewer starts connecting to the VNC server at addr, a host:port with
+// the host defaulting to localhost, and returns a Viewer drawing into gfx.
+// Connection failures are shown by Render rather than returned.
+func NewViewer(gfx graphics.Window, addr string) (*Viewer, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+
+	font, err := text.Load(gfx)
+	if err != nil {
+		return nil, err
+	}
+
+	if gfx.IsSoftwareRenderer() {
+		log.Printf("Warning: OpenGL is rendering in software (%s); updates will be slow", gfx.Renderer())
+	} else if gfx.IsIntegratedGPU() {
+		log.Printf("Rendering on integrated GPU %s (%s)", gfx.Renderer(), gfx.Vendor())
+	}
+
+	v := &Viewer{
+		gfx:        gfx,
+		font:       font,
+		connecting: true,
+		smooth:     true,
+		heldKeys:   make(map[uint32]bool),
+	}
+	go v.connect(net.JoinHostPort(host, port))
+	return v, nil
+}
+
+// SetSmooth selects between the default linear filtering when the desktop
+// is scaled and nearest-neighbour filtering, which keeps it crisp at whole
+// multiples of its size. Like Render, it must be called on the window's
+// goroutine.
+func (v *Viewer) SetSmooth(smooth bool) {
+	v.smooth = smooth
+	if v.fbTexture != nil {
+		v.fbTexture.SetFilter(v.filter(), v.filter())
+	}
+}
+
+func (v *Viewer) filter() graphics.Filter {
+	return v.textureOptions().Filter
+}
+
+func (v *Viewer) textureOptions() graphics.TextureOptions {
+	if v.smooth {
+		return graphics.TextureOptionsVideo
+	}
+	return graphics.TextureOptions{Filter: graphics.FilterNearest}
+}
+
+// Close disconnects from the server.
+func (v *Viewer) Close() error {
+	v.mu.Lock()
+	conn := v.conn
+	v.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+func (v *Viewer) connect(addr string) {
+	// Dialing gives no progress of its own, so the bar creeps up over a
+	// second while it runs.
+	dialed := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; i < 100; i++ {
+			select {
+			case <-dialed:
+				return
+			case <-ticker.C:
+			}
+			v.mu.Lock()
+			v.progress = float32(i) / 100
+			v.mu.Unlock()
+		}
+	}()
+
+	netConn, err := net.Dial("tcp", addr)
+	close(dialed)
+	if err != nil {
+		v.fail(fmt.Errorf("failed to connect: %v", err))
+		return
+	}
+
+	conn, err := rfb.NewConn(netConn)
+	if err != nil {
+		v.fail(fmt.Errorf("failed to initialize RFB: %v", err))
+		return
+	}
+
+	// There's no point decoding frames faster than the display shows them.
+	conn.SetMaxUpdateRate(60)
+	conn.SetMaxPointerRate(60)
+	// Idle sessions otherwise get dropped silently by NATs.
+	conn.SetKeepAlive(30 * time.Second)
+
+	v.mu.Lock()
+	v.conn = conn
+	v.progress = 1
+	v.connecting = false
+	v.mu.Unlock()
+
+	v.processEvents(conn)
+}
+
+// fail records err for Render to show. Only the first error is kept.
+func (v *Viewer) fail(err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.connecting = false
+	if v.err == nil {
+		v.err = err
+	}
+}
+
+func (v *Viewer) processEvents(conn *rfb.Connection) {
+	for evt := range conn.Events {
+		switch e := evt.(type) {
+		case *rfb.ConnectedEvent:
+			v.mu.Lock()
+			v.serverName = e.Name
+			v.fbSize = image.Pt(int(e.FrameBufferWidth), int(e.FrameBufferHeight))
+			v.dirty = true
+			v.mu.Unlock()
+			if err := conn.RequestUpdate(false); err != nil {
+				log.Printf("Failed to request update: %v", err)
+			}
+
+		case *rfb.FrameBufferUpdateEvent:
+			// The connection has already applied the rectangles to its
+			// framebuffer; Render fetches it once per frame however many
+			// updates arrive in between.
+			v.mu.Lock()
+			v.dirty = true
+			v.mu.Unlock()
+			if err := conn.RequestUpdate(true); err != nil {
+				log.Printf("Failed to request update: %v", err)
+			}
+
+		case *rfb.BellEvent:
+			log.Printf("Bell from %s", v.serverName)
+
+		case *rfb.ErrorEvent:
+			log.Printf("RFB error: %v", e)
+			v.fail(e)
+
+		case *rfb.DisconnectedEvent:
+			v.fail(fmt.Errorf("disconnected from %s", v.serverName))
+		}
+	}
+}
+
+// Render draws the remote desktop, or the connection status, filling the
+// frame, and forwards the frame's input to the server.
+func (v *Viewer) Render(f graphics.Frame) {
+	// Everything drawn lays out in RenderQuad's logical coordinates.
+	w, h := f.WindowSizeLogical()
+
+	v.mu.Lock()
+	connecting, progress, err := v.connecting, v.progress, v.err
+	waiting := v.fbSize == image.Point{}
+	v.mu.Unlock()
+
+	switch {
+	case connecting:
+		v.renderLoading(f, w, h, progress)
+	case err != nil:
+		v.centerText(fmt.Sprintf("Error: %v", err), w, h, graphics.ColorRed)
+	case waiting:
+		v.centerText("Waiting for server...", w, h, graphics.ColorWhite)
+	default:
+		v.renderDesktop(f, w, h)
+		// Don't send the remote pointer motion or keys meant for another
+		// window after the user switches away.
+		if f.IsFocused() {
+			v.handleInput(f)
+		} else {
+			v.releaseHeld()
+		}
+	}
+}
+
+func (v *Viewer) renderLoading(f graphics.Frame, w, h int, progress float32) {
+	barWidth := float32(w) * 0.6
+	barHeight := float32(40)
+	barX := (float32(w) - barWidth) / 2
+	barY := float32(h)/2 - barHeight/2
+	radius := barHeight / 2
+
+	// A subtle vertical gradient behind the bar.
+	top := color.RGBA{R: 36, G: 40, B: 52, A: 255}
+	bottom := color.RGBA{R: 16, G: 16, B: 20, A: 255}
+	f.RenderQuadGradient(0, 0, float32(w), float32(h), top, top, bottom, bottom)
+
+	f.FillRoundedRect(barX, barY, barWidth, barHeight, radius, graphics.ColorDarkGray)
+	if fill := barWidth * progress; fill > 0 {
+		// Keep the fill at least as wide as its rounded ends.
+		f.FillRoundedRect(barX, barY, max(fill, barHeight), barHeight, radius, graphics.ColorBlue)
+	}
+
+	v.font.RenderText(fmt.Sprintf("Connecting... %.0f%%", progress*100), barX, barY-30, 20, graphics.ColorWhite)
+}
+
+func (v *Viewer) centerText(s string, w, h int, c color.Color) {
+	tw, _ := v.font.Measure(s, 24)
+	v.font.RenderText(s, (float32(w)-tw)/2, float32(h)/2, 24, c)
+}
+
+func (v *Viewer) renderDesktop(f graphics.Frame, w, h int) {
+	v.mu.Lock()
+	if v.dirty || v.fbTexture == nil {
+		if err := v.uploadLocked(); err != nil {
+			v.mu.Unlock()
+			// Retrying every frame won't help (e.g. the framebuffer is
+			// larger than the GPU's maximum texture size), so surface it.
+			v.fail(err)
+			return
+		}
+	}
+	size := v.fbSize
+	v.mu.Unlock()
+
+	x, y, scale := fit(size, w, h)
+	f.RenderQuad(x, y, float32(size.X)*scale, float32(size.Y)*scale, v.fbTexture, graphics.ColorWhite)
+}
+
+// uploadLocked uploads the connection's framebuffer to the texture,
+// replacing the texture if the framebuffer's size changed. v.mu must be
+// held.
+func (v *Viewer) uploadLocked() error {
+	var err error
+	// The framebuffer is already in RGBA order with opaque alpha, so it
+	// goes to the texture as is, straight from the connection's copy.
+	ok := v.conn.WithFramebuffer(func(fb *image.RGBA) {
+		size := fb.Bounds().Size()
+		if v.fbTexture == nil || !sizeMatches(v.fbTexture, size) {
+			if v.fbTexture != nil {
+				v.fbTexture.Delete()
+				v.fbTexture = nil
+			}
+			tex, texErr := v.gfx.NewStreamingTexture(size.X, size.Y, v.textureOptions())
+			if texErr != nil {
+				err = fmt.Errorf("failed to create texture: %v", texErr)
+				return
+			}
+			v.fbTexture = tex
+		}
+		if updateErr := v.fbTexture.UpdateRaw(graphics.PixelFormatRGBA, fb.Pix); updateErr != nil {
+			err = fmt.Errorf("failed to update texture: %v", updateErr)
+			return
+		}
+		v.fbSize = size
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no framebuffer before the connection is set up")
+	}
+	v.dirty = false
+	return nil
+}
+
+func sizeMatches(tex graphics.Texture, size image.Point) bool {
+	w, h := tex.Size()
+	return w == size.X && h == size.Y
+}
+
+// fit scales a framebuffer of size fb to fit a w x h window, keeping its
+// aspect ratio, and centers it. It returns the top-left corner and the
+// scale. The window doesn't resize to the remote desktop, so this is used
+// both to draw and to map the pointer back, in whatever unit w and h are.
+func fit(fb image.Point, w, h int) (x, y, scale float32) {
+	scale = min(float32(w)/float32(fb.X), float32(h)/float32(fb.Y))
+	x = (float32(w) - float32(fb.X)*scale) / 2
+	y = (float32(h) - float32(fb.Y)*scale) / 2
+	return x, y, scale
+}
+
+func (v *Viewer) handleInput(f graphics.Frame) {
+	v.mu.Lock()
+	conn := v.conn
+	size := v.fbSize
+	v.mu.Unlock()
+
+	// Hit-test in physical pixels, the unit of both WindowSize and
+	// CursorPosPixels.
+	w, h := f.WindowSize()
+	mouseX, mouseY := f.CursorPosPixels()
+	x, y, scale := fit(size, w, h)
+	if f.MouseInWindow() && mouseX >= x && mouseX <= x+float32(size.X)*scale &&
+		mouseY >= y && mouseY <= y+float32(size.Y)*scale {
+		var buttons rfb.Buttons
+		if f.ButtonDown(window.ButtonLeft) {
+			buttons.Set(rfb.ButtonLeft)
+		}
+		if f.ButtonDown(window.ButtonRight) {
+			buttons.Set(rfb.ButtonRight)
+		}
+		if f.ButtonDown(window.ButtonMiddle) {
+			buttons.Set(rfb.ButtonMiddle)
+		}
+
+		v.heldButtons = buttons
+		v.pointerX = uint16(min((mouseX-x)/scale, float32(size.X-1)))
+		v.pointerY = uint16(min((mouseY-y)/scale, float32(size.Y-1)))
+		if err := conn.SendPointerIfChanged(buttons, v.pointerX, v.pointerY); err != nil {
+			log.Printf("Failed to send pointer event: %v", err)
+		}
+	}
+
+	if f.KeyJustPressed(hotkeySmooth) {
+		v.SetSmooth(!v.smooth)
+	}
+	v.handleKeyboard(f, conn)
+}
+
+// releaseHeld sends the server a release for every key and button it was
+// last told is down. Those releases happen in another window and would
+// otherwise never be forwarded.
+func (v *Viewer) releaseHeld() {
+	if len(v.heldKeys) == 0 && v.heldButtons == 0 {
+		return
+	}
+	v.mu.Lock()
+	conn := v.conn
+	v.mu.Unlock()
+
+	for keysym := range v.heldKeys {
+		if err := conn.SendKeyEvent(false, keysym); err != nil {
+			log.Printf("Failed to send key event: %v", err)
+		}
+		delete(v.heldKeys, keysym)
+	}
+	if v.heldButtons != 0 {
+		v.heldButtons = 0
+		if err := conn.SendPointerIfChanged(0, v.pointerX, v.pointerY); err != nil {
+			log.Printf("Failed to send pointer event: %v", err)
+		}
+	}
+}
+
+func (v *Viewer) handleKeyboard(f graphics.Frame, conn *rfb.Connection) {
+	send := func(key window.Key, keysym uint32) {
+		var err error
+		switch {
+		case f.KeyJustPressed(key):
+			v.heldKeys[keysym] = true
+			err = conn.SendKeyEvent(true, keysym)
+		case f.KeyJustReleased(key):
+			delete(v.heldKeys, keysym)
+			err = conn.SendKeyEvent(false, keysym)
+		}
+		if err != nil {
+			log.Printf("Failed to send key event: %v", err)
+		}
+	}
+
+	for key, keysym := range keysyms {
+		send(key, keysym)
+	}
+	for key := window.KeyA; key <= window.KeyZ; key++ {
+		send(key, uint32('a'+(key-window.KeyA)))
+	}
+	for key := window.Key0; key <= window.Key9; key++ {
+		send(key, uint32('0'+(key-window.Key0)))
+	}
+}
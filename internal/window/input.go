@@ -129,6 +129,32 @@ const (
 	KeyNumpadEqual // =
 )
 
+// Modifiers is a set of modifier keys, for keyboard shortcuts. The left
+// and right keys of each kind count the same.
+type Modifiers int
+
+const (
+	ModShift Modifiers = 1 << iota
+	ModControl
+	ModAlt
+	ModSuper // Windows key on Windows, Command key on macOS
+)
+
+// Modifier returns the modifier k is a key for, or 0 if it isn't one.
+func (k Key) Modifier() Modifiers {
+	switch k {
+	case KeyLeftShift, KeyRightShift:
+		return ModShift
+	case KeyLeftControl, KeyRightControl:
+		return ModControl
+	case KeyLeftAlt, KeyRightAlt:
+		return ModAlt
+	case KeyLeftSuper, KeyRightSuper:
+		return ModSuper
+	}
+	return 0
+}
+
 // Button represents a mouse button.
 type Button int
 
@@ -1,15 +1,263 @@
 package window
 
-import "github.com/tinyrange/gowin/internal/gl"
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/tinyrange/gowin/internal/gl"
+)
+
+// ErrNoDisplay is returned by New when there is no display server to
+// connect to, or the libraries needed to talk to it are missing, as is
+// usual over SSH and in CI.
+var ErrNoDisplay = errors.New("window: no display available")
+
+// ErrNoGL is returned by New when the system OpenGL library is missing.
+var ErrNoGL = errors.New("window: OpenGL library not available")
+
+// ErrWaylandOnly is returned by New on Linux when the session is Wayland
+// and no X server (XWayland) is available. There is no native Wayland
+// backend yet.
+var ErrWaylandOnly = errors.New("window: Wayland session without XWayland (DISPLAY is unset); only X11 is supported")
+
+// Options configures the window and GL context created by New.
+type Options struct {
+	// CoreProfile requests an OpenGL core profile context where the platform
+	// distinguishes between core and legacy profiles.
+	CoreProfile bool
+
+	// GLMajor and GLMinor request a specific OpenGL version. Zero means the
+	// platform default: 3.0 on Linux and Windows, and on macOS 4.1 core or
+	// legacy depending on CoreProfile. Profiles only exist from 3.2, so
+	// CoreProfile has no effect on lower versions.
+	GLMajor int
+	GLMinor int
+
+	// Samples is the number of samples per pixel to request for multisample
+	// anti-aliasing. Zero disables multisampling. If no multisampled format
+	// is available the window falls back to a single-sampled one.
+	Samples int
+
+	// Transparent requests a framebuffer with an alpha channel that the
+	// desktop compositor blends with what is behind the window, so only
+	// what is drawn shows. Clear to a transparent color to use it. It has no
+	// effect without a compositor.
+	Transparent bool
+
+	// StartHidden creates the window without showing it, so it can be set
+	// up before appearing. Call Show when ready.
+	StartHidden bool
+
+	// Monitor, if non-nil, is the monitor to open the window on. The window
+	// is centred within its bounds.
+	Monitor *Monitor
+}
+
+// glVersion returns the requested GL version, or major.minor if none was.
+func (o Options) glVersion(major, minor int) (int, int) {
+	if o.GLMajor > 0 {
+		return o.GLMajor, o.GLMinor
+	}
+	return major, minor
+}
+
+// clampOpacity limits opacity to [0, 1].
+func clampOpacity(opacity float32) float32 {
+	return max(0, min(1, opacity))
+}
+
+// Monitor describes a display attached to the desktop, as returned by
+// Monitors. Bounds are in desktop coordinates with the origin at the
+// top-left of the primary monitor: pixels on Linux and Windows, points on
+// macOS.
+type Monitor struct {
+	Name    string
+	X       int
+	Y       int
+	Width   int
+	Height  int
+	Scale   float32
+	Primary bool
+
+	// Platform handle used by Modes: the XRandR output on Linux and the
+	// CGDirectDisplayID on macOS. Windows identifies monitors by Name.
+	id uintptr
+}
+
+// center returns the top-left corner that centres a width x height window
+// on m.
+func (m *Monitor) center(width, height int) (x, y int) {
+	return m.X + (m.Width-width)/2, m.Y + (m.Height-height)/2
+}
+
+// DisplayMode is a resolution and refresh rate a monitor can be switched
+// to with Window.SetFullscreenMode, as returned by Monitor.Modes. Width and
+// Height are in the same units as the monitor's bounds.
+type DisplayMode struct {
+	Width       int
+	Height      int
+	RefreshRate float64 // Hz, or zero if unknown
+
+	// The monitor the mode belongs to and the platform's ID for the mode:
+	// the XRandR mode on Linux, the IOKit mode ID on macOS and the bit
+	// depth on Windows.
+	monitor Monitor
+	id      uintptr
+}
+
+// Modes returns the display modes m supports, largest and fastest first.
+// Modes that differ only in timings the caller can't choose between are
+// listed once.
+func (m *Monitor) Modes() ([]DisplayMode, error) {
+	modes, err := monitorModes(m)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(modes, func(i, j int) bool {
+		a, b := modes[i], modes[j]
+		if a.Width*a.Height != b.Width*b.Height {
+			return a.Width*a.Height > b.Width*b.Height
+		}
+		if a.Width != b.Width {
+			return a.Width > b.Width
+		}
+		return a.RefreshRate > b.RefreshRate
+	})
+	out := modes[:0]
+	for _, mode := range modes {
+		if n := len(out); n > 0 && sameMode(out[n-1], mode) {
+			continue
+		}
+		out = append(out, mode)
+	}
+	return out, nil
+}
+
+// sameMode reports whether a and b look the same to the user: the same
+// size and a refresh rate within rounding.
+func sameMode(a, b DisplayMode) bool {
+	d := a.RefreshRate - b.RefreshRate
+	return a.Width == b.Width && a.Height == b.Height && d < 0.5 && d > -0.5
+}
+
+// DisplayInfo describes the monitor a window is on.
+type DisplayInfo struct {
+	// Width and Height are the monitor resolution in physical pixels.
+	Width  int
+	Height int
+	// WidthMM and HeightMM are the physical size reported by the monitor,
+	// or zero if it is unknown. Some monitors and projectors report
+	// nonsense here.
+	WidthMM  int
+	HeightMM int
+	// DPI is the horizontal pixel density computed from the above, or zero
+	// if the physical size is unknown.
+	DPI float32
+}
+
+// computeDPI fills in d.DPI from its pixel and millimetre widths.
+func (d DisplayInfo) computeDPI() DisplayInfo {
+	if d.WidthMM > 0 {
+		d.DPI = float32(d.Width) / float32(d.WidthMM) * 25.4
+	}
+	return d
+}
 
 type Window interface {
 	GL() (gl.OpenGL, error)
 	Close()
 	Poll() bool
+	// Wait blocks until an event is ready for Poll or Wake is called.
+	Wait()
+	// Wake makes a blocked Wait return. It is safe to call from any goroutine.
+	Wake()
 	Swap()
+	// Show and Hide change whether the window is on screen. Closing the
+	// window is still reported by Poll, hidden or not.
+	Show()
+	Hide()
+	// SetOpacity sets the opacity of the whole window, from 0 (invisible)
+	// to 1 (opaque). On Linux it needs a compositing window manager.
+	SetOpacity(opacity float32)
 	BackingSize() (width, height int)
+	// Cursor returns the mouse position relative to the top-left corner of
+	// the window's content area, in the same physical pixels as
+	// BackingSize. It can lie outside the window.
 	Cursor() (x, y float32)
+	// Scale returns the current display scale factor. It may change while
+	// the window is open, e.g. when it moves to another monitor.
 	Scale() float32
+	// DisplayInfo returns the geometry of the monitor the window is on.
+	DisplayInfo() DisplayInfo
+	// Focused reports whether the window has keyboard focus. A minimized
+	// window never does.
+	Focused() bool
+	// StartDrag lets the user move the window by dragging its content, for
+	// windows that draw their own title bar. Call it while the left button
+	// is down after a press inside the app's drag region; the system then
+	// moves the window until the button is released. The left button is
+	// reported Released as the drag starts, since the app won't see the
+	// release that ends it.
+	StartDrag()
+	// SetFullscreenMode switches the monitor mode belongs to into that
+	// mode and makes the window cover it without decorations, for
+	// exclusive fullscreen at a chosen resolution. A nil mode leaves
+	// fullscreen and restores the monitor's original mode. Close restores
+	// it too; if the process exits without closing the window, Windows and
+	// macOS restore it themselves but X11 keeps the new mode.
+	SetFullscreenMode(mode *DisplayMode) error
+	// MakeCurrent makes the window's GL context current on the calling
+	// thread, and ClearCurrent releases it so that another context can be
+	// used, e.g. by other GL code in the same process. New leaves the
+	// context current on the thread that created the window. A context can
+	// only be current on one thread at a time, and it is bound to an OS
+	// thread rather than a goroutine, so call these from a goroutine locked
+	// with runtime.LockOSThread; on Windows and macOS that must also be the
+	// thread that created the window. Make the context current again before
+	// any other call that draws, such as Swap.
+	MakeCurrent() error
+	ClearCurrent()
+	// NewSharedContext creates another GL context that shares textures,
+	// buffers and other objects with the window's, for loading resources
+	// on a second thread. It must be called on the window's thread.
+	NewSharedContext() (SharedContext, error)
 	GetKeyState(key Key) KeyState
 	GetButtonState(button Button) ButtonState
+	// TextInput returns the text typed since the previous Poll, including
+	// text committed by an input method (IME) on Windows and Linux.
+	// Control characters such as Backspace and Enter are left out; use
+	// GetKeyState for those. Input methods are not supported on macOS,
+	// where it reports the characters of each key as typed.
+	TextInput() string
+	// Composition returns the text an input method is composing but has
+	// not committed yet, or "" when there is none. Only Windows reports
+	// it; on Linux the input method draws the preedit text itself, and
+	// macOS has no input method support, so it is always "" there.
+	Composition() string
+}
+
+// SharedContext is a GL context created by Window.NewSharedContext. It has
+// the same thread rules as the window's own context: make it current only
+// on a goroutine locked with runtime.LockOSThread, and on one thread at a
+// time. Objects it creates can be used by the window's context once they
+// are complete, which glFinish before handing them over ensures.
+type SharedContext interface {
+	MakeCurrent() error
+	ClearCurrent()
+	// Close destroys the context. Call it on the thread it is current on,
+	// if any, and before closing the window. Shared objects stay valid.
+	Close()
+}
+
+// appendText appends the printable characters of s to buf.
+func appendText(buf []byte, s string) []byte {
+	for _, r := range s {
+		// 0xF700-0xF8FF is where macOS puts arrow and function keys.
+		if r < 0x20 || r == 0x7f || (r >= 0xf700 && r <= 0xf8ff) {
+			continue
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf
 }
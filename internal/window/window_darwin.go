@@ -6,6 +6,7 @@ package window
 
 import (
 	"errors"
+	"fmt"
 	"runtime"
 	"sync"
 	"unsafe"
@@ -42,18 +43,35 @@ const (
 
 	nsBackingStoreBuffered = 2
 
+	nsWindowStyleBorderless = 0
+
+	nsApplicationPresentationDefault     = 0
+	nsApplicationPresentationHideDock    = 1 << 1
+	nsApplicationPresentationHideMenuBar = 1 << 3
+
 	nsEventMaskAny = ^uint(0)
 
+	nsEventTypeLeftMouseDown      = 1
+	nsEventTypeKeyDown            = 10
+	nsEventTypeApplicationDefined = 15
+
 	// NSOpenGL pixel format attributes.
 	nsOpenGLPFAAccelerated       = 73
 	nsOpenGLPFADoubleBuffer      = 5
 	nsOpenGLPFAColorSize         = 8
+	nsOpenGLPFAAlphaSize         = 11
 	nsOpenGLPFADepthSize         = 12
+	nsOpenGLPFAStencilSize       = 13
+	nsOpenGLPFASampleBuffers     = 55
+	nsOpenGLPFASamples           = 56
+	nsOpenGLPFAMultisample       = 59
 	nsOpenGLPFAOpenGLProfile     = 99
 	nsOpenGLProfileVersionLegacy = 0x1000
+	nsOpenGLProfileVersion32Core = 0x3200
 	nsOpenGLProfileVersion41Core = 0x4100
 
-	nsOpenGLCPSwapInterval = 222
+	nsOpenGLCPSwapInterval   = 222
+	nsOpenGLCPSurfaceOpacity = 236
 )
 
 // Cocoa exposes objects as pointers (Objective-C id).
@@ -64,6 +82,23 @@ type Cocoa struct {
 	ctx     objc.ID
 	pool    objc.ID
 	running bool
+	hidden  bool
+
+	transparent bool
+
+	// Characters typed since the last Poll.
+	text []byte
+
+	// The latest left mouse down event, retained for StartDrag.
+	mouseDown objc.ID
+
+	// While SetFullscreenMode has changed a display's mode: the display,
+	// its original mode (a retained CGDisplayModeRef), and the window's
+	// style and frame to restore.
+	savedDisplay uint32
+	savedMode    uintptr
+	savedStyle   uint
+	savedFrame   NSRect
 }
 
 var (
@@ -74,6 +109,23 @@ var (
 	cfRunLoopRunInMode func(uintptr, float64, bool) int32
 	cfDefaultMode      uintptr
 
+	// CoreGraphics.
+	cgDisplayScreenSize func(uint32) NSSize // returns millimetres
+
+	// CoreGraphics display modes, for SetFullscreenMode.
+	cfArrayGetCount                    func(uintptr) int
+	cfArrayGetValueAtIndex             func(uintptr, int) uintptr
+	cfRelease                          func(uintptr)
+	cgDisplayCopyAllDisplayModes       func(uint32, uintptr) uintptr
+	cgDisplayCopyDisplayMode           func(uint32) uintptr
+	cgDisplaySetDisplayMode            func(uint32, uintptr, uintptr) int32
+	cgDisplayModeRelease               func(uintptr)
+	cgDisplayModeGetWidth              func(uintptr) uint
+	cgDisplayModeGetHeight             func(uintptr) uint
+	cgDisplayModeGetRefreshRate        func(uintptr) float64
+	cgDisplayModeGetIODisplayModeID    func(uintptr) int32
+	cgDisplayModeIsUsableForDesktopGUI func(uintptr) bool
+
 	// Cached selectors.
 	selAlloc                 objc.SEL
 	selInit                  objc.SEL
@@ -85,6 +137,13 @@ var (
 	selStringWithUTF8String  objc.SEL
 	selInitWithContentRect   objc.SEL
 	selMakeKeyAndOrderFront  objc.SEL
+	selOrderOut              objc.SEL
+	selSetAlphaValue         objc.SEL
+	selSetOpaque             objc.SEL
+	selType                  objc.SEL
+	selCharacters            objc.SEL
+	selSetBackgroundColor    objc.SEL
+	selClearColor            objc.SEL
 	selSetTitle              objc.SEL
 	selSetAcceptsMouseMoved  objc.SEL
 	selSetReleasedWhenClosed objc.SEL
@@ -93,32 +152,61 @@ var (
 	selBounds                objc.SEL
 	selMouseLocationOutside  objc.SEL
 	selConvertRectToBacking  objc.SEL
+	selConvertPointFromView  objc.SEL
 	selIsVisible             objc.SEL
 	selSendEvent             objc.SEL
 	selFlushBuffer           objc.SEL
 	selSetView               objc.SEL
 	selMakeCurrentContext    objc.SEL
+	selPixelFormat           objc.SEL
 	selClearCurrentContext   objc.SEL
 	selInitWithAttributes    objc.SEL
 	selInitWithFormat        objc.SEL
 	selSetValuesForParameter objc.SEL
+	selDistantFuture         objc.SEL
+	selOtherEventWithType    objc.SEL
+	selPostEventAtStart      objc.SEL
+	selBackingScaleFactor    objc.SEL
+	selScreen                objc.SEL
+	selFrame                 objc.SEL
+	selDeviceDescription     objc.SEL
+	selObjectForKey          objc.SEL
+	selUnsignedIntValue      objc.SEL
+	selScreens               objc.SEL
+	selCount                 objc.SEL
+	selObjectAtIndex         objc.SEL
+	selLocalizedName         objc.SEL
+	selRespondsToSelector    objc.SEL
+	selUTF8String            objc.SEL
+	selSetFrameTopLeftPoint  objc.SEL
+
+	selRetain                     objc.SEL
+	selPerformWindowDragWithEvent objc.SEL
+	selIsKeyWindow                objc.SEL
+	selIsMiniaturized             objc.SEL
+
+	selStyleMask              objc.SEL
+	selSetStyleMask           objc.SEL
+	selSetFrameDisplay        objc.SEL
+	selSetPresentationOptions objc.SEL
+	selUpdate                 objc.SEL
 )
 
 // Init boots Cocoa and OpenGL, keeping control of the run loop in Go.
-func New(title string, width, height int, useCoreProfile bool) (Window, error) {
+func New(title string, width, height int, opts Options) (Window, error) {
 	runtime.LockOSThread()
 	if err := ensureRuntime(); err != nil {
 		return nil, err
 	}
 
-	c := &Cocoa{running: true}
+	c := &Cocoa{running: true, hidden: opts.StartHidden, transparent: opts.Transparent}
 	if err := c.bootstrapApp(); err != nil {
 		return nil, err
 	}
-	if err := c.makeWindow(title, width, height); err != nil {
+	if err := c.makeWindow(title, width, height, opts.Monitor); err != nil {
 		return nil, err
 	}
-	if err := c.makeGLContext(useCoreProfile); err != nil {
+	if err := c.makeGLContext(opts); err != nil {
 		return nil, err
 	}
 	return c, nil
@@ -135,21 +223,60 @@ func (c *Cocoa) Poll() bool {
 	}
 
 	// Drain one slice of the run loop without blocking and pump pending NSEvents.
+	c.text = c.text[:0]
 	cfRunLoopRunInMode(cfDefaultMode, 0, true)
 	for {
 		ev := objc.Send[objc.ID](c.app, selNextEventMatchingMask, nsEventMaskAny, objc.ID(0), objc.ID(cfDefaultMode), true)
 		if ev == 0 {
 			break
 		}
+		switch objc.Send[uint](ev, selType) {
+		case nsEventTypeKeyDown:
+			c.text = appendText(c.text, goString(ev.Send(selCharacters)))
+		case nsEventTypeLeftMouseDown:
+			if c.mouseDown != 0 {
+				c.mouseDown.Send(selRelease)
+			}
+			c.mouseDown = ev.Send(selRetain)
+		}
 		c.app.Send(selSendEvent, ev)
 	}
 
-	if !objc.Send[bool](c.window, selIsVisible) {
+	// A window that is closed stops being visible; one hidden on purpose
+	// hasn't been closed.
+	if !c.hidden && !objc.Send[bool](c.window, selIsVisible) {
 		c.running = false
 	}
 	return c.running
 }
 
+// Wait blocks until an event is queued without removing it from the queue.
+func (c *Cocoa) Wait() {
+	if !c.running {
+		return
+	}
+	until := objc.ID(objc.GetClass("NSDate")).Send(selDistantFuture)
+	c.app.Send(selNextEventMatchingMask, nsEventMaskAny, until, objc.ID(cfDefaultMode), false)
+}
+
+// Wake posts an application-defined event to end a blocked Wait. It may be
+// called from any goroutine.
+func (c *Cocoa) Wake() {
+	if c.app == 0 {
+		return
+	}
+	// The event is autoreleased; this goroutine may not have a pool of its own.
+	pool := objc.ID(objc.GetClass("NSAutoreleasePool")).Send(selAlloc).Send(selInit)
+	defer pool.Send(selRelease)
+
+	ev := objc.ID(objc.GetClass("NSEvent")).Send(selOtherEventWithType,
+		uint(nsEventTypeApplicationDefined), NSPoint{}, uint(0), float64(0),
+		0, objc.ID(0), int16(0), 0, 0)
+	if ev != 0 {
+		c.app.Send(selPostEventAtStart, ev, false)
+	}
+}
+
 // Swap presents the back buffer.
 func (c *Cocoa) Swap() {
 	if c.ctx != 0 {
@@ -157,6 +284,22 @@ func (c *Cocoa) Swap() {
 	}
 }
 
+func (c *Cocoa) Show() {
+	c.window.Send(selMakeKeyAndOrderFront, objc.ID(0))
+	c.hidden = false
+}
+
+func (c *Cocoa) Hide() {
+	c.hidden = true
+	c.window.Send(selOrderOut, objc.ID(0))
+}
+
+func (c *Cocoa) SetOpacity(opacity float32) {
+	opacity = clampOpacity(opacity)
+	c.window.Send(selSetOpaque, opacity == 1 && !c.transparent)
+	c.window.Send(selSetAlphaValue, float64(opacity))
+}
+
 // BackingSize returns the current pixel dimensions, accounting for Retina scale.
 func (c *Cocoa) BackingSize() (int, int) {
 	if c.view == 0 {
@@ -174,10 +317,184 @@ func (c *Cocoa) Cursor() (float32, float32) {
 	return x, float32(h) - y
 }
 
+// Focused reports whether the window is the key window and not in the Dock.
+func (c *Cocoa) Focused() bool {
+	return objc.Send[bool](c.window, selIsKeyWindow) && !objc.Send[bool](c.window, selIsMiniaturized)
+}
+
+// MakeCurrent makes the NSOpenGLContext current on the calling thread. It
+// can't fail.
+func (c *Cocoa) MakeCurrent() error {
+	c.ctx.Send(selMakeCurrentContext)
+	return nil
+}
+
+// ClearCurrent clears the calling thread's current NSOpenGLContext.
+func (c *Cocoa) ClearCurrent() {
+	objc.ID(objc.GetClass("NSOpenGLContext")).Send(selClearCurrentContext)
+}
+
+// NewSharedContext creates an NSOpenGLContext with the window context's
+// pixel format, passing it as shareContext. The new context has no view;
+// it is only for creating objects.
+func (c *Cocoa) NewSharedContext() (SharedContext, error) {
+	pf := c.ctx.Send(selPixelFormat)
+	ctx := objc.ID(objc.GetClass("NSOpenGLContext")).Send(selAlloc)
+	ctx = ctx.Send(selInitWithFormat, pf, c.ctx)
+	if ctx == 0 {
+		return nil, errors.New("failed to create shared gl context")
+	}
+	return &cocoaSharedContext{ctx: ctx}, nil
+}
+
+type cocoaSharedContext struct {
+	ctx objc.ID
+}
+
+func (c *cocoaSharedContext) MakeCurrent() error {
+	c.ctx.Send(selMakeCurrentContext)
+	return nil
+}
+
+func (c *cocoaSharedContext) ClearCurrent() {
+	objc.ID(objc.GetClass("NSOpenGLContext")).Send(selClearCurrentContext)
+}
+
+func (c *cocoaSharedContext) Close() {
+	if c.ctx != 0 {
+		c.ctx.Send(selRelease)
+		c.ctx = 0
+	}
+}
+
+// StartDrag moves the window with the mouse from the last left mouse down,
+// using performWindowDragWithEvent: (macOS 10.11+).
+func (c *Cocoa) StartDrag() {
+	if c.mouseDown == 0 || !objc.Send[bool](c.window, selRespondsToSelector, selPerformWindowDragWithEvent) {
+		return
+	}
+	c.window.Send(selPerformWindowDragWithEvent, c.mouseDown)
+}
+
+// SetFullscreenMode switches the display with CGDisplaySetDisplayMode,
+// which macOS undoes when the process exits, then makes the window a
+// borderless one covering the screen with the menu bar and Dock hidden.
+func (c *Cocoa) SetFullscreenMode(mode *DisplayMode) error {
+	if mode == nil {
+		c.restoreMode()
+		return nil
+	}
+	display := uint32(mode.monitor.id)
+	if display == 0 {
+		return errors.New("display mode has no display ID")
+	}
+	if c.savedMode != 0 && c.savedDisplay != display {
+		c.restoreMode()
+	}
+
+	list := cgDisplayCopyAllDisplayModes(display, 0)
+	if list == 0 {
+		return errors.New("CGDisplayCopyAllDisplayModes failed")
+	}
+	defer cfRelease(list)
+	var target uintptr
+	for i := range cfArrayGetCount(list) {
+		if m := cfArrayGetValueAtIndex(list, i); uintptr(cgDisplayModeGetIODisplayModeID(m)) == mode.id {
+			target = m
+			break
+		}
+	}
+	if target == 0 {
+		return fmt.Errorf("display mode %dx%d is no longer available", mode.Width, mode.Height)
+	}
+
+	if c.savedMode == 0 {
+		c.savedDisplay = display
+		c.savedMode = cgDisplayCopyDisplayMode(display)
+		c.savedStyle = objc.Send[uint](c.window, selStyleMask)
+		c.savedFrame = objc.Send[NSRect](c.window, selFrame)
+	}
+	if err := cgDisplaySetDisplayMode(display, target, 0); err != 0 {
+		return fmt.Errorf("CGDisplaySetDisplayMode failed with error %d", err)
+	}
+
+	// NSScreen catches up with the new mode later, so work the frame out
+	// from the monitor's top-left corner, which stays put.
+	primaryH := objc.Send[NSRect](screens().Send(selObjectAtIndex, uint(0)), selFrame).Size.H
+	if mode.monitor.Primary {
+		primaryH = float64(mode.Height)
+	}
+	frame := NSRect{
+		Origin: NSPoint{X: float64(mode.monitor.X), Y: primaryH - float64(mode.monitor.Y+mode.Height)},
+		Size:   NSSize{W: float64(mode.Width), H: float64(mode.Height)},
+	}
+	c.app.Send(selSetPresentationOptions, uint(nsApplicationPresentationHideDock|nsApplicationPresentationHideMenuBar))
+	c.window.Send(selSetStyleMask, uint(nsWindowStyleBorderless))
+	c.window.Send(selSetFrameDisplay, frame, true)
+	c.ctx.Send(selUpdate)
+	return nil
+}
+
+// restoreMode puts back the display mode, window style and frame that
+// SetFullscreenMode replaced.
+func (c *Cocoa) restoreMode() {
+	if c.savedMode == 0 {
+		return
+	}
+	cgDisplaySetDisplayMode(c.savedDisplay, c.savedMode, 0)
+	cgDisplayModeRelease(c.savedMode)
+	c.savedMode = 0
+	if c.window == 0 {
+		return
+	}
+	c.app.Send(selSetPresentationOptions, uint(nsApplicationPresentationDefault))
+	c.window.Send(selSetStyleMask, c.savedStyle)
+	c.window.Send(selSetFrameDisplay, c.savedFrame, true)
+	if c.ctx != 0 {
+		c.ctx.Send(selUpdate)
+	}
+}
+
+// monitorModes lists the modes of m's display that the desktop can use.
+func monitorModes(m *Monitor) ([]DisplayMode, error) {
+	if err := ensureRuntime(); err != nil {
+		return nil, err
+	}
+	if m.id == 0 {
+		return nil, errors.New("monitor has no display ID")
+	}
+	list := cgDisplayCopyAllDisplayModes(uint32(m.id), 0)
+	if list == 0 {
+		return nil, errors.New("CGDisplayCopyAllDisplayModes failed")
+	}
+	defer cfRelease(list)
+
+	var modes []DisplayMode
+	for i := range cfArrayGetCount(list) {
+		mode := cfArrayGetValueAtIndex(list, i)
+		if !cgDisplayModeIsUsableForDesktopGUI(mode) {
+			continue
+		}
+		modes = append(modes, DisplayMode{
+			Width:       int(cgDisplayModeGetWidth(mode)),
+			Height:      int(cgDisplayModeGetHeight(mode)),
+			RefreshRate: cgDisplayModeGetRefreshRate(mode),
+			monitor:     *m,
+			id:          uintptr(cgDisplayModeGetIODisplayModeID(mode)),
+		})
+	}
+	return modes, nil
+}
+
 // Close tears down the GL context and window.
 func (c *Cocoa) Close() {
+	c.restoreMode()
+	if c.mouseDown != 0 {
+		c.mouseDown.Send(selRelease)
+		c.mouseDown = 0
+	}
 	if c.ctx != 0 {
-		objc.ID(objc.GetClass("NSOpenGLContext")).Send(selClearCurrentContext)
+		c.ClearCurrent()
 		c.ctx.Send(selRelease)
 		c.ctx = 0
 	}
@@ -209,7 +526,7 @@ func (c *Cocoa) bootstrapApp() error {
 	return nil
 }
 
-func (c *Cocoa) makeWindow(title string, width, height int) error {
+func (c *Cocoa) makeWindow(title string, width, height int, monitor *Monitor) error {
 	frame := NSRect{
 		Origin: NSPoint{X: 100, Y: 100},
 		Size:   NSSize{W: float64(width), H: float64(height)},
@@ -225,12 +542,26 @@ func (c *Cocoa) makeWindow(title string, width, height int) error {
 		return errors.New("failed to create nswindow")
 	}
 
-	win.Send(selCenter)
+	if monitor != nil {
+		// Monitor coordinates are top-left based; Cocoa's origin is the
+		// bottom-left of the primary screen.
+		x, y := monitor.center(width, height)
+		primary := objc.Send[NSRect](screens().Send(selObjectAtIndex, uint(0)), selFrame)
+		win.Send(selSetFrameTopLeftPoint, NSPoint{X: float64(x), Y: primary.Size.H - float64(y)})
+	} else {
+		win.Send(selCenter)
+	}
 	win.Send(selSetAcceptsMouseMoved, 1)
 	win.Send(selSetReleasedWhenClosed, 0)
+	if c.transparent {
+		win.Send(selSetOpaque, false)
+		win.Send(selSetBackgroundColor, objc.ID(objc.GetClass("NSColor")).Send(selClearColor))
+	}
 	titleStr := nsString(title)
 	win.Send(selSetTitle, titleStr)
-	win.Send(selMakeKeyAndOrderFront, objc.ID(0))
+	if !c.hidden {
+		win.Send(selMakeKeyAndOrderFront, objc.ID(0))
+	}
 
 	c.window = win
 	c.view = win.Send(selContentView)
@@ -240,24 +571,13 @@ func (c *Cocoa) makeWindow(title string, width, height int) error {
 	return nil
 }
 
-func (c *Cocoa) makeGLContext(useCoreProfile bool) error {
-	attrs := []uint32{
-		nsOpenGLPFAAccelerated,
-		nsOpenGLPFADoubleBuffer,
-		nsOpenGLPFAColorSize, 24,
-		nsOpenGLPFADepthSize, 24,
-		nsOpenGLPFAOpenGLProfile,
+func (c *Cocoa) makeGLContext(opts Options) error {
+	profile := glProfile(opts)
+	pf := newPixelFormat(profile, opts.Samples)
+	if pf == 0 && opts.Samples > 0 {
+		// Fall back to a single-sampled format.
+		pf = newPixelFormat(profile, 0)
 	}
-	if useCoreProfile {
-		attrs = append(attrs, nsOpenGLProfileVersion41Core)
-	} else {
-		attrs = append(attrs, nsOpenGLProfileVersionLegacy)
-	}
-	attrs = append(attrs, 0)
-
-	pfClass := objc.GetClass("NSOpenGLPixelFormat")
-	pf := objc.ID(pfClass).Send(selAlloc)
-	pf = pf.Send(selInitWithAttributes, unsafe.Pointer(&attrs[0]))
 	if pf == 0 {
 		return errors.New("failed to create pixel format")
 	}
@@ -277,10 +597,56 @@ func (c *Cocoa) makeGLContext(useCoreProfile bool) error {
 	swap := int32(1)
 	ctx.Send(selSetValuesForParameter, unsafe.Pointer(&swap), nsOpenGLCPSwapInterval)
 
+	if c.transparent {
+		// Let the window server composite through the surface's alpha.
+		opaque := int32(0)
+		ctx.Send(selSetValuesForParameter, unsafe.Pointer(&opaque), nsOpenGLCPSurfaceOpacity)
+	}
+
 	c.ctx = ctx
 	return nil
 }
 
+// newPixelFormat returns an NSOpenGLPixelFormat, or 0 if none matches.
+// glProfile maps the requested GL version to an NSOpenGL profile. macOS only
+// offers legacy (2.1), 3.2 core and 4.1 core contexts, and core contexts may
+// be of a higher version than asked for.
+func glProfile(opts Options) uint32 {
+	major, minor := opts.glVersion(4, 1)
+	switch {
+	case !opts.CoreProfile || major < 3 || (major == 3 && minor < 2):
+		return nsOpenGLProfileVersionLegacy
+	case major == 3:
+		return nsOpenGLProfileVersion32Core
+	default:
+		return nsOpenGLProfileVersion41Core
+	}
+}
+
+func newPixelFormat(profile uint32, samples int) objc.ID {
+	attrs := []uint32{
+		nsOpenGLPFAAccelerated,
+		nsOpenGLPFADoubleBuffer,
+		nsOpenGLPFAColorSize, 24,
+		nsOpenGLPFAAlphaSize, 8,
+		nsOpenGLPFADepthSize, 24,
+		nsOpenGLPFAStencilSize, 8,
+		nsOpenGLPFAOpenGLProfile, profile,
+	}
+	if samples > 0 {
+		attrs = append(attrs,
+			nsOpenGLPFAMultisample,
+			nsOpenGLPFASampleBuffers, 1,
+			nsOpenGLPFASamples, uint32(samples),
+		)
+	}
+	attrs = append(attrs, 0)
+
+	pfClass := objc.GetClass("NSOpenGLPixelFormat")
+	pf := objc.ID(pfClass).Send(selAlloc)
+	return pf.Send(selInitWithAttributes, unsafe.Pointer(&attrs[0]))
+}
+
 func ensureRuntime() error {
 	initOnce.Do(func() {
 		if err := loadObjc(); err != nil {
@@ -313,6 +679,24 @@ func loadObjc() error {
 	// Dlsym returns the address of the CFStringRef variable; read its value.
 	cfDefaultMode = *(*uintptr)(unsafe.Pointer(ptr))
 
+	cg, err := purego.Dlopen("/System/Library/Frameworks/CoreGraphics.framework/CoreGraphics", purego.RTLD_GLOBAL)
+	if err != nil {
+		return err
+	}
+	purego.RegisterLibFunc(&cgDisplayScreenSize, cg, "CGDisplayScreenSize")
+	purego.RegisterLibFunc(&cfArrayGetCount, cf, "CFArrayGetCount")
+	purego.RegisterLibFunc(&cfArrayGetValueAtIndex, cf, "CFArrayGetValueAtIndex")
+	purego.RegisterLibFunc(&cfRelease, cf, "CFRelease")
+	purego.RegisterLibFunc(&cgDisplayCopyAllDisplayModes, cg, "CGDisplayCopyAllDisplayModes")
+	purego.RegisterLibFunc(&cgDisplayCopyDisplayMode, cg, "CGDisplayCopyDisplayMode")
+	purego.RegisterLibFunc(&cgDisplaySetDisplayMode, cg, "CGDisplaySetDisplayMode")
+	purego.RegisterLibFunc(&cgDisplayModeRelease, cg, "CGDisplayModeRelease")
+	purego.RegisterLibFunc(&cgDisplayModeGetWidth, cg, "CGDisplayModeGetWidth")
+	purego.RegisterLibFunc(&cgDisplayModeGetHeight, cg, "CGDisplayModeGetHeight")
+	purego.RegisterLibFunc(&cgDisplayModeGetRefreshRate, cg, "CGDisplayModeGetRefreshRate")
+	purego.RegisterLibFunc(&cgDisplayModeGetIODisplayModeID, cg, "CGDisplayModeGetIODisplayModeID")
+	purego.RegisterLibFunc(&cgDisplayModeIsUsableForDesktopGUI, cg, "CGDisplayModeIsUsableForDesktopGUI")
+
 	return nil
 }
 
@@ -327,6 +711,13 @@ func loadSelectors() {
 	selStringWithUTF8String = objc.RegisterName("stringWithUTF8String:")
 	selInitWithContentRect = objc.RegisterName("initWithContentRect:styleMask:backing:defer:")
 	selMakeKeyAndOrderFront = objc.RegisterName("makeKeyAndOrderFront:")
+	selOrderOut = objc.RegisterName("orderOut:")
+	selSetAlphaValue = objc.RegisterName("setAlphaValue:")
+	selSetOpaque = objc.RegisterName("setOpaque:")
+	selType = objc.RegisterName("type")
+	selCharacters = objc.RegisterName("characters")
+	selSetBackgroundColor = objc.RegisterName("setBackgroundColor:")
+	selClearColor = objc.RegisterName("clearColor")
 	selSetTitle = objc.RegisterName("setTitle:")
 	selSetAcceptsMouseMoved = objc.RegisterName("setAcceptsMouseMovedEvents:")
 	selSetReleasedWhenClosed = objc.RegisterName("setReleasedWhenClosed:")
@@ -335,15 +726,42 @@ func loadSelectors() {
 	selBounds = objc.RegisterName("bounds")
 	selMouseLocationOutside = objc.RegisterName("mouseLocationOutsideOfEventStream")
 	selConvertRectToBacking = objc.RegisterName("convertRectToBacking:")
+	selConvertPointFromView = objc.RegisterName("convertPoint:fromView:")
 	selIsVisible = objc.RegisterName("isVisible")
 	selSendEvent = objc.RegisterName("sendEvent:")
 	selFlushBuffer = objc.RegisterName("flushBuffer")
 	selSetView = objc.RegisterName("setView:")
 	selMakeCurrentContext = objc.RegisterName("makeCurrentContext")
+	selPixelFormat = objc.RegisterName("pixelFormat")
 	selClearCurrentContext = objc.RegisterName("clearCurrentContext")
 	selInitWithAttributes = objc.RegisterName("initWithAttributes:")
 	selInitWithFormat = objc.RegisterName("initWithFormat:shareContext:")
 	selSetValuesForParameter = objc.RegisterName("setValues:forParameter:")
+	selDistantFuture = objc.RegisterName("distantFuture")
+	selBackingScaleFactor = objc.RegisterName("backingScaleFactor")
+	selScreen = objc.RegisterName("screen")
+	selFrame = objc.RegisterName("frame")
+	selDeviceDescription = objc.RegisterName("deviceDescription")
+	selObjectForKey = objc.RegisterName("objectForKey:")
+	selUnsignedIntValue = objc.RegisterName("unsignedIntValue")
+	selScreens = objc.RegisterName("screens")
+	selCount = objc.RegisterName("count")
+	selObjectAtIndex = objc.RegisterName("objectAtIndex:")
+	selLocalizedName = objc.RegisterName("localizedName")
+	selRespondsToSelector = objc.RegisterName("respondsToSelector:")
+	selUTF8String = objc.RegisterName("UTF8String")
+	selSetFrameTopLeftPoint = objc.RegisterName("setFrameTopLeftPoint:")
+	selOtherEventWithType = objc.RegisterName("otherEventWithType:location:modifierFlags:timestamp:windowNumber:context:subtype:data1:data2:")
+	selPostEventAtStart = objc.RegisterName("postEvent:atStart:")
+	selRetain = objc.RegisterName("retain")
+	selPerformWindowDragWithEvent = objc.RegisterName("performWindowDragWithEvent:")
+	selIsKeyWindow = objc.RegisterName("isKeyWindow")
+	selIsMiniaturized = objc.RegisterName("isMiniaturized")
+	selStyleMask = objc.RegisterName("styleMask")
+	selSetStyleMask = objc.RegisterName("setStyleMask:")
+	selSetFrameDisplay = objc.RegisterName("setFrame:display:")
+	selSetPresentationOptions = objc.RegisterName("setPresentationOptions:")
+	selUpdate = objc.RegisterName("update")
 }
 
 func nsString(v string) objc.ID {
@@ -355,24 +773,122 @@ func (c *Cocoa) cursorBackingPos() (float32, float32) {
 	if c.window == 0 || c.view == 0 {
 		return 0, 0
 	}
+	// The location is in window coordinates, which include the title bar;
+	// the view's coordinates start at the bottom-left of the content area.
 	pos := objc.Send[NSPoint](c.window, selMouseLocationOutside)
+	pos = objc.Send[NSPoint](c.view, selConvertPointFromView, pos, objc.ID(0))
 	rect := NSRect{Origin: pos, Size: NSSize{W: 0, H: 0}}
 	backing := objc.Send[NSRect](c.view, selConvertRectToBacking, rect)
 	return float32(backing.Origin.X), float32(backing.Origin.Y)
 }
 
+// Scale returns the ratio of backing pixels to points for the screen the
+// window is currently on. It is queried each call so moving the window to
+// a display with a different density is picked up immediately.
 func (c *Cocoa) Scale() float32 {
-	// macOS handles scaling automatically through BackingSize()
-	// which already accounts for Retina scaling, so we return 1.0
-	// as the coordinate system is already scaled appropriately.
+	if c.window == 0 {
+		return 1.0
+	}
+	if s := objc.Send[float64](c.window, selBackingScaleFactor); s > 0 {
+		return float32(s)
+	}
 	return 1.0
 }
 
+// Monitors returns the attached screens. The first is the primary screen,
+// the one with the menu bar.
+func Monitors() ([]Monitor, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := ensureRuntime(); err != nil {
+		return nil, err
+	}
+
+	list := screens()
+	n := objc.Send[uint](list, selCount)
+	if n == 0 {
+		return nil, errors.New("no screens")
+	}
+	primaryH := objc.Send[NSRect](list.Send(selObjectAtIndex, uint(0)), selFrame).Size.H
+	mons := make([]Monitor, 0, n)
+	for i := uint(0); i < n; i++ {
+		screen := list.Send(selObjectAtIndex, i)
+		frame := objc.Send[NSRect](screen, selFrame)
+		m := Monitor{
+			X:       int(frame.Origin.X),
+			Y:       int(primaryH - (frame.Origin.Y + frame.Size.H)),
+			Width:   int(frame.Size.W),
+			Height:  int(frame.Size.H),
+			Scale:   float32(objc.Send[float64](screen, selBackingScaleFactor)),
+			Primary: i == 0,
+		}
+		if num := screen.Send(selDeviceDescription).Send(selObjectForKey, nsString("NSScreenNumber")); num != 0 {
+			m.id = uintptr(objc.Send[uint32](num, selUnsignedIntValue))
+		}
+		// localizedName is only available on macOS 10.15 and later.
+		if objc.Send[bool](screen, selRespondsToSelector, selLocalizedName) {
+			m.Name = goString(screen.Send(selLocalizedName))
+		}
+		mons = append(mons, m)
+	}
+	return mons, nil
+}
+
+func screens() objc.ID {
+	return objc.ID(objc.GetClass("NSScreen")).Send(selScreens)
+}
+
+// goString copies an NSString into a Go string.
+func goString(str objc.ID) string {
+	if str == 0 {
+		return ""
+	}
+	p := objc.Send[*byte](str, selUTF8String)
+	if p == nil {
+		return ""
+	}
+	n := 0
+	for *(*byte)(unsafe.Add(unsafe.Pointer(p), n)) != 0 {
+		n++
+	}
+	return string(unsafe.Slice(p, n))
+}
+
+// DisplayInfo describes the NSScreen the window is on.
+func (c *Cocoa) DisplayInfo() DisplayInfo {
+	if c.window == 0 {
+		return DisplayInfo{}
+	}
+	screen := c.window.Send(selScreen)
+	if screen == 0 {
+		return DisplayInfo{}
+	}
+	frame := objc.Send[NSRect](screen, selFrame)
+	scale := objc.Send[float64](screen, selBackingScaleFactor)
+	info := DisplayInfo{
+		Width:  int(frame.Size.W * scale),
+		Height: int(frame.Size.H * scale),
+	}
+	num := screen.Send(selDeviceDescription).Send(selObjectForKey, nsString("NSScreenNumber"))
+	if num != 0 {
+		mm := cgDisplayScreenSize(objc.Send[uint32](num, selUnsignedIntValue))
+		info.WidthMM, info.HeightMM = int(mm.W), int(mm.H)
+	}
+	return info.computeDPI()
+}
+
 func (c *Cocoa) GetKeyState(key Key) KeyState {
 	// TODO: Implement key state tracking
 	return KeyStateUp
 }
 
+func (c *Cocoa) TextInput() string { return string(c.text) }
+
+// Composition is always empty: input methods are not supported on macOS.
+// The view doesn't adopt NSTextInputClient, so key events bypass them and
+// TextInput reports their characters as typed.
+func (c *Cocoa) Composition() string { return "" }
+
 func (c *Cocoa) GetButtonState(button Button) ButtonState {
 	// TODO: Implement button state tracking
 	return ButtonStateUp
@@ -4,9 +4,14 @@ package window
 
 import (
 	"errors"
+	"fmt"
+	"image"
 	"os"
 	"runtime"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/ebitengine/purego"
@@ -17,13 +22,33 @@ const (
 	glxRGBA         = 4
 	glxDoubleBuffer = 5
 	glxDepthSize    = 12
+	glxStencilSize  = 13
 	glxNone         = 0
 
+	// GLX 1.3 FBConfig attributes.
+	glxRedSize      = 8
+	glxGreenSize    = 9
+	glxBlueSize     = 10
+	glxAlphaSize    = 11
+	glxXVisualType  = 0x22
+	glxTrueColor    = 0x8002
+	glxDrawableType = 0x8010
+	glxRenderType   = 0x8011
+	glxXRenderable  = 0x8012
+	glxWindowBit    = 0x1
+	glxRGBABit      = 0x1
+
+	// GLX_ARB_multisample constants
+	glxSampleBuffers = 100000
+	glxSamples       = 100001
+
 	// GLX_ARB_create_context constants
-	glxContextMajorVersionArb   = 0x2091
-	glxContextMinorVersionArb   = 0x2092
-	glxContextFlagsArb          = 0x2094
-	glxContextCoreProfileBitArb = 0x00000001
+	glxContextMajorVersionArb     = 0x2091
+	glxContextMinorVersionArb     = 0x2092
+	glxContextFlagsArb            = 0x2094
+	glxContextProfileMaskArb      = 0x9126
+	glxContextCoreProfileBitArb   = 0x00000001
+	glxContextCompatProfileBitArb = 0x00000002
 
 	inputOutput = 1
 
@@ -34,13 +59,52 @@ const (
 	buttonPressMask     = 1 << 2
 	buttonReleaseMask   = 1 << 3
 	pointerMotionMask   = 1 << 6
-
-	clientMessage = 33
-	destroyNotify = 17
-	keyPress      = 2
-	keyRelease    = 3
-	buttonPress   = 4
-	buttonRelease = 5
+	focusChangeMask     = 1 << 21
+	propertyChangeMask  = 1 << 22
+
+	clientMessage  = 33
+	destroyNotify  = 17
+	keyPress       = 2
+	keyRelease     = 3
+	buttonPress    = 4
+	buttonRelease  = 5
+	propertyNotify = 28
+	mappingNotify  = 34
+	focusIn        = 9
+	focusOut       = 10
+
+	// _NET_WM_MOVERESIZE, sent to the root window to start a move.
+	substructureNotifyMask   = 1 << 19
+	substructureRedirectMask = 1 << 20
+	netWMMoveResizeMove      = 8
+	netWMSourceApplication   = 1
+	currentTime              = 0
+
+	xaCardinal = 6
+	xaString   = 31
+
+	propModeReplace = 0
+
+	rrConnected = 0
+
+	// XRandR rotation and mode flags.
+	rrRotate90   = 2
+	rrRotate270  = 8
+	rrInterlace  = 0x10
+	rrDoubleScan = 0x20
+
+	// _NET_WM_STATE actions.
+	netWMStateRemove = 0
+	netWMStateAdd    = 1
+
+	ximPreeditNothing = 0x0008
+	ximStatusNothing  = 0x0400
+
+	xBufferOverflow = -1
+	xLookupChars    = 2
+	xLookupBoth     = 4
+
+	lcCtype = 0 // glibc
 )
 
 type XVisualInfo struct {
@@ -93,6 +157,78 @@ type xKeyEvent struct {
 	SameScreen int32
 }
 
+type xPropertyEvent struct {
+	Type      int32
+	_         int32
+	Serial    uint64
+	SendEvent int32
+	_         int32
+	Display   uintptr
+	Window    uintptr
+	Atom      uintptr
+	Time      uint64
+	State     int32
+}
+
+// XRandR structures, up to the last field read.
+type xrrScreenResources struct {
+	Timestamp       uint64
+	ConfigTimestamp uint64
+	NCrtc           int32
+	Crtcs           *uintptr
+	NOutput         int32
+	Outputs         *uintptr
+	NMode           int32
+	Modes           *xrrModeInfo
+}
+
+type xrrModeInfo struct {
+	ID         uintptr
+	Width      uint32
+	Height     uint32
+	DotClock   uint64
+	HSyncStart uint32
+	HSyncEnd   uint32
+	HTotal     uint32
+	HSkew      uint32
+	VSyncStart uint32
+	VSyncEnd   uint32
+	VTotal     uint32
+	Name       *byte
+	NameLength uint32
+	ModeFlags  uint64
+}
+
+type xrrOutputInfo struct {
+	Timestamp  uint64
+	Crtc       uintptr
+	Name       *byte
+	NameLen    int32
+	MMWidth    uint64
+	MMHeight   uint64
+	Connection uint16
+	Subpixel   uint16
+	NCrtc      int32
+	Crtcs      *uintptr
+	NClone     int32
+	Clones     *uintptr
+	NMode      int32
+	NPreferred int32
+	Modes      *uintptr
+}
+
+type xrrCrtcInfo struct {
+	Timestamp uint64
+	X         int32
+	Y         int32
+	Width     uint32
+	Height    uint32
+	Mode      uintptr
+	Rotation  uint16
+	NOutput   int32
+	Outputs   *uintptr
+}
+
 type xButtonEvent struct {
 	Type       int32
 	_          int32 // padding (align Serial)
@@ -117,29 +253,59 @@ var (
 	x11lib uintptr
 	gllib  uintptr
 
+	xInitThreads           func() int32
 	xOpenDisplay           func(*byte) uintptr
 	xDefaultScreen         func(uintptr) int32
 	xRootWindow            func(uintptr, int32) uintptr
 	xCreateColormap        func(uintptr, uintptr, uintptr, int32) uintptr
 	xCreateWindow          func(uintptr, uintptr, int32, int32, uint32, uint32, uint32, int32, uint32, uintptr, uint64, unsafe.Pointer) uintptr
 	xMapWindow             func(uintptr, uintptr) int32
+	xUnmapWindow           func(uintptr, uintptr) int32
+	xChangeProperty        func(uintptr, uintptr, uintptr, uintptr, int32, int32, unsafe.Pointer, int32) int32
+	xDeleteProperty        func(uintptr, uintptr, uintptr) int32
 	xStoreName             func(uintptr, uintptr, *byte) int32
 	xInternAtom            func(uintptr, *byte, int32) uintptr
 	xSetWMProtocols        func(uintptr, uintptr, *uintptr, int32) int32
 	xSelectInput           func(uintptr, uintptr, int64)
 	xPending               func(uintptr) int32
+	xConnectionNumber      func(uintptr) int32
 	xNextEvent             func(uintptr, unsafe.Pointer)
 	xGetGeometry           func(uintptr, uintptr, *uintptr, *int32, *int32, *uint32, *uint32, *uint32, *uint32) int32
 	xDestroyWindow         func(uintptr, uintptr) int32
 	xCloseDisplay          func(uintptr) int32
 	xQueryPointer          func(uintptr, uintptr, *uintptr, *uintptr, *int32, *int32, *int32, *int32, *uint32) int32
+	xTranslateCoordinates  func(uintptr, uintptr, uintptr, int32, int32, *int32, *int32, *uintptr) int32
 	xDisplayWidth          func(uintptr, int32) int32
 	xDisplayWidthMM        func(uintptr, int32) int32
 	xDisplayHeight         func(uintptr, int32) int32
 	xDisplayHeightMM       func(uintptr, int32) int32
 	xResourceManagerString func(uintptr) *byte
+	xGetWindowProperty     func(uintptr, uintptr, uintptr, int64, int64, int32, uintptr, *uintptr, *int32, *uint64, *uint64, **byte) int32
+	xFree                  func(unsafe.Pointer) int32
+	xMoveWindow            func(uintptr, uintptr, int32, int32) int32
 	xLookupKeysym          func(*xKeyEvent, int32) uint32
 
+	xSendEvent     func(uintptr, uintptr, int32, int64, unsafe.Pointer) int32
+	xUngrabPointer func(uintptr, uintptr) int32
+	xFlush         func(uintptr) int32
+
+	xkbKeycodeToKeysym      func(uintptr, uint8, int32, int32) uint32
+	xRefreshKeyboardMapping func(unsafe.Pointer) int32
+
+	// Input method support; nil when Xlib lacks it.
+	ximOnce             sync.Once
+	xSetLocaleModifiers func(*byte) *byte
+	xOpenIM             func(uintptr, uintptr, uintptr, uintptr) uintptr
+	xCloseIM            func(uintptr) int32
+	// XCreateIC is variadic; this is the one argument list we pass.
+	xCreateIC         func(uintptr, *byte, uintptr, *byte, uintptr, *byte, uintptr, uintptr) uintptr
+	xDestroyIC        func(uintptr)
+	xSetICFocus       func(uintptr)
+	xUnsetICFocus     func(uintptr)
+	xFilterEvent      func(unsafe.Pointer, uintptr) int32
+	xutf8LookupString func(uintptr, *xKeyEvent, *byte, int32, unsafe.Pointer, *int32) int32
+	xLookupString     func(*xKeyEvent, *byte, int32, unsafe.Pointer, unsafe.Pointer) int32
+
 	glxChooseVisual            func(uintptr, int32, *int32) *XVisualInfo
 	glxCreateContext           func(uintptr, *XVisualInfo, uintptr, int32) uintptr
 	glxMakeCurrent             func(uintptr, uintptr, uintptr) int32
@@ -149,6 +315,18 @@ var (
 	glxGetVisualFromFBConfig   func(uintptr, uintptr) *XVisualInfo
 	glxCreateContextAttribsARB func(uintptr, uintptr, uintptr, int32, *int32) uintptr
 	glXGetProcAddressARB       func(*byte) unsafe.Pointer
+
+	// XRandR is optional; without it the whole X screen is one monitor.
+	xrandrOnce                   sync.Once
+	xrandrLib                    uintptr
+	xrrGetScreenResourcesCurrent func(uintptr, uintptr) *xrrScreenResources
+	xrrFreeScreenResources       func(*xrrScreenResources)
+	xrrGetOutputInfo             func(uintptr, *xrrScreenResources, uintptr) *xrrOutputInfo
+	xrrFreeOutputInfo            func(*xrrOutputInfo)
+	xrrGetCrtcInfo               func(uintptr, *xrrScreenResources, uintptr) *xrrCrtcInfo
+	xrrFreeCrtcInfo              func(*xrrCrtcInfo)
+	xrrGetOutputPrimary          func(uintptr, uintptr) uintptr
+	xrrSetCrtcConfig             func(uintptr, *xrrScreenResources, uintptr, uint64, int32, int32, uintptr, uint16, *uintptr, int32) int32
 )
 
 type x11Window struct {
@@ -160,19 +338,71 @@ type x11Window struct {
 	scale        float32
 	keyStates    map[Key]KeyState
 	buttonStates map[Button]ButtonState
+	focused      bool
+
+	// What ctx was created from, for NewSharedContext: fbConfig and
+	// ctxAttribs if it came from glXCreateContextAttribsARB, otherwise
+	// visual.
+	fbConfig   uintptr
+	ctxAttribs []int32
+	visual     *XVisualInfo
+
+	// Wait blocks in epoll on the X connection and a self-pipe that Wake
+	// writes to. epfd is -1 if they couldn't be set up. Wake runs on other
+	// goroutines, so wakeMu guards closing them against its write.
+	wakeMu sync.Mutex
+	epfd   int
+	wakeR  int
+	wakeW  int
+
+	// Position to move to when first mapped, if placed on a monitor.
+	place          bool
+	placeX, placeY int32
+
+	// The desktop publishes Xft.dpi in the RESOURCE_MANAGER property of the
+	// root window; scale is recalculated whenever it changes.
+	screen          int32
+	root            uintptr
+	resourceManager uintptr
+
+	// Input method and context used to turn key presses into text. Both
+	// are 0 if no input method could be opened.
+	im   uintptr
+	ic   uintptr
+	text []byte
+
+	// The CRTC configuration SetFullscreenMode replaced, or nil while the
+	// monitor is in its original mode.
+	savedCrtc *crtcConfig
 }
 
-func New(title string, width, height int, _ bool) (Window, error) {
+// crtcConfig is what XRRSetCrtcConfig needs to put a CRTC back as it was.
+type crtcConfig struct {
+	crtc     uintptr
+	x, y     int32
+	mode     uintptr
+	rotation uint16
+	outputs  []uintptr
+}
+
+// New creates an X11 window. On a Wayland session this goes through
+// XWayland, so the compositor scales the window as a whole rather than the
+// application rendering at the output's (possibly fractional) scale.
+func New(title string, width, height int, opts Options) (Window, error) {
+	if isWaylandSession() && os.Getenv("DISPLAY") == "" {
+		return nil, ErrWaylandOnly
+	}
+
 	runtime.LockOSThread()
 	if err := ensureLibs(); err != nil {
 		runtime.UnlockOSThread()
 		return nil, err
 	}
 
-	dpy := xOpenDisplay(nil)
-	if dpy == 0 {
+	dpy, err := openDisplay()
+	if err != nil {
 		runtime.UnlockOSThread()
-		return nil, errors.New("XOpenDisplay failed")
+		return nil, err
 	}
 
 	screen := xDefaultScreen(dpy)
@@ -181,47 +411,71 @@ func New(title string, width, height int, _ bool) (Window, error) {
 	// Try to use GLX_ARB_create_context for OpenGL 3.0+
 	var visual *XVisualInfo
 	var fbConfig uintptr
+	var ctxAttribs []int32
 	var ctx uintptr
 
 	// First, try FBConfig-based approach for GL 3.0+
 	if glxChooseFBConfig != nil {
 		fbAttribs := []int32{
-			0x8011, // GLX_X_RENDERABLE
-			1,      // True
-			0x8012, // GLX_DRAWABLE_TYPE
-			0x8001, // GLX_WINDOW_BIT
-			0x8013, // GLX_RENDER_TYPE
-			0x8011, // GLX_RGBA_BIT
-			0x8014, // GLX_X_VISUAL_TYPE
-			0x8012, // GLX_TRUE_COLOR
-			0x8002, // GLX_DOUBLEBUFFER
-			1,      // True
-			0x8015, // GLX_RED_SIZE
-			8,
-			0x8016, // GLX_GREEN_SIZE
-			8,
-			0x8017, // GLX_BLUE_SIZE
-			8,
-			0x8018, // GLX_ALPHA_SIZE
-			8,
-			0x8019, // GLX_DEPTH_SIZE
-			24,
-			glxNone,
+			glxXRenderable, 1,
+			glxDrawableType, glxWindowBit,
+			glxRenderType, glxRGBABit,
+			glxXVisualType, glxTrueColor,
+			glxDoubleBuffer, 1,
+			glxRedSize, 8,
+			glxGreenSize, 8,
+			glxBlueSize, 8,
+			glxAlphaSize, 8,
+			glxDepthSize, 24,
+			glxStencilSize, 8,
+		}
+		if opts.Samples > 0 {
+			fbAttribs = append(fbAttribs,
+				glxSampleBuffers, 1,
+				glxSamples, int32(opts.Samples),
+			)
 		}
+		fbAttribs = append(fbAttribs, glxNone)
 		var numConfigs int32
 		fbConfigs := glxChooseFBConfig(dpy, screen, &fbAttribs[0], &numConfigs)
+		if (fbConfigs == 0 || numConfigs == 0) && opts.Samples > 0 {
+			// No multisampled config; retry without the sample attributes.
+			fbAttribs = append(fbAttribs[:len(fbAttribs)-5], glxNone)
+			fbConfigs = glxChooseFBConfig(dpy, screen, &fbAttribs[0], &numConfigs)
+		}
 		if fbConfigs != 0 && numConfigs > 0 {
-			// Use first FBConfig
-			fbConfig = *(*uintptr)(unsafe.Pointer(fbConfigs))
-			visual = glxGetVisualFromFBConfig(dpy, fbConfig)
+			configs := unsafe.Slice((*uintptr)(unsafe.Pointer(fbConfigs)), numConfigs)
+			fbConfig, visual = configs[0], glxGetVisualFromFBConfig(dpy, configs[0])
+			if opts.Transparent {
+				// The compositor only blends windows with a 32-bit (ARGB)
+				// visual; the first config's visual is usually 24-bit.
+				for _, c := range configs {
+					if v := glxGetVisualFromFBConfig(dpy, c); v != nil && v.Depth == 32 {
+						if visual != nil {
+							xFree(unsafe.Pointer(visual))
+						}
+						fbConfig, visual = c, v
+						break
+					} else if v != nil {
+						xFree(unsafe.Pointer(v))
+					}
+				}
+			}
 			if visual != nil && glxCreateContextAttribsARB != nil {
-				// Create OpenGL 3.0 context
-				ctxAttribs := []int32{
-					glxContextMajorVersionArb, 3,
-					glxContextMinorVersionArb, 0,
-					glxContextFlagsArb, glxContextCoreProfileBitArb,
-					glxNone,
+				// Create an OpenGL 3.0 context unless asked for another version
+				major, minor := opts.glVersion(3, 0)
+				ctxAttribs = []int32{
+					glxContextMajorVersionArb, int32(major),
+					glxContextMinorVersionArb, int32(minor),
+				}
+				if major > 3 || (major == 3 && minor >= 2) {
+					profile := int32(glxContextCompatProfileBitArb)
+					if opts.CoreProfile {
+						profile = glxContextCoreProfileBitArb
+					}
+					ctxAttribs = append(ctxAttribs, glxContextProfileMaskArb, profile)
 				}
+				ctxAttribs = append(ctxAttribs, glxNone)
 				ctx = glxCreateContextAttribsARB(dpy, fbConfig, 0, 1, &ctxAttribs[0])
 			}
 		}
@@ -229,7 +483,8 @@ func New(title string, width, height int, _ bool) (Window, error) {
 
 	// Fallback to legacy path if GL 3.0 context creation failed
 	if ctx == 0 {
-		attrs := []int32{glxRGBA, glxDoubleBuffer, glxDepthSize, 24, glxNone}
+		ctxAttribs = nil
+		attrs := []int32{glxRGBA, glxDoubleBuffer, glxDepthSize, 24, glxStencilSize, 8, glxNone}
 		visual = glxChooseVisual(dpy, screen, &attrs[0])
 		if visual == nil {
 			xCloseDisplay(dpy)
@@ -254,7 +509,7 @@ func New(title string, width, height int, _ bool) (Window, error) {
 
 	var swa xSetWindowAttributes
 	swa.Colormap = cmap
-	swa.EventMask = exposureMask | structureNotifyMask | keyPressMask | keyReleaseMask | buttonPressMask | buttonReleaseMask | pointerMotionMask
+	swa.EventMask = exposureMask | structureNotifyMask | keyPressMask | keyReleaseMask | buttonPressMask | buttonReleaseMask | pointerMotionMask | focusChangeMask
 
 	const (
 		cwColormap    = 1 << 13
@@ -262,9 +517,14 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		cwBorderPixel = 1 << 3
 	)
 
+	var x, y int
+	if opts.Monitor != nil {
+		x, y = opts.Monitor.center(width, height)
+	}
+
 	win := xCreateWindow(
 		dpy, root,
-		0, 0,
+		int32(x), int32(y),
 		uint32(width), uint32(height),
 		0,
 		visual.Depth,
@@ -285,7 +545,14 @@ func New(title string, width, height int, _ bool) (Window, error) {
 
 	titleBytes := append([]byte(title), 0)
 	xStoreName(dpy, win, &titleBytes[0])
-	xMapWindow(dpy, win)
+	if !opts.StartHidden {
+		xMapWindow(dpy, win)
+		if opts.Monitor != nil {
+			// Window managers usually ignore the position given at creation,
+			// so ask again now the window is mapped.
+			xMoveWindow(dpy, win, int32(x), int32(y))
+		}
+	}
 
 	wmDelete := xInternAtom(dpy, cString("WM_DELETE_WINDOW"), 0)
 	xSetWMProtocols(dpy, win, &wmDelete, 1)
@@ -298,8 +565,10 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		return nil, errors.New("glXMakeCurrent failed")
 	}
 
-	// Calculate scale factor from DPI
+	// Calculate scale factor from DPI, and watch the root window so it can
+	// be recalculated when the desktop's DPI setting changes.
 	scale := calculateScale(dpy, screen)
+	xSelectInput(dpy, root, propertyChangeMask)
 
 	w := &x11Window{
 		display:      dpy,
@@ -310,15 +579,67 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		scale:        scale,
 		keyStates:    make(map[Key]KeyState),
 		buttonStates: make(map[Button]ButtonState),
+		epfd:         -1,
+
+		fbConfig:   fbConfig,
+		ctxAttribs: ctxAttribs,
+		visual:     visual,
+
+		place:           opts.Monitor != nil,
+		placeX:          int32(x),
+		placeY:          int32(y),
+		screen:          screen,
+		root:            root,
+		resourceManager: xInternAtom(dpy, cString("RESOURCE_MANAGER"), 0),
 	}
+	w.initWait()
+	w.openIM()
 	return w, nil
 }
 
+// isWaylandSession reports whether the desktop is a Wayland compositor.
+func isWaylandSession() bool {
+	return os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("XDG_SESSION_TYPE") == "wayland"
+}
+
+// initWait sets up the epoll instance and wake pipe used by Wait and Wake.
+func (w *x11Window) initWait() {
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		return
+	}
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		syscall.Close(p[0])
+		syscall.Close(p[1])
+		return
+	}
+	for _, fd := range []int{int(xConnectionNumber(w.display)), p[0]} {
+		ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+		if err := syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+			syscall.Close(epfd)
+			syscall.Close(p[0])
+			syscall.Close(p[1])
+			return
+		}
+	}
+	w.epfd, w.wakeR, w.wakeW = epfd, p[0], p[1]
+}
+
 func (w *x11Window) GL() (gl.OpenGL, error) {
 	return gl.Load()
 }
 
 func (w *x11Window) Close() {
+	w.restoreMode()
+	if w.ic != 0 {
+		xDestroyIC(w.ic)
+		w.ic = 0
+	}
+	if w.im != 0 {
+		xCloseIM(w.im)
+		w.im = 0
+	}
 	if w.ctx != 0 {
 		glxMakeCurrent(w.display, 0, 0)
 		glxDestroyContext(w.display, w.ctx)
@@ -332,16 +653,78 @@ func (w *x11Window) Close() {
 		xCloseDisplay(w.display)
 		w.display = 0
 	}
+	w.wakeMu.Lock()
+	if w.epfd >= 0 {
+		syscall.Close(w.epfd)
+		syscall.Close(w.wakeR)
+		syscall.Close(w.wakeW)
+		w.epfd = -1
+	}
+	w.wakeMu.Unlock()
 	w.running = false
 	runtime.UnlockOSThread()
 }
 
+func (w *x11Window) Wait() {
+	if !w.running || xPending(w.display) > 0 {
+		return
+	}
+	if w.epfd < 0 {
+		time.Sleep(time.Second / 120)
+		return
+	}
+
+	var events [2]syscall.EpollEvent
+	for {
+		_, err := syscall.EpollWait(w.epfd, events[:], -1)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+
+	// Drain the wake pipe so the next Wait blocks again.
+	var buf [64]byte
+	for {
+		if n, err := syscall.Read(w.wakeR, buf[:]); n <= 0 || err != nil {
+			break
+		}
+	}
+}
+
+func (w *x11Window) Wake() {
+	// The pipe is non-blocking, so holding the lock for the write is
+	// cheap; once it is full a wakeup is pending anyway.
+	w.wakeMu.Lock()
+	defer w.wakeMu.Unlock()
+	if w.epfd >= 0 {
+		syscall.Write(w.wakeW, []byte{0})
+	}
+}
+
 func (w *x11Window) Poll() bool {
 	if !w.running {
 		return false
 	}
 
-	// Transition states: Pressed -> Down, Released -> Up
+	w.advanceInput()
+	w.text = w.text[:0]
+
+	for xPending(w.display) > 0 {
+		var ev xEvent
+		xNextEvent(w.display, unsafe.Pointer(&ev[0]))
+		// The input method gets first look at every event and swallows
+		// the key presses that are part of a composition.
+		if w.im != 0 && xFilterEvent(unsafe.Pointer(&ev[0]), 0) != 0 {
+			continue
+		}
+		w.handleEvent(&ev)
+	}
+	return w.running
+}
+
+// advanceInput moves the key and button states from the previous Poll on:
+// Pressed becomes Down and Released becomes Up.
+func (w *x11Window) advanceInput() {
 	for key, state := range w.keyStates {
 		if state == KeyStatePressed {
 			w.keyStates[key] = KeyStateDown
@@ -356,50 +739,74 @@ func (w *x11Window) Poll() bool {
 			w.buttonStates[button] = ButtonStateUp
 		}
 	}
+}
 
-	for xPending(w.display) > 0 {
-		var ev xEvent
-		xNextEvent(w.display, unsafe.Pointer(&ev[0]))
-		etype := *(*int32)(unsafe.Pointer(&ev[0]))
-		switch etype {
-		case clientMessage:
-			cm := (*xclientMessage)(unsafe.Pointer(&ev[0]))
-			if cm.Format == 32 && cm.Data[0] == uint64(w.wmDelete) {
-				w.running = false
-			}
-		case destroyNotify:
+// handleEvent updates the window from an event the input method didn't
+// swallow.
+func (w *x11Window) handleEvent(ev *xEvent) {
+	etype := *(*int32)(unsafe.Pointer(&ev[0]))
+	switch etype {
+	case clientMessage:
+		cm := (*xclientMessage)(unsafe.Pointer(&ev[0]))
+		if cm.Format == 32 && cm.Data[0] == uint64(w.wmDelete) {
 			w.running = false
-		case keyPress:
-			kev := (*xKeyEvent)(unsafe.Pointer(&ev[0]))
-			key := w.keycodeToKey(kev)
-			if key != KeyUnknown {
-				// Treat missing entries as Up (map default is 0 which equals Pressed).
-				prev := w.GetKeyState(key)
-				if prev == KeyStateUp || prev == KeyStateReleased {
-					w.keyStates[key] = KeyStatePressed
-				} else {
-					w.keyStates[key] = KeyStateRepeated
-				}
-			}
-		case keyRelease:
-			kev := (*xKeyEvent)(unsafe.Pointer(&ev[0]))
-			key := w.keycodeToKey(kev)
-			if key != KeyUnknown {
-				w.keyStates[key] = KeyStateReleased
-			}
-		case buttonPress:
-			bev := (*xButtonEvent)(unsafe.Pointer(&ev[0]))
-			if button := w.buttonToButton(bev.Button); button >= ButtonLeft && button <= Button5 {
-				w.buttonStates[button] = ButtonStatePressed
-			}
-		case buttonRelease:
-			bev := (*xButtonEvent)(unsafe.Pointer(&ev[0]))
-			if button := w.buttonToButton(bev.Button); button >= ButtonLeft && button <= Button5 {
-				w.buttonStates[button] = ButtonStateReleased
+		}
+	case destroyNotify:
+		w.running = false
+	case propertyNotify:
+		pev := (*xPropertyEvent)(unsafe.Pointer(&ev[0]))
+		if pev.Window == w.root && pev.Atom == w.resourceManager {
+			w.scale = calculateScale(w.display, w.screen)
+		}
+	case focusIn:
+		w.focused = true
+		if w.ic != 0 {
+			xSetICFocus(w.ic)
+		}
+	case focusOut:
+		w.focused = false
+		// Releases that happen while another window has focus never
+		// reach us, so let go of everything still held.
+		w.releaseAll()
+		if w.ic != 0 {
+			xUnsetICFocus(w.ic)
+		}
+	case mappingNotify:
+		// The keyboard layout changed (e.g. setxkbmap); drop Xlib's
+		// cached keymap so later lookups use the new one.
+		if xRefreshKeyboardMapping != nil {
+			xRefreshKeyboardMapping(unsafe.Pointer(&ev[0]))
+		}
+	case keyPress:
+		kev := (*xKeyEvent)(unsafe.Pointer(&ev[0]))
+		key := w.keycodeToKey(kev)
+		if key != KeyUnknown {
+			// Treat missing entries as Up (map default is 0 which equals Pressed).
+			prev := w.GetKeyState(key)
+			if prev == KeyStateUp || prev == KeyStateReleased {
+				w.keyStates[key] = KeyStatePressed
+			} else {
+				w.keyStates[key] = KeyStateRepeated
 			}
 		}
+		w.lookupText(kev)
+	case keyRelease:
+		kev := (*xKeyEvent)(unsafe.Pointer(&ev[0]))
+		key := w.keycodeToKey(kev)
+		if key != KeyUnknown {
+			w.keyStates[key] = KeyStateReleased
+		}
+	case buttonPress:
+		bev := (*xButtonEvent)(unsafe.Pointer(&ev[0]))
+		if button := w.buttonToButton(bev.Button); button >= ButtonLeft && button <= Button5 {
+			w.buttonStates[button] = ButtonStatePressed
+		}
+	case buttonRelease:
+		bev := (*xButtonEvent)(unsafe.Pointer(&ev[0]))
+		if button := w.buttonToButton(bev.Button); button >= ButtonLeft && button <= Button5 {
+			w.buttonStates[button] = ButtonStateReleased
+		}
 	}
-	return w.running
 }
 
 func (w *x11Window) Swap() {
@@ -408,6 +815,235 @@ func (w *x11Window) Swap() {
 	}
 }
 
+func (w *x11Window) Show() {
+	xMapWindow(w.display, w.window)
+	if w.place {
+		xMoveWindow(w.display, w.window, w.placeX, w.placeY)
+		w.place = false
+	}
+}
+
+func (w *x11Window) Hide() {
+	xUnmapWindow(w.display, w.window)
+}
+
+// SetOpacity sets _NET_WM_WINDOW_OPACITY, which compositing window managers
+// apply to the whole window.
+func (w *x11Window) SetOpacity(opacity float32) {
+	atom := xInternAtom(w.display, cString("_NET_WM_WINDOW_OPACITY"), 0)
+	opacity = clampOpacity(opacity)
+	if opacity == 1 {
+		xDeleteProperty(w.display, w.window, atom)
+		return
+	}
+	// Format 32 properties are passed as C longs.
+	value := uint64(float64(opacity) * 0xffffffff)
+	xChangeProperty(w.display, w.window, atom, xaCardinal, 32, propModeReplace, unsafe.Pointer(&value), 1)
+}
+
+// Focused reports the last FocusIn or FocusOut. The window manager moves
+// focus away when the window is iconified.
+func (w *x11Window) Focused() bool {
+	return w.focused
+}
+
+// MakeCurrent binds the GLX context to the window on the calling thread.
+func (w *x11Window) MakeCurrent() error {
+	if glxMakeCurrent(w.display, w.window, w.ctx) == 0 {
+		return errors.New("glXMakeCurrent failed")
+	}
+	return nil
+}
+
+// ClearCurrent releases the calling thread's current GLX context.
+func (w *x11Window) ClearCurrent() {
+	glxMakeCurrent(w.display, 0, 0)
+}
+
+// NewSharedContext creates a context the same way as the window's, with the
+// window's context as its share list. It is made current on the window
+// itself, since the FBConfig may not support pbuffers; nothing is drawn
+// through it.
+func (w *x11Window) NewSharedContext() (SharedContext, error) {
+	var ctx uintptr
+	if w.ctxAttribs != nil {
+		ctx = glxCreateContextAttribsARB(w.display, w.fbConfig, w.ctx, 1, &w.ctxAttribs[0])
+	} else {
+		ctx = glxCreateContext(w.display, w.visual, w.ctx, 1)
+	}
+	if ctx == 0 {
+		return nil, errors.New("failed to create shared GLX context")
+	}
+	return &x11SharedContext{display: w.display, drawable: w.window, ctx: ctx}, nil
+}
+
+type x11SharedContext struct {
+	display  uintptr
+	drawable uintptr
+	ctx      uintptr
+}
+
+func (c *x11SharedContext) MakeCurrent() error {
+	if glxMakeCurrent(c.display, c.drawable, c.ctx) == 0 {
+		return errors.New("glXMakeCurrent failed")
+	}
+	return nil
+}
+
+func (c *x11SharedContext) ClearCurrent() {
+	glxMakeCurrent(c.display, 0, 0)
+}
+
+func (c *x11SharedContext) Close() {
+	if c.ctx != 0 {
+		glxDestroyContext(c.display, c.ctx)
+		c.ctx = 0
+	}
+}
+
+// StartDrag asks the window manager to move the window with the pointer by
+// sending it a _NET_WM_MOVERESIZE message.
+func (w *x11Window) StartDrag() {
+	var root, child uintptr
+	var rootX, rootY, winX, winY int32
+	var mask uint32
+	if xQueryPointer(w.display, w.window, &root, &child, &rootX, &rootY, &winX, &winY, &mask) == 0 {
+		return
+	}
+
+	// The button press gave us an implicit pointer grab, which would stop
+	// the window manager from taking the pointer over.
+	xUngrabPointer(w.display, currentTime)
+
+	var ev xEvent
+	cm := (*xclientMessage)(unsafe.Pointer(&ev[0]))
+	cm.Type = clientMessage
+	cm.SendEvent = 1
+	cm.Display = w.display
+	cm.Window = w.window
+	cm.MessageType = xInternAtom(w.display, cString("_NET_WM_MOVERESIZE"), 0)
+	cm.Format = 32
+	cm.Data = [5]uint64{uint64(rootX), uint64(rootY), netWMMoveResizeMove, 1, netWMSourceApplication}
+	xSendEvent(w.display, w.root, 0, substructureRedirectMask|substructureNotifyMask, unsafe.Pointer(&ev[0]))
+	xFlush(w.display)
+
+	// The window manager swallows the release that ends the drag.
+	if w.buttonStates[ButtonLeft].IsDown() {
+		w.buttonStates[ButtonLeft] = ButtonStateReleased
+	}
+}
+
+// SetFullscreenMode sets the mode on the monitor's CRTC with XRandR, then
+// moves the window there and asks the window manager to make it
+// fullscreen. The mode must fit in the X screen as it is laid out now, so
+// it can't grow a monitor over its neighbours.
+func (w *x11Window) SetFullscreenMode(mode *DisplayMode) error {
+	if mode == nil {
+		w.restoreMode()
+		w.setFullscreen(false)
+		return nil
+	}
+	if !loadXrandr() {
+		return errors.New("display modes need XRandR")
+	}
+	if mode.monitor.id == 0 {
+		return errors.New("display mode has no XRandR output")
+	}
+
+	res := xrrGetScreenResourcesCurrent(w.display, w.root)
+	if res == nil {
+		return errors.New("XRRGetScreenResourcesCurrent failed")
+	}
+	defer xrrFreeScreenResources(res)
+	info := xrrGetOutputInfo(w.display, res, mode.monitor.id)
+	if info == nil {
+		return errors.New("XRRGetOutputInfo failed")
+	}
+	crtcID := info.Crtc
+	xrrFreeOutputInfo(info)
+	if crtcID == 0 {
+		return fmt.Errorf("monitor %s is not active", mode.monitor.Name)
+	}
+	crtc := xrrGetCrtcInfo(w.display, res, crtcID)
+	if crtc == nil {
+		return errors.New("XRRGetCrtcInfo failed")
+	}
+	defer xrrFreeCrtcInfo(crtc)
+
+	width, height := int32(mode.Width), int32(mode.Height)
+	if crtc.Rotation&(rrRotate90|rrRotate270) != 0 {
+		width, height = height, width
+	}
+	if crtc.X+width > xDisplayWidth(w.display, w.screen) || crtc.Y+height > xDisplayHeight(w.display, w.screen) {
+		return fmt.Errorf("%dx%d does not fit in the X screen", mode.Width, mode.Height)
+	}
+
+	// Switching between monitors puts the first one back.
+	if w.savedCrtc != nil && w.savedCrtc.crtc != crtcID {
+		w.restoreMode()
+	}
+	outputs := append([]uintptr(nil), unsafe.Slice(crtc.Outputs, crtc.NOutput)...)
+	if len(outputs) == 0 {
+		return fmt.Errorf("monitor %s has no outputs on its CRTC", mode.monitor.Name)
+	}
+	if w.savedCrtc == nil {
+		w.savedCrtc = &crtcConfig{
+			crtc:     crtcID,
+			x:        crtc.X,
+			y:        crtc.Y,
+			mode:     crtc.Mode,
+			rotation: crtc.Rotation,
+			outputs:  outputs,
+		}
+	}
+	if status := xrrSetCrtcConfig(w.display, res, crtcID, currentTime, crtc.X, crtc.Y,
+		mode.id, crtc.Rotation, &outputs[0], int32(len(outputs))); status != 0 {
+		return fmt.Errorf("XRRSetCrtcConfig failed with status %d", status)
+	}
+
+	xMoveWindow(w.display, w.window, crtc.X, crtc.Y)
+	w.setFullscreen(true)
+	return nil
+}
+
+// restoreMode puts back the CRTC configuration SetFullscreenMode replaced.
+func (w *x11Window) restoreMode() {
+	saved := w.savedCrtc
+	if saved == nil {
+		return
+	}
+	w.savedCrtc = nil
+	res := xrrGetScreenResourcesCurrent(w.display, w.root)
+	if res == nil {
+		return
+	}
+	xrrSetCrtcConfig(w.display, res, saved.crtc, currentTime, saved.x, saved.y,
+		saved.mode, saved.rotation, &saved.outputs[0], int32(len(saved.outputs)))
+	xrrFreeScreenResources(res)
+	xFlush(w.display)
+}
+
+// setFullscreen adds or removes _NET_WM_STATE_FULLSCREEN by asking the
+// window manager, which makes the window cover the monitor it is on.
+func (w *x11Window) setFullscreen(on bool) {
+	action := uint64(netWMStateRemove)
+	if on {
+		action = netWMStateAdd
+	}
+	var ev xEvent
+	cm := (*xclientMessage)(unsafe.Pointer(&ev[0]))
+	cm.Type = clientMessage
+	cm.SendEvent = 1
+	cm.Display = w.display
+	cm.Window = w.window
+	cm.MessageType = xInternAtom(w.display, cString("_NET_WM_STATE"), 0)
+	cm.Format = 32
+	fullscreen := xInternAtom(w.display, cString("_NET_WM_STATE_FULLSCREEN"), 0)
+	cm.Data = [5]uint64{action, uint64(fullscreen), 0, netWMSourceApplication}
+	xSendEvent(w.display, w.root, 0, substructureRedirectMask|substructureNotifyMask, unsafe.Pointer(&ev[0]))
+	xFlush(w.display)
+}
+
 func (w *x11Window) BackingSize() (int, int) {
 	var root uintptr
 	var x, y int32
@@ -433,6 +1069,105 @@ func (w *x11Window) Scale() float32 {
 	return w.scale
 }
 
+// DisplayInfo describes the XRandR output containing the largest part of
+// the window: the size of its CRTC's mode and the physical size the output
+// reports. Without XRandR it describes the X screen, which spans every
+// monitor.
+func (w *x11Window) DisplayInfo() DisplayInfo {
+	if info, ok := w.randrDisplayInfo(); ok {
+		return info
+	}
+	return screenInfo(w.display, w.screen)
+}
+
+// randrDisplayInfo looks up the output for DisplayInfo.
+func (w *x11Window) randrDisplayInfo() (DisplayInfo, bool) {
+	if !loadXrandr() {
+		return DisplayInfo{}, false
+	}
+	var x, y int32
+	var child uintptr
+	if xTranslateCoordinates(w.display, w.window, w.root, 0, 0, &x, &y, &child) == 0 {
+		return DisplayInfo{}, false
+	}
+	width, height := w.BackingSize()
+	bounds := image.Rect(int(x), int(y), int(x)+width, int(y)+height)
+
+	res := xrrGetScreenResourcesCurrent(w.display, w.root)
+	if res == nil {
+		return DisplayInfo{}, false
+	}
+	defer xrrFreeScreenResources(res)
+	modes := unsafe.Slice(res.Modes, res.NMode)
+
+	var infos []DisplayInfo
+	var crtcs []image.Rectangle
+	for _, output := range unsafe.Slice(res.Outputs, res.NOutput) {
+		out := xrrGetOutputInfo(w.display, res, output)
+		if out == nil {
+			continue
+		}
+		if out.Crtc != 0 && out.Connection == rrConnected {
+			if crtc := xrrGetCrtcInfo(w.display, res, out.Crtc); crtc != nil {
+				info := DisplayInfo{
+					Width:    int(crtc.Width),
+					Height:   int(crtc.Height),
+					WidthMM:  int(out.MMWidth),
+					HeightMM: int(out.MMHeight),
+				}
+				// The mode and physical size are those of the unrotated
+				// output, while the CRTC's size is as the screen sees it.
+				rotated := crtc.Rotation&(rrRotate90|rrRotate270) != 0
+				for _, m := range modes {
+					if m.ID == crtc.Mode {
+						info.Width, info.Height = int(m.Width), int(m.Height)
+						if rotated {
+							info.Width, info.Height = info.Height, info.Width
+						}
+						break
+					}
+				}
+				if rotated {
+					info.WidthMM, info.HeightMM = info.HeightMM, info.WidthMM
+				}
+				infos = append(infos, info)
+				crtcs = append(crtcs, image.Rect(int(crtc.X), int(crtc.Y),
+					int(crtc.X)+int(crtc.Width), int(crtc.Y)+int(crtc.Height)))
+				xrrFreeCrtcInfo(crtc)
+			}
+		}
+		xrrFreeOutputInfo(out)
+	}
+	i := largestOverlap(bounds, crtcs)
+	if i < 0 {
+		return DisplayInfo{}, false
+	}
+	return infos[i].computeDPI(), true
+}
+
+// largestOverlap returns the index of the rectangle in rects sharing the
+// most area with r, the first one if none does, or -1 if rects is empty.
+func largestOverlap(r image.Rectangle, rects []image.Rectangle) int {
+	best, bestArea := -1, -1
+	for i, rect := range rects {
+		in := r.Intersect(rect)
+		if area := in.Dx() * in.Dy(); area > bestArea {
+			best, bestArea = i, area
+		}
+	}
+	return best
+}
+
+// screenInfo returns the size of an X screen.
+func screenInfo(dpy uintptr, screen int32) DisplayInfo {
+	return DisplayInfo{
+		Width:    int(xDisplayWidth(dpy, screen)),
+		Height:   int(xDisplayHeight(dpy, screen)),
+		WidthMM:  int(xDisplayWidthMM(dpy, screen)),
+		HeightMM: int(xDisplayHeightMM(dpy, screen)),
+	}.computeDPI()
+}
+
 func (w *x11Window) GetKeyState(key Key) KeyState {
 	if state, ok := w.keyStates[key]; ok {
 		return state
@@ -447,14 +1182,120 @@ func (w *x11Window) GetButtonState(button Button) ButtonState {
 	return ButtonStateUp
 }
 
-// keycodeToKey converts an X11 keycode to our Key enum
+func (w *x11Window) TextInput() string { return string(w.text) }
+
+// Composition is always empty: the input context asks the input method to
+// draw its own preedit window.
+func (w *x11Window) Composition() string { return "" }
+
+// openIM connects to the user's input method (XMODIFIERS) and creates an
+// input context for the window. Without one, text falls back to
+// XLookupString, which only knows Latin-1.
+func (w *x11Window) openIM() {
+	if !loadXIM() {
+		return
+	}
+	// Xlib picks the input method and text encoding from the C locale,
+	// which Go programs never set.
+	if libc, err := purego.Dlopen("libc.so.6", purego.RTLD_LAZY); err == nil {
+		var setlocale func(int32, *byte) *byte
+		purego.RegisterLibFunc(&setlocale, libc, "setlocale")
+		setlocale(lcCtype, cString(""))
+	}
+	if xSetLocaleModifiers(cString("")) == nil {
+		xSetLocaleModifiers(cString("@im=none"))
+	}
+	im := xOpenIM(w.display, 0, 0, 0)
+	if im == 0 {
+		// The configured input method isn't running; Xlib's built-in one
+		// still handles dead keys and compose sequences.
+		xSetLocaleModifiers(cString("@im=none"))
+		im = xOpenIM(w.display, 0, 0, 0)
+	}
+	if im == 0 {
+		return
+	}
+	ic := xCreateIC(im,
+		cString("inputStyle"), ximPreeditNothing|ximStatusNothing,
+		cString("clientWindow"), w.window,
+		cString("focusWindow"), w.window,
+		0)
+	if ic == 0 {
+		xCloseIM(im)
+		return
+	}
+	w.im, w.ic = im, ic
+	xSetICFocus(ic)
+}
+
+// lookupText appends the text produced by a key press to w.text.
+func (w *x11Window) lookupText(kev *xKeyEvent) {
+	var buf [64]byte
+	if w.ic != 0 {
+		var status int32
+		n := xutf8LookupString(w.ic, kev, &buf[0], int32(len(buf)), nil, &status)
+		text := buf[:max(n, 0)]
+		if status == xBufferOverflow {
+			text = make([]byte, n)
+			n = xutf8LookupString(w.ic, kev, &text[0], n, nil, &status)
+			text = text[:max(n, 0)]
+		}
+		if status == xLookupChars || status == xLookupBoth {
+			w.text = appendText(w.text, string(text))
+		}
+		return
+	}
+	if xLookupString == nil {
+		return
+	}
+	n := xLookupString(kev, &buf[0], int32(len(buf)), nil, nil)
+	runes := make([]rune, 0, n)
+	for _, b := range buf[:max(n, 0)] {
+		runes = append(runes, rune(b)) // Latin-1
+	}
+	w.text = appendText(w.text, string(runes))
+}
+
+// releaseAll moves every held key and button to Released, as if the user
+// had let go of them.
+func (w *x11Window) releaseAll() {
+	for key, state := range w.keyStates {
+		if state.IsDown() {
+			w.keyStates[key] = KeyStateReleased
+		}
+	}
+	for button, state := range w.buttonStates {
+		if state.IsDown() {
+			w.buttonStates[button] = ButtonStateReleased
+		}
+	}
+}
+
+// keycodeToKey converts an X11 keycode to our Key enum using the active
+// keyboard layout, so that e.g. KeyO is reported for the key that types 'o'
+// on Dvorak.
 func (w *x11Window) keycodeToKey(kev *xKeyEvent) Key {
+	if xkbKeycodeToKeysym != nil {
+		// Bits 13-14 of the state hold the active XKB group (layout).
+		group := int32(kev.State>>13) & 3
+		key := keysymToKey(xkbKeycodeToKeysym(w.display, uint8(kev.KeyCode), group, 0))
+		if key != KeyUnknown || group == 0 {
+			return key
+		}
+		// Non-Latin layouts have no keysym for most of our keys; fall back
+		// to the first group so letter shortcuts keep working.
+		return keysymToKey(xkbKeycodeToKeysym(w.display, uint8(kev.KeyCode), 0, 0))
+	}
 	if xLookupKeysym == nil {
 		return KeyUnknown
 	}
 
 	// Use XLookupKeysym with index 0 (no modifiers)
-	keysym := xLookupKeysym(kev, 0)
+	return keysymToKey(xLookupKeysym(kev, 0))
+}
+
+// keysymToKey maps an unshifted X11 keysym to our Key enum.
+func keysymToKey(keysym uint32) Key {
 	if keysym == 0 {
 		return KeyUnknown
 	}
@@ -681,6 +1522,205 @@ func (w *x11Window) buttonToButton(x11Button uint32) Button {
 	}
 }
 
+// Monitors returns the monitors of the default X screen, using XRandR when
+// it is available. X11 has a single scale factor for the whole screen, so
+// every monitor reports the same Scale.
+func Monitors() ([]Monitor, error) {
+	if err := ensureLibs(); err != nil {
+		return nil, err
+	}
+	dpy, err := openDisplay()
+	if err != nil {
+		return nil, err
+	}
+	defer xCloseDisplay(dpy)
+
+	screen := xDefaultScreen(dpy)
+	scale := calculateScale(dpy, screen)
+	if mons := randrMonitors(dpy, xRootWindow(dpy, screen), scale); len(mons) > 0 {
+		return mons, nil
+	}
+	info := screenInfo(dpy, screen)
+	return []Monitor{{
+		Name:    "default",
+		Width:   info.Width,
+		Height:  info.Height,
+		Scale:   scale,
+		Primary: true,
+	}}, nil
+}
+
+// randrMonitors lists the active XRandR outputs, or returns nil if XRandR
+// is unavailable.
+func randrMonitors(dpy, root uintptr, scale float32) []Monitor {
+	if !loadXrandr() {
+		return nil
+	}
+	res := xrrGetScreenResourcesCurrent(dpy, root)
+	if res == nil {
+		return nil
+	}
+	defer xrrFreeScreenResources(res)
+
+	primary := xrrGetOutputPrimary(dpy, root)
+	var mons []Monitor
+	for _, output := range unsafe.Slice(res.Outputs, res.NOutput) {
+		info := xrrGetOutputInfo(dpy, res, output)
+		if info == nil {
+			continue
+		}
+		if info.Crtc != 0 && info.Connection == rrConnected {
+			if crtc := xrrGetCrtcInfo(dpy, res, info.Crtc); crtc != nil {
+				mons = append(mons, Monitor{
+					Name:    string(unsafe.Slice(info.Name, info.NameLen)),
+					X:       int(crtc.X),
+					Y:       int(crtc.Y),
+					Width:   int(crtc.Width),
+					Height:  int(crtc.Height),
+					Scale:   scale,
+					Primary: output == primary,
+					id:      output,
+				})
+				xrrFreeCrtcInfo(crtc)
+			}
+		}
+		xrrFreeOutputInfo(info)
+	}
+	return mons
+}
+
+// monitorModes lists the XRandR modes of m's output.
+func monitorModes(m *Monitor) ([]DisplayMode, error) {
+	if err := ensureLibs(); err != nil {
+		return nil, err
+	}
+	if !loadXrandr() {
+		return nil, errors.New("display modes need XRandR")
+	}
+	if m.id == 0 {
+		return nil, errors.New("monitor has no XRandR output")
+	}
+	dpy, err := openDisplay()
+	if err != nil {
+		return nil, err
+	}
+	defer xCloseDisplay(dpy)
+
+	res := xrrGetScreenResourcesCurrent(dpy, xRootWindow(dpy, xDefaultScreen(dpy)))
+	if res == nil {
+		return nil, errors.New("XRRGetScreenResourcesCurrent failed")
+	}
+	defer xrrFreeScreenResources(res)
+	info := xrrGetOutputInfo(dpy, res, m.id)
+	if info == nil {
+		return nil, errors.New("XRRGetOutputInfo failed")
+	}
+	defer xrrFreeOutputInfo(info)
+
+	all := unsafe.Slice(res.Modes, res.NMode)
+	var modes []DisplayMode
+	for _, id := range unsafe.Slice(info.Modes, info.NMode) {
+		for i := range all {
+			if mi := &all[i]; mi.ID == id {
+				modes = append(modes, DisplayMode{
+					Width:       int(mi.Width),
+					Height:      int(mi.Height),
+					RefreshRate: mi.refreshRate(),
+					monitor:     *m,
+					id:          id,
+				})
+				break
+			}
+		}
+	}
+	return modes, nil
+}
+
+// refreshRate computes the vertical refresh rate of the mode in Hz from
+// its timings, or returns zero if they are missing.
+func (mi *xrrModeInfo) refreshRate() float64 {
+	vTotal := float64(mi.VTotal)
+	if mi.ModeFlags&rrDoubleScan != 0 {
+		vTotal *= 2
+	}
+	if mi.ModeFlags&rrInterlace != 0 {
+		vTotal /= 2
+	}
+	if mi.HTotal == 0 || vTotal == 0 {
+		return 0
+	}
+	return float64(mi.DotClock) / (float64(mi.HTotal) * vTotal)
+}
+
+func loadXIM() bool {
+	ximOnce.Do(func() {
+		if _, err := purego.Dlsym(x11lib, "XLookupString"); err == nil {
+			purego.RegisterLibFunc(&xLookupString, x11lib, "XLookupString")
+		}
+		if _, err := purego.Dlsym(x11lib, "Xutf8LookupString"); err != nil {
+			return
+		}
+		purego.RegisterLibFunc(&xSetLocaleModifiers, x11lib, "XSetLocaleModifiers")
+		purego.RegisterLibFunc(&xOpenIM, x11lib, "XOpenIM")
+		purego.RegisterLibFunc(&xCloseIM, x11lib, "XCloseIM")
+		purego.RegisterLibFunc(&xCreateIC, x11lib, "XCreateIC")
+		purego.RegisterLibFunc(&xDestroyIC, x11lib, "XDestroyIC")
+		purego.RegisterLibFunc(&xSetICFocus, x11lib, "XSetICFocus")
+		purego.RegisterLibFunc(&xUnsetICFocus, x11lib, "XUnsetICFocus")
+		purego.RegisterLibFunc(&xFilterEvent, x11lib, "XFilterEvent")
+		purego.RegisterLibFunc(&xutf8LookupString, x11lib, "Xutf8LookupString")
+	})
+	return xutf8LookupString != nil
+}
+
+func loadXrandr() bool {
+	xrandrOnce.Do(func() {
+		lib, err := purego.Dlopen("libXrandr.so.2", purego.RTLD_LAZY|purego.RTLD_GLOBAL)
+		if err != nil {
+			return
+		}
+		purego.RegisterLibFunc(&xrrGetScreenResourcesCurrent, lib, "XRRGetScreenResourcesCurrent")
+		purego.RegisterLibFunc(&xrrFreeScreenResources, lib, "XRRFreeScreenResources")
+		purego.RegisterLibFunc(&xrrGetOutputInfo, lib, "XRRGetOutputInfo")
+		purego.RegisterLibFunc(&xrrFreeOutputInfo, lib, "XRRFreeOutputInfo")
+		purego.RegisterLibFunc(&xrrGetCrtcInfo, lib, "XRRGetCrtcInfo")
+		purego.RegisterLibFunc(&xrrFreeCrtcInfo, lib, "XRRFreeCrtcInfo")
+		purego.RegisterLibFunc(&xrrGetOutputPrimary, lib, "XRRGetOutputPrimary")
+		purego.RegisterLibFunc(&xrrSetCrtcConfig, lib, "XRRSetCrtcConfig")
+		xrandrLib = lib
+	})
+	return xrandrLib != 0
+}
+
+// resourceManagerString returns the X resource database of the screen.
+// XResourceManagerString only returns the copy Xlib fetched when the display
+// was opened, so the RESOURCE_MANAGER property is read directly when possible
+// to pick up later changes.
+func resourceManagerString(dpy uintptr, screen int32) string {
+	if xGetWindowProperty != nil && xFree != nil {
+		atom := xInternAtom(dpy, cString("RESOURCE_MANAGER"), 1)
+		if atom != 0 {
+			var actualType uintptr
+			var actualFormat int32
+			var nitems, bytesAfter uint64
+			var data *byte
+			if xGetWindowProperty(dpy, xRootWindow(dpy, screen), atom, 0, 1<<20, 0, xaString,
+				&actualType, &actualFormat, &nitems, &bytesAfter, &data) == 0 && data != nil {
+				defer xFree(unsafe.Pointer(data))
+				if actualType == xaString && actualFormat == 8 {
+					return string(unsafe.Slice(data, nitems))
+				}
+			}
+		}
+	}
+	if xResourceManagerString != nil {
+		if rmString := xResourceManagerString(dpy); rmString != nil {
+			return gostring(rmString)
+		}
+	}
+	return ""
+}
+
 // calculateScale calculates the display scale factor based on DPI.
 // It tries multiple methods in order of reliability:
 // 1. GTK_SCALE environment variable (common on Wayland)
@@ -702,35 +1742,21 @@ func calculateScale(dpy uintptr, screen int32) float32 {
 	}
 
 	// Try to get Xft.dpi from X resources (for X11)
-	if xResourceManagerString != nil {
-		rmString := xResourceManagerString(dpy)
-		if rmString != nil {
-			// Parse Xft.dpi from resource string
-			// Format is like: "Xft.dpi:\t96\n..."
-			rmStr := gostring(rmString)
-			if dpi := parseXftDPI(rmStr); dpi > 0 {
-				scale := dpi / 96.0
-				// Round to common scale factors (1.0, 1.25, 1.5, 2.0, etc.)
-				return roundScale(scale)
-			}
+	if rmStr := resourceManagerString(dpy, screen); rmStr != "" {
+		// Parse Xft.dpi from resource string
+		// Format is like: "Xft.dpi:\t96\n..."
+		if dpi := parseXftDPI(rmStr); dpi > 0 {
+			scale := dpi / 96.0
+			// Round to common scale factors (1.0, 1.25, 1.5, 2.0, etc.)
+			return roundScale(scale)
 		}
 	}
 
 	// Fall back to calculating DPI from physical dimensions
-	if xDisplayWidth != nil && xDisplayWidthMM != nil {
-		widthPx := xDisplayWidth(dpy, screen)
-		widthMM := xDisplayWidthMM(dpy, screen)
-
-		if widthMM > 0 && widthPx > 0 {
-			// Calculate DPI: (pixels / millimeters) * 25.4 mm/inch
-			dpi := (float32(widthPx) / float32(widthMM)) * 25.4
-
-			// Only use this if it's reasonable (between 72 and 300 DPI)
-			if dpi >= 72 && dpi <= 300 {
-				scale := dpi / 96.0
-				return roundScale(scale)
-			}
-		}
+	if dpi := screenInfo(dpy, screen).DPI; dpi >= 72 && dpi <= 300 {
+		// Only use this if it's reasonable (between 72 and 300 DPI)
+		scale := dpi / 96.0
+		return roundScale(scale)
 	}
 
 	// Default to 1.0 if we can't determine scale
@@ -880,41 +1906,69 @@ func ensureLibs() error {
 	if x11lib == 0 {
 		x11lib, err = purego.Dlopen("libX11.so.6", purego.RTLD_LAZY|purego.RTLD_GLOBAL)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: cannot load libX11.so.6 (install libx11): %w", ErrNoDisplay, err)
 		}
 		registerX11()
+		// A shared context makes GLX calls on the display from a second
+		// thread, which Xlib only allows if told before anything else.
+		xInitThreads()
 	}
 	if gllib == 0 {
 		gllib, err = purego.Dlopen("libGL.so.1", purego.RTLD_LAZY|purego.RTLD_GLOBAL)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: cannot load libGL.so.1 (install a GL driver such as Mesa): %w", ErrNoGL, err)
 		}
 		registerGLX()
 	}
 	return nil
 }
 
+// openDisplay connects to the X server named by DISPLAY.
+func openDisplay() (uintptr, error) {
+	name := os.Getenv("DISPLAY")
+	if name == "" {
+		return 0, fmt.Errorf("%w: no X11 display found; set DISPLAY", ErrNoDisplay)
+	}
+	dpy := xOpenDisplay(nil)
+	if dpy == 0 {
+		return 0, fmt.Errorf("%w: cannot open X11 display %q", ErrNoDisplay, name)
+	}
+	return dpy, nil
+}
+
 func registerX11() {
+	purego.RegisterLibFunc(&xInitThreads, x11lib, "XInitThreads")
 	purego.RegisterLibFunc(&xOpenDisplay, x11lib, "XOpenDisplay")
 	purego.RegisterLibFunc(&xDefaultScreen, x11lib, "XDefaultScreen")
 	purego.RegisterLibFunc(&xRootWindow, x11lib, "XRootWindow")
 	purego.RegisterLibFunc(&xCreateColormap, x11lib, "XCreateColormap")
 	purego.RegisterLibFunc(&xCreateWindow, x11lib, "XCreateWindow")
 	purego.RegisterLibFunc(&xMapWindow, x11lib, "XMapWindow")
+	purego.RegisterLibFunc(&xUnmapWindow, x11lib, "XUnmapWindow")
+	purego.RegisterLibFunc(&xChangeProperty, x11lib, "XChangeProperty")
+	purego.RegisterLibFunc(&xDeleteProperty, x11lib, "XDeleteProperty")
 	purego.RegisterLibFunc(&xStoreName, x11lib, "XStoreName")
 	purego.RegisterLibFunc(&xInternAtom, x11lib, "XInternAtom")
 	purego.RegisterLibFunc(&xSetWMProtocols, x11lib, "XSetWMProtocols")
 	purego.RegisterLibFunc(&xSelectInput, x11lib, "XSelectInput")
 	purego.RegisterLibFunc(&xPending, x11lib, "XPending")
+	purego.RegisterLibFunc(&xConnectionNumber, x11lib, "XConnectionNumber")
 	purego.RegisterLibFunc(&xNextEvent, x11lib, "XNextEvent")
 	purego.RegisterLibFunc(&xGetGeometry, x11lib, "XGetGeometry")
 	purego.RegisterLibFunc(&xDestroyWindow, x11lib, "XDestroyWindow")
 	purego.RegisterLibFunc(&xCloseDisplay, x11lib, "XCloseDisplay")
 	purego.RegisterLibFunc(&xQueryPointer, x11lib, "XQueryPointer")
+	purego.RegisterLibFunc(&xTranslateCoordinates, x11lib, "XTranslateCoordinates")
 	purego.RegisterLibFunc(&xDisplayWidth, x11lib, "XDisplayWidth")
 	purego.RegisterLibFunc(&xDisplayWidthMM, x11lib, "XDisplayWidthMM")
 	purego.RegisterLibFunc(&xDisplayHeight, x11lib, "XDisplayHeight")
 	purego.RegisterLibFunc(&xDisplayHeightMM, x11lib, "XDisplayHeightMM")
+	purego.RegisterLibFunc(&xGetWindowProperty, x11lib, "XGetWindowProperty")
+	purego.RegisterLibFunc(&xFree, x11lib, "XFree")
+	purego.RegisterLibFunc(&xMoveWindow, x11lib, "XMoveWindow")
+	purego.RegisterLibFunc(&xSendEvent, x11lib, "XSendEvent")
+	purego.RegisterLibFunc(&xUngrabPointer, x11lib, "XUngrabPointer")
+	purego.RegisterLibFunc(&xFlush, x11lib, "XFlush")
 	// Try to register XResourceManagerString, but don't fail if it's not available
 	if _, err := purego.Dlsym(x11lib, "XResourceManagerString"); err == nil {
 		purego.RegisterLibFunc(&xResourceManagerString, x11lib, "XResourceManagerString")
@@ -929,6 +1983,12 @@ func registerX11() {
 		// Function not available, key mapping will be limited
 		xLookupKeysym = nil
 	}
+	if _, err := purego.Dlsym(x11lib, "XkbKeycodeToKeysym"); err == nil {
+		purego.RegisterLibFunc(&xkbKeycodeToKeysym, x11lib, "XkbKeycodeToKeysym")
+	}
+	if _, err := purego.Dlsym(x11lib, "XRefreshKeyboardMapping"); err == nil {
+		purego.RegisterLibFunc(&xRefreshKeyboardMapping, x11lib, "XRefreshKeyboardMapping")
+	}
 }
 
 func registerGLX() {
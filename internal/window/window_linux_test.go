@@ -0,0 +1,146 @@
+package window
+
+import (
+	"image"
+	"sync"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// newTestWindow returns an x11Window with no X connection, for feeding
+// events to handleEvent.
+func newTestWindow() *x11Window {
+	return &x11Window{
+		running:      true,
+		focused:      true,
+		keyStates:    make(map[Key]KeyState),
+		buttonStates: make(map[Button]ButtonState),
+	}
+}
+
+func eventOfType(etype int32) *xEvent {
+	var ev xEvent
+	*(*int32)(unsafe.Pointer(&ev[0])) = etype
+	return &ev
+}
+
+func buttonEvent(etype int32, button uint32) *xEvent {
+	ev := eventOfType(etype)
+	(*xButtonEvent)(unsafe.Pointer(&ev[0])).Button = button
+	return ev
+}
+
+func TestFocusOutReleasesInput(t *testing.T) {
+	w := newTestWindow()
+	// A held key, one pressed this frame, and one already up.
+	w.keyStates[KeyA] = KeyStateDown
+	w.keyStates[KeyLeftShift] = KeyStatePressed
+	w.keyStates[KeyB] = KeyStateUp
+	w.handleEvent(buttonEvent(buttonPress, 1))
+	w.handleEvent(buttonEvent(buttonPress, 3))
+	w.advanceInput()
+	w.handleEvent(buttonEvent(buttonRelease, 3))
+
+	w.handleEvent(eventOfType(focusOut))
+	if w.Focused() {
+		t.Error("still focused after FocusOut")
+	}
+	for key, want := range map[Key]KeyState{
+		KeyA:         KeyStateReleased,
+		KeyLeftShift: KeyStateReleased,
+		KeyB:         KeyStateUp,
+	} {
+		if got := w.GetKeyState(key); got != want {
+			t.Errorf("key %v is %v after FocusOut, want %v", key, got, want)
+		}
+	}
+	for button, want := range map[Button]ButtonState{
+		ButtonLeft:   ButtonStateReleased,
+		ButtonRight:  ButtonStateReleased,
+		ButtonMiddle: ButtonStateUp,
+	} {
+		if got := w.GetButtonState(button); got != want {
+			t.Errorf("button %v is %v after FocusOut, want %v", button, got, want)
+		}
+	}
+
+	// The next Poll sees everything up, not stuck down.
+	w.advanceInput()
+	if w.GetKeyState(KeyA).IsDown() || w.GetButtonState(ButtonLeft).IsDown() {
+		t.Error("input still held a frame after FocusOut")
+	}
+
+	w.handleEvent(eventOfType(focusIn))
+	if !w.Focused() {
+		t.Error("not focused after FocusIn")
+	}
+}
+
+func TestWakeDuringClose(t *testing.T) {
+	w := newTestWindow()
+	var p [2]int
+	if err := syscall.Pipe2(p[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.epfd, w.wakeR, w.wakeW = epfd, p[0], p[1]
+
+	// Wakers racing Close must neither write to a closed (and possibly
+	// reused) descriptor nor trip the race detector.
+	var wg, started sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Wake()
+			started.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					w.Wake()
+				}
+			}
+		}()
+	}
+	started.Wait()
+	w.Close()
+	close(stop)
+	wg.Wait()
+
+	if w.epfd != -1 {
+		t.Errorf("epfd = %d after Close, want -1", w.epfd)
+	}
+	w.Wake()
+}
+
+func TestLargestOverlap(t *testing.T) {
+	left := image.Rect(0, 0, 1920, 1080)
+	right := image.Rect(1920, 0, 3840, 1080)
+	tests := []struct {
+		name  string
+		r     image.Rectangle
+		rects []image.Rectangle
+		want  int
+	}{
+		{"no monitors", image.Rect(0, 0, 100, 100), nil, -1},
+		{"inside one", image.Rect(2000, 100, 2100, 200), []image.Rectangle{left, right}, 1},
+		{"mostly left", image.Rect(1800, 0, 1950, 100), []image.Rectangle{left, right}, 0},
+		{"mostly right", image.Rect(1890, 0, 2040, 100), []image.Rectangle{left, right}, 1},
+		{"off screen", image.Rect(-500, -500, -400, -400), []image.Rectangle{left, right}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := largestOverlap(tt.r, tt.rects); got != tt.want {
+				t.Errorf("largestOverlap(%v) = %d, want %d", tt.r, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sync"
 	"syscall"
+	"unicode/utf16"
 	"unsafe"
 
 	"github.com/tinyrange/gowin/internal/gl"
@@ -22,26 +24,95 @@ const (
 	wsClipSiblings     = 0x04000000
 	wsClipChildren     = 0x02000000
 	swShow             = 5
+	swHide             = 0
 
+	wmNull    = 0x0000
 	wmClose   = 0x0010
 	wmDestroy = 0x0002
 	pmRemove  = 0x0001
 
+	wmDPIChanged = 0x02E0
+	defaultDPI   = 96
+
+	// DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2, (DPI_AWARENESS_CONTEXT)-4,
+	// and PROCESS_PER_MONITOR_DPI_AWARE for the older API.
+	dpiAwarenessContextPerMonitorAwareV2 = ^uintptr(3)
+	processPerMonitorDPIAware            = 2
+
+	wmChar              = 0x0102
+	wmImeEndComposition = 0x010E
+	wmImeComposition    = 0x010F
+	gcsCompStr          = 0x0008
+	gcsResultStr        = 0x0800
+
+	wmNCLButtonDown = 0x00A1
+	htCaption       = 2
+
+	swpNoSize     = 0x0001
+	swpNoMove     = 0x0002
+	swpNoZOrder   = 0x0004
+	swpNoActivate = 0x0010
+
+	gwlExStyle  = -20
+	wsExLayered = 0x00080000
+	lwaAlpha    = 0x00000002
+
+	// Fullscreen display modes.
+	gwlStyle             = -16
+	wsPopup              = 0x80000000
+	swpFrameChanged      = 0x0020
+	swpNoOwnerZOrder     = 0x0200
+	enumCurrentSettings  = 0xFFFFFFFF
+	cdsFullscreen        = 0x00000004
+	dispChangeSuccessful = 0
+	dmBitsPerPel         = 0x00040000
+	dmPelsWidth          = 0x00080000
+	dmPelsHeight         = 0x00100000
+	dmDisplayFrequency   = 0x00400000
+
+	monitorDefaultToNearest = 2
+	monitorInfoFPrimary     = 1
+	mdtEffectiveDPI         = 0
+
+	// GetDeviceCaps indices.
+	horzSize = 4
+	vertSize = 6
+
 	pfdTypeRGBA      = 0
 	pfdMainPlane     = 0
 	pfdDrawToWindow  = 0x00000004
 	pfdSupportOpenGL = 0x00000020
 	pfdDoubleBuffer  = 0x00000001
 
+	pfdSupportComposition = 0x00008000
+
+	dwmBBEnable     = 0x00000001
+	dwmBBBlurRegion = 0x00000002
+
 	cwUseDefault = 0x80000000
 
 	errorClassAlreadyExists = 1410
 
 	// WGL_ARB_create_context constants
-	wglContextMajorVersionArb   = 0x2091
-	wglContextMinorVersionArb   = 0x2092
-	wglContextFlagsArb          = 0x2094
-	wglContextCoreProfileBitArb = 0x00000001
+	wglContextMajorVersionArb     = 0x2091
+	wglContextMinorVersionArb     = 0x2092
+	wglContextFlagsArb            = 0x2094
+	wglContextProfileMaskArb      = 0x9126
+	wglContextCoreProfileBitArb   = 0x00000001
+	wglContextCompatProfileBitArb = 0x00000002
+
+	// WGL_ARB_pixel_format / WGL_ARB_multisample constants
+	wglDrawToWindowArb  = 0x2001
+	wglSupportOpenGLArb = 0x2010
+	wglDoubleBufferArb  = 0x2011
+	wglPixelTypeArb     = 0x2013
+	wglColorBitsArb     = 0x2014
+	wglAlphaBitsArb     = 0x201B
+	wglDepthBitsArb     = 0x2022
+	wglStencilBitsArb   = 0x2023
+	wglTypeRGBAArb      = 0x202B
+	wglSampleBuffersArb = 0x2041
+	wglSamplesArb       = 0x2042
 )
 
 type (
@@ -87,6 +158,60 @@ type rect struct {
 	bottom int32
 }
 
+// Mirrors DWM_BLURBEHIND.
+type dwmBlurBehind struct {
+	dwFlags                uint32
+	fEnable                int32
+	hRgnBlur               syscall.Handle
+	fTransitionOnMaximized int32
+}
+
+type monitorInfo struct {
+	cbSize    uint32
+	rcMonitor rect
+	rcWork    rect
+	dwFlags   uint32
+}
+
+// Mirrors DEVMODEW with the display fields of its unions.
+type devMode struct {
+	dmDeviceName       [32]uint16
+	dmSpecVersion      uint16
+	dmDriverVersion    uint16
+	dmSize             uint16
+	dmDriverExtra      uint16
+	dmFields           uint32
+	dmPosition         [2]int32
+	dmDisplayOrient    uint32
+	dmDisplayFixed     uint32
+	dmColor            int16
+	dmDuplex           int16
+	dmYResolution      int16
+	dmTTOption         int16
+	dmCollate          int16
+	dmFormName         [32]uint16
+	dmLogPixels        uint16
+	dmBitsPerPel       uint32
+	dmPelsWidth        uint32
+	dmPelsHeight       uint32
+	dmDisplayFlags     uint32
+	dmDisplayFrequency uint32
+	dmICMMethod        uint32
+	dmICMIntent        uint32
+	dmMediaType        uint32
+	dmDitherType       uint32
+	dmReserved1        uint32
+	dmReserved2        uint32
+	dmPanningWidth     uint32
+	dmPanningHeight    uint32
+}
+
+// Mirrors MONITORINFOEXW.
+type monitorInfoEx struct {
+	monitorInfo
+	szDevice [32]uint16
+}
+
 // Mirrors PIXELFORMATDESCRIPTOR (must be 40 bytes).
 type pixelFormatDescriptor struct {
 	nSize           uint16
@@ -122,6 +247,9 @@ var (
 	gdi32    = syscall.NewLazyDLL("gdi32.dll")
 	opengl32 = syscall.NewLazyDLL("opengl32.dll")
 	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	shcore   = syscall.NewLazyDLL("shcore.dll")
+	dwmapi   = syscall.NewLazyDLL("dwmapi.dll")
+	imm32    = syscall.NewLazyDLL("imm32.dll")
 
 	procRegisterClassEx  = user32.NewProc("RegisterClassExW")
 	procCreateWindowEx   = user32.NewProc("CreateWindowExW")
@@ -130,6 +258,8 @@ var (
 	procShowWindow       = user32.NewProc("ShowWindow")
 	procGetClientRect    = user32.NewProc("GetClientRect")
 	procPeekMessage      = user32.NewProc("PeekMessageW")
+	procWaitMessage      = user32.NewProc("WaitMessage")
+	procPostMessage      = user32.NewProc("PostMessageW")
 	procTranslateMessage = user32.NewProc("TranslateMessage")
 	procDispatchMessage  = user32.NewProc("DispatchMessageW")
 	procPostQuitMessage  = user32.NewProc("PostQuitMessage")
@@ -140,6 +270,28 @@ var (
 	procUpdateWindow     = user32.NewProc("UpdateWindow")
 	procWindowFromDC     = user32.NewProc("WindowFromDC")
 	procLoadCursor       = user32.NewProc("LoadCursorW")
+	procSetWindowPos     = user32.NewProc("SetWindowPos")
+	procGetDpiForWindow  = user32.NewProc("GetDpiForWindow") // Windows 10 1607+
+
+	procSetProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext") // Windows 10 1703+
+	procSetProcessDpiAwareness        = shcore.NewProc("SetProcessDpiAwareness")        // Windows 8.1+
+
+	procSendMessage         = user32.NewProc("SendMessageW")
+	procReleaseCapture      = user32.NewProc("ReleaseCapture")
+	procGetForegroundWindow = user32.NewProc("GetForegroundWindow")
+	procIsIconic            = user32.NewProc("IsIconic")
+
+	procGetWindowLongPtr           = user32.NewProc("GetWindowLongPtrW")
+	procSetWindowLongPtr           = user32.NewProc("SetWindowLongPtrW")
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes")
+	procGetWindowRect              = user32.NewProc("GetWindowRect")
+	procEnumDisplaySettings        = user32.NewProc("EnumDisplaySettingsW")
+	procChangeDisplaySettingsEx    = user32.NewProc("ChangeDisplaySettingsExW")
+
+	procMonitorFromWindow   = user32.NewProc("MonitorFromWindow")
+	procGetMonitorInfo      = user32.NewProc("GetMonitorInfoW")
+	procEnumDisplayMonitors = user32.NewProc("EnumDisplayMonitors")
+	procGetDpiForMonitor    = shcore.NewProc("GetDpiForMonitor") // Windows 8.1+
 
 	procChoosePixelFormat   = gdi32.NewProc("ChoosePixelFormat")
 	procDescribePixelFormat = gdi32.NewProc("DescribePixelFormat")
@@ -147,11 +299,21 @@ var (
 	procSetPixelFormat      = gdi32.NewProc("SetPixelFormat")
 	procSwapBuffers         = gdi32.NewProc("SwapBuffers")
 	procGetObjectType       = gdi32.NewProc("GetObjectType")
+	procGetDeviceCaps       = gdi32.NewProc("GetDeviceCaps")
+	procCreateRectRgn       = gdi32.NewProc("CreateRectRgn")
+	procDeleteObject        = gdi32.NewProc("DeleteObject")
+
+	procDwmEnableBlurBehindWindow = dwmapi.NewProc("DwmEnableBlurBehindWindow")
+
+	procImmGetContext            = imm32.NewProc("ImmGetContext")
+	procImmReleaseContext        = imm32.NewProc("ImmReleaseContext")
+	procImmGetCompositionStringW = imm32.NewProc("ImmGetCompositionStringW")
 
 	procWglCreateContext  = opengl32.NewProc("wglCreateContext")
 	procWglMakeCurrent    = opengl32.NewProc("wglMakeCurrent")
 	procWglDeleteContext  = opengl32.NewProc("wglDeleteContext")
 	procWglGetProcAddress = opengl32.NewProc("wglGetProcAddress")
+	procWglShareLists     = opengl32.NewProc("wglShareLists")
 
 	procGetModuleHandle = kernel32.NewProc("GetModuleHandleW")
 	procSetLastError    = kernel32.NewProc("SetLastError")
@@ -222,10 +384,25 @@ type winWindow struct {
 	hdc     hdc
 	ctx     hglrc
 	running bool
+	dpi     uint32 // updated by WM_DPICHANGED
+	opts    Options
+
+	// Text from WM_CHAR since the last Poll, the IME's uncommitted text,
+	// and the first half of a surrogate pair waiting for its second.
+	text          []byte
+	composition   string
+	highSurrogate uint16
+
+	// While SetFullscreenMode has changed a monitor's mode: its device
+	// name, and the window style and rectangle to restore afterwards.
+	fullscreenDevice string
+	savedStyle       uintptr
+	savedRect        rect
 }
 
-func New(title string, width, height int, _ bool) (Window, error) {
+func New(title string, width, height int, opts Options) (Window, error) {
 	runtime.LockOSThread()
+	enableDPIAwareness()
 
 	if unsafe.Sizeof(pixelFormatDescriptor{}) != 40 {
 		runtime.UnlockOSThread()
@@ -240,6 +417,13 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		return nil, err
 	}
 
+	// A window's pixel format can only be set once, so a multisampled format
+	// has to be found through a throwaway window before the real one exists.
+	var msaaFormat int32
+	if opts.Samples > 0 {
+		msaaFormat = chooseMultisamplePixelFormat(opts.Samples)
+	}
+
 	hwd, hdc, err := createWindow(title, width, height)
 	if err != nil {
 		runtime.UnlockOSThread()
@@ -260,14 +444,18 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		)
 	}
 
-	if _, _, err := chooseAndSetPixelFormat(hdc); err != nil {
+	if msaaFormat == 0 || setPixelFormat(hdc, msaaFormat) != nil {
+		// No multisampled format, or it could not be applied: use a plain one.
+		_, _, err = chooseAndSetPixelFormat(hdc)
+	}
+	if err != nil {
 		procReleaseDC.Call(uintptr(hwd), uintptr(hdc))
 		procDestroyWindow.Call(uintptr(hwd))
 		runtime.UnlockOSThread()
 		return nil, err
 	}
 
-	ctx, err := createGLContext(hdc)
+	ctx, err := createGLContext(hdc, opts)
 	if err != nil {
 		procReleaseDC.Call(uintptr(hwd), uintptr(hdc))
 		procDestroyWindow.Call(uintptr(hwd))
@@ -275,11 +463,33 @@ func New(title string, width, height int, _ bool) (Window, error) {
 		return nil, err
 	}
 
+	// width and height are logical pixels, and the window was created at
+	// that many physical ones.
+	dpi := windowDPI(hwd)
+	if dpi != defaultDPI {
+		width = width * int(dpi) / defaultDPI
+		height = height * int(dpi) / defaultDPI
+		procSetWindowPos.Call(uintptr(hwd), 0, 0, 0, uintptr(width), uintptr(height),
+			swpNoMove|swpNoZOrder|swpNoActivate)
+	}
+
+	if opts.Monitor != nil {
+		x, y := opts.Monitor.center(width, height)
+		procSetWindowPos.Call(uintptr(hwd), 0, uintptr(x), uintptr(y), 0, 0,
+			swpNoSize|swpNoZOrder|swpNoActivate)
+	}
+
+	if opts.Transparent {
+		enableTransparency(hwd)
+	}
+
 	// Show only after pixel format + context are established.
-	procShowWindow.Call(uintptr(hwd), swShow)
-	procUpdateWindow.Call(uintptr(hwd))
+	if !opts.StartHidden {
+		procShowWindow.Call(uintptr(hwd), swShow)
+		procUpdateWindow.Call(uintptr(hwd))
+	}
 
-	win := &winWindow{hwnd: hwd, hdc: hdc, ctx: ctx, running: true}
+	win := &winWindow{hwnd: hwd, hdc: hdc, ctx: ctx, running: true, dpi: dpi, opts: opts}
 	currentWin = win
 
 	return win, nil
@@ -290,6 +500,7 @@ func (w *winWindow) GL() (gl.OpenGL, error) {
 }
 
 func (w *winWindow) Close() {
+	w.restoreMode()
 	if w.ctx != 0 {
 		procWglMakeCurrent.Call(uintptr(w.hdc), 0)
 		procWglDeleteContext.Call(uintptr(w.ctx))
@@ -312,6 +523,8 @@ func (w *winWindow) Poll() bool {
 		return false
 	}
 
+	w.text = w.text[:0]
+
 	var m msg
 	for {
 		ret, _, _ := procPeekMessage.Call(
@@ -334,12 +547,231 @@ func (w *winWindow) Poll() bool {
 	return w.running
 }
 
+func (w *winWindow) Wait() {
+	if w.running {
+		procWaitMessage.Call()
+	}
+}
+
+func (w *winWindow) Wake() {
+	if w.hwnd != 0 {
+		// PostMessage is safe from any thread; the null message only serves
+		// to end WaitMessage.
+		procPostMessage.Call(uintptr(w.hwnd), wmNull, 0, 0)
+	}
+}
+
 func (w *winWindow) Swap() {
 	if w.hdc != 0 {
 		procSwapBuffers.Call(uintptr(w.hdc))
 	}
 }
 
+func (w *winWindow) Show() {
+	procShowWindow.Call(uintptr(w.hwnd), swShow)
+	procUpdateWindow.Call(uintptr(w.hwnd))
+}
+
+func (w *winWindow) Hide() {
+	procShowWindow.Call(uintptr(w.hwnd), swHide)
+}
+
+// Focused reports whether the window is the foreground window. A window
+// being minimized can still be foreground for a moment, so that is checked
+// too.
+func (w *winWindow) Focused() bool {
+	fg, _, _ := procGetForegroundWindow.Call()
+	if fg != uintptr(w.hwnd) {
+		return false
+	}
+	iconic, _, _ := procIsIconic.Call(uintptr(w.hwnd))
+	return iconic == 0
+}
+
+// MakeCurrent binds the WGL context to the window's device context on the
+// calling thread.
+func (w *winWindow) MakeCurrent() error {
+	if ret, _, err := procWglMakeCurrent.Call(uintptr(w.hdc), uintptr(w.ctx)); ret == 0 {
+		return fmt.Errorf("wglMakeCurrent failed: %w", err)
+	}
+	return nil
+}
+
+// ClearCurrent releases the calling thread's current WGL context.
+func (w *winWindow) ClearCurrent() {
+	procWglMakeCurrent.Call(0, 0)
+}
+
+// NewSharedContext creates a context like the window's and joins it to the
+// window's share list with wglShareLists, which needs the new context to
+// have no objects yet. Creating it makes it current, so the window's
+// context is made current again afterwards.
+func (w *winWindow) NewSharedContext() (SharedContext, error) {
+	ctx, err := createGLContext(w.hdc, w.opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.MakeCurrent(); err != nil {
+		procWglDeleteContext.Call(uintptr(ctx))
+		return nil, err
+	}
+	clearLastError()
+	if ret, _, _ := procWglShareLists.Call(uintptr(w.ctx), uintptr(ctx)); ret == 0 {
+		procWglDeleteContext.Call(uintptr(ctx))
+		return nil, winErr("wglShareLists")
+	}
+	return &winSharedContext{hdc: w.hdc, ctx: ctx}, nil
+}
+
+type winSharedContext struct {
+	hdc hdc
+	ctx hglrc
+}
+
+func (c *winSharedContext) MakeCurrent() error {
+	if ret, _, err := procWglMakeCurrent.Call(uintptr(c.hdc), uintptr(c.ctx)); ret == 0 {
+		return fmt.Errorf("wglMakeCurrent failed: %w", err)
+	}
+	return nil
+}
+
+func (c *winSharedContext) ClearCurrent() {
+	procWglMakeCurrent.Call(0, 0)
+}
+
+func (c *winSharedContext) Close() {
+	if c.ctx != 0 {
+		procWglDeleteContext.Call(uintptr(c.ctx))
+		c.ctx = 0
+	}
+}
+
+// StartDrag hands the mouse to the system's move loop as if the title bar
+// had been pressed. SendMessage returns once the move is over.
+func (w *winWindow) StartDrag() {
+	procReleaseCapture.Call()
+	procSendMessage.Call(uintptr(w.hwnd), wmNCLButtonDown, htCaption, 0)
+}
+
+// SetOpacity makes the window layered, which lets the desktop window
+// manager blend it with what is behind.
+func (w *winWindow) SetOpacity(opacity float32) {
+	idx := gwlExStyle // negative, so it can't be converted to uintptr as a constant
+	style, _, _ := procGetWindowLongPtr.Call(uintptr(w.hwnd), uintptr(idx))
+	if style&wsExLayered == 0 {
+		procSetWindowLongPtr.Call(uintptr(w.hwnd), uintptr(idx), style|wsExLayered)
+	}
+	alpha := uintptr(clampOpacity(opacity)*255 + 0.5)
+	procSetLayeredWindowAttributes.Call(uintptr(w.hwnd), 0, alpha, lwaAlpha)
+}
+
+// SetFullscreenMode changes the monitor's mode with ChangeDisplaySettingsEx
+// and turns the window into a borderless popup covering the monitor.
+// CDS_FULLSCREEN makes the change temporary, so Windows puts the old mode
+// back if the process exits.
+func (w *winWindow) SetFullscreenMode(mode *DisplayMode) error {
+	if mode == nil {
+		w.restoreMode()
+		return nil
+	}
+	device, err := syscall.UTF16PtrFromString(mode.monitor.Name)
+	if err != nil {
+		return err
+	}
+	if w.fullscreenDevice != "" && w.fullscreenDevice != mode.monitor.Name {
+		w.restoreMode()
+	}
+
+	var dm devMode
+	dm.dmSize = uint16(unsafe.Sizeof(dm))
+	dm.dmFields = dmBitsPerPel | dmPelsWidth | dmPelsHeight | dmDisplayFrequency
+	dm.dmBitsPerPel = uint32(mode.id)
+	dm.dmPelsWidth = uint32(mode.Width)
+	dm.dmPelsHeight = uint32(mode.Height)
+	dm.dmDisplayFrequency = uint32(mode.RefreshRate + 0.5)
+	if ret, _, _ := procChangeDisplaySettingsEx.Call(uintptr(unsafe.Pointer(device)),
+		uintptr(unsafe.Pointer(&dm)), 0, cdsFullscreen, 0); int32(ret) != dispChangeSuccessful {
+		return fmt.Errorf("ChangeDisplaySettingsEx failed with code %d", int32(ret))
+	}
+
+	idx := gwlStyle // negative, so it can't be converted to uintptr as a constant
+	if w.fullscreenDevice == "" {
+		w.savedStyle, _, _ = procGetWindowLongPtr.Call(uintptr(w.hwnd), uintptr(idx))
+		procGetWindowRect.Call(uintptr(w.hwnd), uintptr(unsafe.Pointer(&w.savedRect)))
+	}
+	w.fullscreenDevice = mode.monitor.Name
+
+	// The monitor's desktop rectangle changes with its mode.
+	bounds := mode.monitor
+	if mons, err := Monitors(); err == nil {
+		for _, m := range mons {
+			if m.Name == mode.monitor.Name {
+				bounds = m
+			}
+		}
+	}
+	procSetWindowLongPtr.Call(uintptr(w.hwnd), uintptr(idx), w.savedStyle&^wsOverlappedWindow|wsPopup)
+	procSetWindowPos.Call(uintptr(w.hwnd), 0, uintptr(bounds.X), uintptr(bounds.Y),
+		uintptr(bounds.Width), uintptr(bounds.Height), swpFrameChanged|swpNoOwnerZOrder)
+	return nil
+}
+
+// restoreMode puts back the monitor mode, window style and window
+// rectangle SetFullscreenMode replaced.
+func (w *winWindow) restoreMode() {
+	if w.fullscreenDevice == "" {
+		return
+	}
+	if device, err := syscall.UTF16PtrFromString(w.fullscreenDevice); err == nil {
+		procChangeDisplaySettingsEx.Call(uintptr(unsafe.Pointer(device)), 0, 0, 0, 0)
+	}
+	w.fullscreenDevice = ""
+	if w.hwnd == 0 {
+		return
+	}
+	idx := gwlStyle
+	procSetWindowLongPtr.Call(uintptr(w.hwnd), uintptr(idx), w.savedStyle)
+	r := w.savedRect
+	procSetWindowPos.Call(uintptr(w.hwnd), 0, uintptr(r.left), uintptr(r.top),
+		uintptr(r.right-r.left), uintptr(r.bottom-r.top), swpFrameChanged|swpNoOwnerZOrder)
+}
+
+// monitorModes lists the modes of m's display device at its current bit
+// depth.
+func monitorModes(m *Monitor) ([]DisplayMode, error) {
+	device, err := syscall.UTF16PtrFromString(m.Name)
+	if err != nil {
+		return nil, err
+	}
+	var current devMode
+	current.dmSize = uint16(unsafe.Sizeof(current))
+	if ret, _, _ := procEnumDisplaySettings.Call(uintptr(unsafe.Pointer(device)), enumCurrentSettings,
+		uintptr(unsafe.Pointer(&current))); ret == 0 {
+		return nil, winErr("EnumDisplaySettings")
+	}
+
+	var modes []DisplayMode
+	for i := uintptr(0); ; i++ {
+		var dm devMode
+		dm.dmSize = uint16(unsafe.Sizeof(dm))
+		if ret, _, _ := procEnumDisplaySettings.Call(uintptr(unsafe.Pointer(device)), i,
+			uintptr(unsafe.Pointer(&dm))); ret == 0 {
+			break
+		}
+		if dm.dmBitsPerPel != current.dmBitsPerPel {
+			continue
+		}
+		modes = append(modes, DisplayMode{
+			Width:       int(dm.dmPelsWidth),
+			Height:      int(dm.dmPelsHeight),
+			RefreshRate: float64(dm.dmDisplayFrequency),
+			monitor:     *m,
+			id:          uintptr(dm.dmBitsPerPel),
+		})
+	}
+	return modes, nil
+}
+
 func (w *winWindow) BackingSize() (int, int) {
 	var r rect
 	procGetClientRect.Call(uintptr(w.hwnd), uintptr(unsafe.Pointer(&r)))
@@ -356,8 +788,134 @@ func (w *winWindow) Cursor() (float32, float32) {
 }
 
 func (w *winWindow) Scale() float32 {
-	// TODO: Implement Windows DPI detection
-	return 1.0
+	return float32(w.dpi) / defaultDPI
+}
+
+// enableTransparency makes the desktop window manager use the alpha channel
+// of the window's GL framebuffer. Blur behind with an empty region is the
+// documented way to get that without the blur itself.
+func enableTransparency(h hwnd) {
+	if procDwmEnableBlurBehindWindow.Find() != nil {
+		return
+	}
+	rgn, _, _ := procCreateRectRgn.Call(0, 0, ^uintptr(0), ^uintptr(0)) // (0, 0, -1, -1)
+	bb := dwmBlurBehind{
+		dwFlags:  dwmBBEnable | dwmBBBlurRegion,
+		fEnable:  1,
+		hRgnBlur: syscall.Handle(rgn),
+	}
+	procDwmEnableBlurBehindWindow.Call(uintptr(h), uintptr(unsafe.Pointer(&bb)))
+	if rgn != 0 {
+		procDeleteObject.Call(rgn)
+	}
+}
+
+var dpiAwareOnce sync.Once
+
+// enableDPIAwareness makes the process per-monitor DPI aware, so that
+// windows get WM_DPICHANGED and GetDpiForWindow reports the monitor's DPI
+// instead of Windows bitmap-stretching them. It must run before the first
+// window is created, and a DPI setting in the application manifest takes
+// precedence over it.
+func enableDPIAwareness() {
+	dpiAwareOnce.Do(func() {
+		if procSetProcessDpiAwarenessContext.Find() == nil {
+			if ret, _, _ := procSetProcessDpiAwarenessContext.Call(dpiAwarenessContextPerMonitorAwareV2); ret != 0 {
+				return
+			}
+		}
+		if procSetProcessDpiAwareness.Find() == nil {
+			procSetProcessDpiAwareness.Call(processPerMonitorDPIAware)
+		}
+	})
+}
+
+// windowDPI returns the DPI of the monitor hwnd is on. Unless the process is
+// DPI aware this is always 96, as Windows scales the window itself.
+func windowDPI(h hwnd) uint32 {
+	if procGetDpiForWindow.Find() != nil {
+		return defaultDPI
+	}
+	dpi, _, _ := procGetDpiForWindow.Call(uintptr(h))
+	if dpi == 0 {
+		return defaultDPI
+	}
+	return uint32(dpi)
+}
+
+// DisplayInfo describes the monitor containing the largest part of the
+// window. The physical size comes from the window's device context, which
+// reports that of the primary monitor.
+func (w *winWindow) DisplayInfo() DisplayInfo {
+	var info DisplayInfo
+	if mon, _, _ := procMonitorFromWindow.Call(uintptr(w.hwnd), monitorDefaultToNearest); mon != 0 {
+		mi := monitorInfo{cbSize: uint32(unsafe.Sizeof(monitorInfo{}))}
+		if ret, _, _ := procGetMonitorInfo.Call(mon, uintptr(unsafe.Pointer(&mi))); ret != 0 {
+			info.Width = int(mi.rcMonitor.right - mi.rcMonitor.left)
+			info.Height = int(mi.rcMonitor.bottom - mi.rcMonitor.top)
+		}
+	}
+	wmm, _, _ := procGetDeviceCaps.Call(uintptr(w.hdc), horzSize)
+	hmm, _, _ := procGetDeviceCaps.Call(uintptr(w.hdc), vertSize)
+	info.WidthMM, info.HeightMM = int(int32(wmm)), int(int32(hmm))
+	return info.computeDPI()
+}
+
+var (
+	// EnumDisplayMonitors reports through a callback, and callbacks are a
+	// limited resource, so one is shared and fills enumMonitors.
+	enumMonitorsOnce sync.Once
+	enumMonitorsProc uintptr
+	enumMonitors     []Monitor
+	enumMonitorsMu   sync.Mutex
+)
+
+// Monitors returns the monitors attached to the desktop.
+func Monitors() ([]Monitor, error) {
+	// Monitor rectangles are scaled for processes that aren't DPI aware.
+	enableDPIAwareness()
+	enumMonitorsOnce.Do(func() {
+		enumMonitorsProc = syscall.NewCallback(func(hmon, hdc, r, lparam uintptr) uintptr {
+			enumMonitors = append(enumMonitors, monitorFromHandle(hmon))
+			return 1
+		})
+	})
+
+	enumMonitorsMu.Lock()
+	defer enumMonitorsMu.Unlock()
+	enumMonitors = nil
+	clearLastError()
+	if ret, _, _ := procEnumDisplayMonitors.Call(0, 0, enumMonitorsProc, 0); ret == 0 {
+		return nil, winErr("EnumDisplayMonitors")
+	}
+	mons := enumMonitors
+	enumMonitors = nil
+	return mons, nil
+}
+
+func monitorFromHandle(hmon uintptr) Monitor {
+	var mi monitorInfoEx
+	mi.cbSize = uint32(unsafe.Sizeof(mi))
+	procGetMonitorInfo.Call(hmon, uintptr(unsafe.Pointer(&mi)))
+
+	scale := float32(1)
+	if procGetDpiForMonitor.Find() == nil {
+		var dpiX, dpiY uint32
+		if ret, _, _ := procGetDpiForMonitor.Call(hmon, mdtEffectiveDPI,
+			uintptr(unsafe.Pointer(&dpiX)), uintptr(unsafe.Pointer(&dpiY))); ret == 0 && dpiX != 0 {
+			scale = float32(dpiX) / defaultDPI
+		}
+	}
+
+	return Monitor{
+		Name:    syscall.UTF16ToString(mi.szDevice[:]),
+		X:       int(mi.rcMonitor.left),
+		Y:       int(mi.rcMonitor.top),
+		Width:   int(mi.rcMonitor.right - mi.rcMonitor.left),
+		Height:  int(mi.rcMonitor.bottom - mi.rcMonitor.top),
+		Scale:   scale,
+		Primary: mi.dwFlags&monitorInfoFPrimary != 0,
+	}
 }
 
 func (w *winWindow) GetKeyState(key Key) KeyState {
@@ -370,6 +928,42 @@ func (w *winWindow) GetButtonState(button Button) ButtonState {
 	return ButtonStateUp
 }
 
+func (w *winWindow) TextInput() string   { return string(w.text) }
+func (w *winWindow) Composition() string { return w.composition }
+
+// addChar handles one UTF-16 code unit from WM_CHAR. Characters outside the
+// BMP arrive as two messages, one per surrogate.
+func (w *winWindow) addChar(c uint16) {
+	r := rune(c)
+	switch {
+	case r >= 0xD800 && r < 0xDC00:
+		w.highSurrogate = c
+		return
+	case utf16.IsSurrogate(r):
+		r = utf16.DecodeRune(rune(w.highSurrogate), r)
+		w.highSurrogate = 0
+	}
+	w.text = appendText(w.text, string(r))
+}
+
+// compositionString returns the text the IME of h is composing.
+func compositionString(h uintptr) string {
+	himc, _, _ := procImmGetContext.Call(h)
+	if himc == 0 {
+		return ""
+	}
+	defer procImmReleaseContext.Call(h, himc)
+
+	// The length is in bytes, or negative on error.
+	n, _, _ := procImmGetCompositionStringW.Call(himc, gcsCompStr, 0, 0)
+	if int32(n) <= 0 {
+		return ""
+	}
+	buf := make([]uint16, int32(n)/2)
+	procImmGetCompositionStringW.Call(himc, gcsCompStr, uintptr(unsafe.Pointer(&buf[0])), n)
+	return string(utf16.Decode(buf))
+}
+
 func registerWindowClass() error {
 	cb := syscall.NewCallback(wndProc)
 	wc := wndClassEx{
@@ -433,9 +1027,10 @@ func chooseAndSetPixelFormat(hdc hdc) (int32, pixelFormatDescriptor, error) {
 	desired := pixelFormatDescriptor{
 		nSize:        uint16(unsafe.Sizeof(pixelFormatDescriptor{})),
 		nVersion:     1,
-		dwFlags:      pfdDrawToWindow | pfdSupportOpenGL | pfdDoubleBuffer,
+		dwFlags:      pfdDrawToWindow | pfdSupportOpenGL | pfdDoubleBuffer | pfdSupportComposition,
 		iPixelType:   pfdTypeRGBA,
 		cColorBits:   24,
+		cAlphaBits:   8,
 		cDepthBits:   24,
 		cStencilBits: 8,
 		iLayerType:   pfdMainPlane,
@@ -587,7 +1182,94 @@ func enumAndSetPixelFormat(
 	return int32(chosenFormat), chosenPFD, nil
 }
 
-func createGLContext(hdc hdc) (hglrc, error) {
+// chooseMultisamplePixelFormat returns a pixel format index with the given
+// number of samples, or 0 if WGL_ARB_pixel_format is unavailable or no format
+// matches. wglChoosePixelFormatARB can only be loaded with a current context,
+// so a hidden dummy window and context are created to query it.
+func chooseMultisamplePixelFormat(samples int) int32 {
+	dummy, dc, err := createWindow("", 1, 1)
+	if err != nil {
+		return 0
+	}
+	defer procDestroyWindow.Call(uintptr(dummy))
+	defer procReleaseDC.Call(uintptr(dummy), uintptr(dc))
+
+	if _, _, err := chooseAndSetPixelFormat(dc); err != nil {
+		return 0
+	}
+	ctx, _, _ := procWglCreateContext.Call(uintptr(dc))
+	if ctx == 0 {
+		return 0
+	}
+	defer procWglDeleteContext.Call(ctx)
+	if ret, _, _ := procWglMakeCurrent.Call(uintptr(dc), ctx); ret == 0 {
+		return 0
+	}
+	defer procWglMakeCurrent.Call(0, 0)
+
+	procName := syscall.StringBytePtr("wglChoosePixelFormatARB")
+	choosePixelFormatARB, _, _ := procWglGetProcAddress.Call(uintptr(unsafe.Pointer(procName)))
+	if choosePixelFormatARB == 0 {
+		return 0
+	}
+
+	attribs := []int32{
+		wglDrawToWindowArb, 1,
+		wglSupportOpenGLArb, 1,
+		wglDoubleBufferArb, 1,
+		wglPixelTypeArb, wglTypeRGBAArb,
+		wglColorBitsArb, 24,
+		wglAlphaBitsArb, 8,
+		wglDepthBitsArb, 24,
+		wglStencilBitsArb, 8,
+		wglSampleBuffersArb, 1,
+		wglSamplesArb, int32(samples),
+		0,
+	}
+	var format int32
+	var numFormats uint32
+	ret, _, _ := syscall.SyscallN(
+		choosePixelFormatARB,
+		uintptr(dc),
+		uintptr(unsafe.Pointer(&attribs[0])),
+		0,
+		1,
+		uintptr(unsafe.Pointer(&format)),
+		uintptr(unsafe.Pointer(&numFormats)),
+	)
+	if ret == 0 || numFormats == 0 {
+		return 0
+	}
+	return format
+}
+
+// setPixelFormat applies the pixel format with the given index to hdc.
+func setPixelFormat(hdc hdc, format int32) error {
+	var pfd pixelFormatDescriptor
+	clearLastError()
+	r, _, _ := procDescribePixelFormat.Call(
+		uintptr(hdc),
+		uintptr(format),
+		uintptr(unsafe.Sizeof(pfd)),
+		uintptr(unsafe.Pointer(&pfd)),
+	)
+	if r == 0 {
+		return winErr("DescribePixelFormat")
+	}
+
+	clearLastError()
+	ok, _, _ := procSetPixelFormat.Call(
+		uintptr(hdc),
+		uintptr(format),
+		uintptr(unsafe.Pointer(&pfd)),
+	)
+	if ok == 0 {
+		return winErr("SetPixelFormat")
+	}
+	return nil
+}
+
+func createGLContext(hdc hdc, opts Options) (hglrc, error) {
 	// First create a temporary legacy context to bootstrap
 	clearLastError()
 	tempCtx, _, _ := procWglCreateContext.Call(uintptr(hdc))
@@ -610,12 +1292,19 @@ func createGLContext(hdc hdc) (hglrc, error) {
 		// We have WGL_ARB_create_context support
 		createContextAttribsARB := *(*func(uintptr, uintptr, uintptr, *int32) uintptr)(unsafe.Pointer(&procAddr))
 
+		major, minor := opts.glVersion(3, 0)
 		attribs := []int32{
-			wglContextMajorVersionArb, 3,
-			wglContextMinorVersionArb, 0,
-			wglContextFlagsArb, wglContextCoreProfileBitArb,
-			0,
+			wglContextMajorVersionArb, int32(major),
+			wglContextMinorVersionArb, int32(minor),
 		}
+		if major > 3 || (major == 3 && minor >= 2) {
+			profile := int32(wglContextCompatProfileBitArb)
+			if opts.CoreProfile {
+				profile = wglContextCoreProfileBitArb
+			}
+			attribs = append(attribs, wglContextProfileMaskArb, profile)
+		}
+		attribs = append(attribs, 0)
 
 		clearLastError()
 		newCtx := createContextAttribsARB(uintptr(hdc), 0, uintptr(unsafe.Pointer(&attribs[0])), nil)
@@ -633,7 +1322,7 @@ func createGLContext(hdc hdc) (hglrc, error) {
 				finalCtx = hglrc(tempCtx)
 			}
 		} else {
-			// Failed to create GL 3.0 context, use temp context
+			// Failed to create the requested context, use temp context
 			finalCtx = hglrc(tempCtx)
 		}
 	} else {
@@ -660,6 +1349,42 @@ func wndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
 	case wmDestroy:
 		procPostQuitMessage.Call(0)
 		return 0
+	case wmDPIChanged:
+		current := currentWin
+		if current != nil && current.hwnd == syscall.Handle(hwnd) {
+			current.dpi = uint32(wParam & 0xFFFF)
+		}
+		// Resize to the rectangle Windows suggests for the new DPI. lParam
+		// points to it in memory owned by the system, not Go.
+		r := (*rect)(unsafe.Pointer(lParam))
+		procSetWindowPos.Call(hwnd, 0,
+			uintptr(r.left), uintptr(r.top),
+			uintptr(r.right-r.left), uintptr(r.bottom-r.top),
+			swpNoZOrder|swpNoActivate)
+		return 0
+	case wmChar:
+		current := currentWin
+		if current != nil && current.hwnd == syscall.Handle(hwnd) {
+			current.addChar(uint16(wParam))
+		}
+		return 0
+	case wmImeComposition:
+		// DefWindowProc still runs and turns the committed result into
+		// WM_CHAR messages.
+		current := currentWin
+		if current != nil && current.hwnd == syscall.Handle(hwnd) {
+			if lParam&gcsResultStr != 0 {
+				current.composition = ""
+			}
+			if lParam&gcsCompStr != 0 {
+				current.composition = compositionString(hwnd)
+			}
+		}
+	case wmImeEndComposition:
+		current := currentWin
+		if current != nil && current.hwnd == syscall.Handle(hwnd) {
+			current.composition = ""
+		}
 	}
 	ret, _, _ := procDefWindowProc.Call(hwnd, msg, wParam, lParam)
 	return ret